@@ -169,11 +169,11 @@ func initTestData() error {
 		PackageID:      pkg.PackageID,
 		AbnormalType:   "sorting",
 		AbnormalReason: "地址标签模糊，需核对收件人信息",
-		Processor:      "测试分拣员",
+		NodeName:       "分拣中心",
+		Reporter:       "测试分拣员",
 		Status:         "pending",
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
-		ProcessingTime: time.Now(),
 	}
 	if err := db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(abnormal).Error; err != nil {
 		return fmt.Errorf("插入异常数据失败: %v", err)
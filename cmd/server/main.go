@@ -7,6 +7,9 @@ import (
 	"github.com/LFrankl/fdu-lab3/config"
 	"github.com/LFrankl/fdu-lab3/internal/api/router"
 	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/internal/routing"
+	"github.com/LFrankl/fdu-lab3/internal/service"
+	"github.com/LFrankl/fdu-lab3/internal/util"
 	"github.com/LFrankl/fdu-lab3/pkg/db"
 )
 
@@ -15,6 +18,9 @@ import (
 // @description 基于Golang实现的快递物流系统API
 // @host localhost:8080
 // @BasePath /api/v1
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
 func main() {
 	// 加载配置
 	if err := config.Load("config/app.yaml"); err != nil {
@@ -24,6 +30,20 @@ func main() {
 	// 初始化日志
 	//logger.Init(config.Cfg.App.Env)
 
+	if err := config.Cfg.Auth.Validate(config.Cfg.App.Env); err != nil {
+		log.Fatalf("认证配置无效，拒绝启动: %v", err)
+	}
+
+	// 初始化地理编码器
+	if err := util.InitGeocoder(config.Cfg.Geo); err != nil {
+		log.Fatalf("初始化地理编码器失败: %v", err)
+	}
+
+	// 加载路网
+	if err := routing.Init(config.Cfg.Routing); err != nil {
+		log.Fatalf("加载路网失败: %v", err)
+	}
+
 	// 初始化数据库
 	if err := db.InitMySQL(); err != nil {
 		log.Fatalf("初始化MySQL失败: %v", err)
@@ -36,17 +56,34 @@ func main() {
 
 	// 自动迁移表结构
 	if err := db.DB.AutoMigrate(
+		&model.Account{},
+		&model.Node{},
+		&model.CourierArea{},
+		&model.Vehicle{},
+		&model.Driver{},
+		&model.PackageETA{},
 		&model.Package{},
 		&model.PackageTrace{},
 		&model.AbnormalRecord{},
+		&model.PackageStatusLog{},
 		&model.TransportTask{},
 		&model.TransportTaskPackage{},
-		//&model.DeliveryTask{},
-		//&model.DeliveryTaskPackage{},
+		&model.DeliveryTask{},
+		&model.DeliveryTaskPackage{},
 	); err != nil {
 		log.Fatalf("表结构迁移失败: %v", err)
 	}
 
+	// 初始化管理员账号
+	if err := service.NewAuthSvc().EnsureAdmin(config.Cfg.Auth.AdminID, config.Cfg.Auth.AdminPassword); err != nil {
+		log.Fatalf("初始化管理员账号失败: %v", err)
+	}
+
+	// 路网节点登记到节点表（已登记的跳过）
+	if err := service.NewNodeSvc().SeedFromNetwork(routing.Default()); err != nil {
+		log.Fatalf("初始化网络节点失败: %v", err)
+	}
+
 	// 配置路由
 	r := router.SetupRouter()
 
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 
 	"gopkg.in/yaml.v3"
@@ -12,12 +13,16 @@ type Config struct {
 	Redis    RedisConfig    `yaml:"redis"`
 	RabbitMQ RabbitMQConfig `yaml:"rabbitmq"`
 	Geo      GeoConfig      `yaml:"geo"`
+	Routing  RoutingConfig  `yaml:"routing"`
+	Auth     AuthConfig     `yaml:"auth"`
+	Tracking TrackingConfig `yaml:"tracking"`
 }
 
 type AppConfig struct {
-	Name string `yaml:"name"`
-	Port int    `yaml:"port"`
-	Env  string `yaml:"env"`
+	Name           string   `yaml:"name"`
+	Port           int      `yaml:"port"`
+	Env            string   `yaml:"env"`
+	TrustedProxies []string `yaml:"trusted_proxies"` // 可信反向代理地址，仅其转发的X-Forwarded-For用于识别客户端IP；为空时取连接地址
 }
 
 type MySQLConfig struct {
@@ -41,16 +46,78 @@ type RabbitMQConfig struct {
 }
 
 type GeoConfig struct {
-	AmapKey string `yaml:"amap_key"`
+	AmapKey       string `yaml:"amap_key"`
+	Backend       string `yaml:"backend"`        // 地理编码后端：gazetteer（离线地名库，默认）/amap（高德在线接口）
+	GazetteerPath string `yaml:"gazetteer_path"` // 离线地名库CSV路径，为空使用内置数据
+	Timeout       int    `yaml:"timeout"`        // 在线接口超时（秒），默认3
+	CacheSize     int    `yaml:"cache_size"`     // 解析结果缓存条数，0取默认值1024，负数关闭缓存
+}
+
+type RoutingConfig struct {
+	NetworkPath string `yaml:"network_path"` // 路网文件（JSON）路径，为空使用内置路网
+}
+
+type AuthConfig struct {
+	JWTSecret     string `yaml:"jwt_secret"`
+	TokenTTL      int    `yaml:"token_ttl"` // 令牌有效期（秒）
+	AdminID       string `yaml:"admin_id"`
+	AdminPassword string `yaml:"admin_password"`
+}
+
+type TrackingConfig struct {
+	RateLimit int `yaml:"rate_limit"` // 公开查件每IP每分钟请求数，默认20
+	FailLimit int `yaml:"fail_limit"` // 公开查件每IP每小时校验失败次数，默认10，达到后拒绝查询
+	LockLimit int `yaml:"lock_limit"` // 公开查件每运单每小时校验失败次数，默认5，达到后锁定该运单的查询
+}
+
+// 环境变量（设置时覆盖配置文件中的对应项，生产环境的密钥与密码应通过环境变量注入）
+const (
+	EnvJWTSecret     = "AUTH_JWT_SECRET"
+	EnvAdminPassword = "AUTH_ADMIN_PASSWORD"
+)
+
+// authPlaceholders 配置文件自带的示例密钥/密码，仅供本地开发
+var authPlaceholders = map[string]bool{
+	"change-me-in-prod": true,
+	"admin123":          true,
 }
 
 var Cfg Config
 
-// Load 加载配置文件
+// Load 加载配置文件，并以环境变量覆盖认证密钥与管理员密码
 func Load(path string) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return err
 	}
-	return yaml.Unmarshal(data, &Cfg)
+	if err := yaml.Unmarshal(data, &Cfg); err != nil {
+		return err
+	}
+	if v := os.Getenv(EnvJWTSecret); v != "" {
+		Cfg.Auth.JWTSecret = v
+	}
+	if v := os.Getenv(EnvAdminPassword); v != "" {
+		Cfg.Auth.AdminPassword = v
+	}
+	return nil
+}
+
+// Validate 校验认证配置：JWT密钥与管理员密码必须配置，生产环境（app.env为prod）不得使用示例值
+func (c AuthConfig) Validate(env string) error {
+	if c.JWTSecret == "" {
+		return fmt.Errorf("未配置auth.jwt_secret（或环境变量%s）", EnvJWTSecret)
+	}
+	if c.AdminPassword == "" {
+		return fmt.Errorf("未配置auth.admin_password（或环境变量%s）", EnvAdminPassword)
+	}
+	if env != "prod" {
+		return nil
+	}
+	if authPlaceholders[c.JWTSecret] {
+		return fmt.Errorf("生产环境不得使用示例auth.jwt_secret，请通过环境变量%s设置", EnvJWTSecret)
+	}
+	if authPlaceholders[c.AdminPassword] {
+		return fmt.Errorf("生产环境不得使用示例auth.admin_password，请通过环境变量%s设置", EnvAdminPassword)
+	}
+	return nil
 }
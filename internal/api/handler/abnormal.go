@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/LFrankl/fdu-lab3/internal/api/middleware"
+	"github.com/LFrankl/fdu-lab3/internal/service"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+	"github.com/gin-gonic/gin"
+)
+
+// AbnormalHandler 异常处理台API处理
+type AbnormalHandler struct {
+	abnormalSvc *service.AbnormalSvc
+}
+
+func NewAbnormalHandler() *AbnormalHandler {
+	return &AbnormalHandler{
+		abnormalSvc: service.NewAbnormalSvc(),
+	}
+}
+
+// ListRecords 查询异常记录
+// @Summary 查询异常记录
+// @Description 按异常类型、节点、状态、处理人与积压时长筛选异常记录，按创建时间正序分页；status缺省仅返回未结记录（pending/processing），status=all不限状态
+// @Tags 异常处理
+// @Produce json
+// @Param type query string false "异常类型"
+// @Param node_name query string false "节点名称"
+// @Param status query string false "处理状态（pending/processing/resolved/closed/all）"
+// @Param assignee_id query string false "处理人账号ID"
+// @Param older_than_hours query int false "仅返回创建已超过该小时数的记录"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认20，最大100"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"total":1,"page":1,"page_size":20,"records":[]}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"参数错误","data":nil}
+// @Router /abnormal-records [get]
+func (h *AbnormalHandler) ListRecords(c *gin.Context) {
+	var req service.AbnormalRecordListReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	records, total, err := h.abnormalSvc.ListRecords(&req)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{
+		"total":     total,
+		"page":      req.Page,
+		"page_size": req.PageSize,
+		"records":   records,
+	})
+}
+
+// GetRecord 查询异常记录详情
+// @Summary 查询异常记录详情
+// @Tags 异常处理
+// @Produce json
+// @Param record_id path string true "异常记录ID"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"record":{}}}
+// @Failure 404 {object} gin.H{"code":404,"msg":"异常记录不存在","data":nil}
+// @Router /abnormal-records/{record_id} [get]
+func (h *AbnormalHandler) GetRecord(c *gin.Context) {
+	record, err := h.abnormalSvc.GetRecord(c.Param("record_id"))
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"record": record})
+}
+
+// AssignRecord 指派异常记录处理人
+// @Summary 指派异常记录处理人
+// @Description 调度员将待处理/处理中的异常记录指派（或改派）给指定账号，记录进入处理中
+// @Tags 异常处理
+// @Accept json
+// @Produce json
+// @Param record_id path string true "异常记录ID"
+// @Param request body object true "处理人账号ID（assignee_id）"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"record":{}}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"指派的处理人不存在或已停用","data":nil}
+// @Failure 404 {object} gin.H{"code":404,"msg":"异常记录不存在","data":nil}
+// @Failure 409 {object} gin.H{"code":409,"msg":"异常记录已被他人处理，请刷新后重试","data":nil}
+// @Router /abnormal-records/{record_id}/assign [post]
+func (h *AbnormalHandler) AssignRecord(c *gin.Context) {
+	var req struct {
+		AssigneeID string `json:"assignee_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	record, err := h.abnormalSvc.AssignRecord(c.Param("record_id"), req.AssigneeID)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"record": record})
+}
+
+// ResolveRecord 处理异常记录
+// @Summary 处理异常记录
+// @Description 被指派的处理人按处理方式（relabel重新贴标/resort重新分拣/return_to_sender退回寄件人）完成处理，仍处于异常状态的包裹随之回到正常流转并记录轨迹
+// @Tags 异常处理
+// @Accept json
+// @Produce json
+// @Param record_id path string true "异常记录ID"
+// @Param request body object true "处理方式（method）与处理说明（remark）"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"record":{}}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"异常处理方式不合法","data":nil}
+// @Failure 403 {object} gin.H{"code":403,"msg":"仅被指派的处理人可处理该异常记录","data":nil}
+// @Failure 404 {object} gin.H{"code":404,"msg":"异常记录不存在","data":nil}
+// @Failure 409 {object} gin.H{"code":409,"msg":"异常记录已被他人处理，请刷新后重试","data":nil}
+// @Router /abnormal-records/{record_id}/resolve [post]
+func (h *AbnormalHandler) ResolveRecord(c *gin.Context) {
+	var req struct {
+		Method string `json:"method" binding:"required"`
+		Remark string `json:"remark"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	record, err := h.abnormalSvc.ResolveRecord(middleware.CurrentIdentity(c), c.Param("record_id"), req.Method, req.Remark)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"record": record})
+}
+
+// CloseRecord 关闭异常记录
+// @Summary 关闭异常记录
+// @Description 调度员确认已处理的异常记录并关闭
+// @Tags 异常处理
+// @Accept json
+// @Produce json
+// @Param record_id path string true "异常记录ID"
+// @Param request body object true "关闭说明（remark）"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"record":{}}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"异常记录状态流转不合法","data":nil}
+// @Router /abnormal-records/{record_id}/close [post]
+func (h *AbnormalHandler) CloseRecord(c *gin.Context) {
+	var req struct {
+		Remark string `json:"remark" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	record, err := h.abnormalSvc.CloseRecord(c.Param("record_id"), req.Remark)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"record": record})
+}
+
+// ReopenRecord 重新打开异常记录
+// @Summary 重新打开异常记录
+// @Description 调度员重新打开已处理/已关闭的异常记录，交回原处理人继续处理
+// @Tags 异常处理
+// @Accept json
+// @Produce json
+// @Param record_id path string true "异常记录ID"
+// @Param request body object true "重新打开原因（reason）"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"record":{}}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"异常记录状态流转不合法","data":nil}
+// @Router /abnormal-records/{record_id}/reopen [post]
+func (h *AbnormalHandler) ReopenRecord(c *gin.Context) {
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	record, err := h.abnormalSvc.ReopenRecord(c.Param("record_id"), req.Reason)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"record": record})
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/LFrankl/fdu-lab3/internal/service"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+	"github.com/gin-gonic/gin"
+)
+
+// AuthHandler 认证API处理
+type AuthHandler struct {
+	authSvc *service.AuthSvc
+}
+
+func NewAuthHandler() *AuthHandler {
+	return &AuthHandler{
+		authSvc: service.NewAuthSvc(),
+	}
+}
+
+// Login 账号登录，签发访问令牌
+// @Summary 账号登录
+// @Description 校验账号密码并签发JWT访问令牌，后续请求通过Authorization: Bearer <token>携带
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body object true "账号ID与密码"
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"token":"xxx","expires_at":"2025-12-09T12:00:00+08:00","role":"driver"}}
+// @Failure 401 {object} gin.H{"code":401,"msg":"账号或密码错误","data":nil}
+// @Router /auth/login [post]
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req struct {
+		UserID   string `json:"user_id" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	token, expiresAt, identity, err := h.authSvc.Login(req.UserID, req.Password)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, errno.ErrLoginFailed) {
+			code = http.StatusUnauthorized
+		}
+		ResponseError(c, code, err)
+		return
+	}
+	ResponseSuccess(c, gin.H{
+		"token":      token,
+		"expires_at": expiresAt,
+		"user_id":    identity.UserID,
+		"name":       identity.Name,
+		"role":       identity.Role,
+	})
+}
+
+// CreateAccount 创建账号（仅管理员）
+// @Summary 创建账号
+// @Description 管理员为揽收员/分拣员/调度员/司机/派送员创建登录账号，司机/派送员的账号ID即司机ID/派送员ID
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body service.CreateAccountReq true "账号信息"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"user_id":"D001","role":"driver"}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"参数错误/角色不合法","data":nil}
+// @Failure 409 {object} gin.H{"code":409,"msg":"账号已存在","data":nil}
+// @Router /auth/accounts [post]
+func (h *AuthHandler) CreateAccount(c *gin.Context) {
+	var req service.CreateAccountReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	account, err := h.authSvc.CreateAccount(&req)
+	if err != nil {
+		code := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, errno.ErrParamInvalid), errors.Is(err, errno.ErrRoleInvalid):
+			code = http.StatusBadRequest
+		case errors.Is(err, errno.ErrAccountExists):
+			code = http.StatusConflict
+		}
+		ResponseError(c, code, err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"user_id": account.UserID, "name": account.Name, "role": account.Role})
+}
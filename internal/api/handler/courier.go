@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/LFrankl/fdu-lab3/internal/service"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+	"github.com/gin-gonic/gin"
+)
+
+// CourierHandler 派送员服务范围API处理
+type CourierHandler struct {
+	courierSvc *service.CourierSvc
+}
+
+func NewCourierHandler() *CourierHandler {
+	return &CourierHandler{
+		courierSvc: service.NewCourierSvc(),
+	}
+}
+
+// SetArea 登记/更新派送员服务范围
+// @Summary 登记/更新派送员服务范围
+// @Description 调度员为派送员登记负责区县或多边形范围及运力上限（整体覆盖），创建派送任务时据此校验
+// @Tags 派送员管理
+// @Accept json
+// @Produce json
+// @Param courier_id path string true "派送员ID"
+// @Param request body service.CourierAreaReq true "服务范围"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"area":{}}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"派送员账号不存在或已停用","data":nil}
+// @Router /couriers/{courier_id}/area [put]
+func (h *CourierHandler) SetArea(c *gin.Context) {
+	var req service.CourierAreaReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	area, err := h.courierSvc.SetCourierArea(c.Param("courier_id"), &req)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"area": area})
+}
+
+// GetArea 查询派送员服务范围
+// @Summary 查询派送员服务范围
+// @Tags 派送员管理
+// @Produce json
+// @Param courier_id path string true "派送员ID"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"area":{}}}
+// @Failure 404 {object} gin.H{"code":404,"msg":"派送员未登记服务范围","data":nil}
+// @Router /couriers/{courier_id}/area [get]
+func (h *CourierHandler) GetArea(c *gin.Context) {
+	area, err := h.courierSvc.GetCourierArea(c.Param("courier_id"))
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"area": area})
+}
+
+// ListAreas 查询派送员服务范围列表
+// @Summary 查询派送员服务范围列表
+// @Tags 派送员管理
+// @Produce json
+// @Param station_id query string false "所属网点ID"
+// @Param district query string false "负责区县"
+// @Param status query string false "状态（active/disabled）"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认20，最大100"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"total":1,"page":1,"page_size":20,"areas":[]}}
+// @Router /couriers/areas [get]
+func (h *CourierHandler) ListAreas(c *gin.Context) {
+	var req service.CourierAreaListReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	areas, total, err := h.courierSvc.ListCourierAreas(&req)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{
+		"total":     total,
+		"page":      req.Page,
+		"page_size": req.PageSize,
+		"areas":     areas,
+	})
+}
+
+// DeleteArea 删除派送员服务范围
+// @Summary 删除派送员服务范围
+// @Tags 派送员管理
+// @Produce json
+// @Param courier_id path string true "派送员ID"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"courier_id":"xxx"}}
+// @Failure 404 {object} gin.H{"code":404,"msg":"派送员未登记服务范围","data":nil}
+// @Router /couriers/{courier_id}/area [delete]
+func (h *CourierHandler) DeleteArea(c *gin.Context) {
+	courierID := c.Param("courier_id")
+	if err := h.courierSvc.DeleteCourierArea(courierID); err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"courier_id": courierID})
+}
+
+// Eligible 推荐可承接派送区域的派送员
+// @Summary 推荐可承接派送区域的派送员
+// @Description 返回服务范围覆盖该区域（区县匹配或坐标落在多边形内）且运力未满的派送员，按当前负载由低到高排序
+// @Tags 派送员管理
+// @Produce json
+// @Param delivery_area query string true "派送区域"
+// @Param station_id query string false "派送网点ID"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"couriers":[]}}
+// @Router /couriers/eligible [get]
+func (h *CourierHandler) Eligible(c *gin.Context) {
+	var req service.EligibleCourierReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	couriers, err := h.courierSvc.EligibleCouriers(&req)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"couriers": couriers})
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"net/http"
 
+	"github.com/LFrankl/fdu-lab3/internal/api/middleware"
 	"github.com/LFrankl/fdu-lab3/internal/service"
 	"github.com/LFrankl/fdu-lab3/pkg/errno"
 	"github.com/gin-gonic/gin"
@@ -28,12 +29,38 @@ func (h *DeliveryHandler) CreateTask(c *gin.Context) {
 	}
 	task, err := h.deliverySvc.CreateDeliveryTask(&req)
 	if err != nil {
-		ResponseError(c, http.StatusInternalServerError, err)
+		ResponseError(c, errorStatus(err), err)
 		return
 	}
 	ResponseSuccess(c, gin.H{"task_id": task.TaskID, "status": task.Status})
 }
 
+// GenerateTasks 按网点自动生成派送任务
+// @Summary 按网点自动生成派送任务
+// @Description 收集网点已到站且未绑定派送任务的包裹，按收件区县分组、按派送员服务范围与运力拆分，创建待派送任务并绑定包裹；dry_run=true时仅返回方案
+// @Tags 派送任务管理
+// @Accept json
+// @Produce json
+// @Param request body service.GenerateDeliveryTasksReq true "派送网点及生成选项"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"plan":{}}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"网络节点类型不合法","data":nil}
+// @Failure 404 {object} gin.H{"code":404,"msg":"网络节点不存在","data":nil}
+// @Router /delivery/tasks/generate [post]
+func (h *DeliveryHandler) GenerateTasks(c *gin.Context) {
+	var req service.GenerateDeliveryTasksReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	plan, err := h.deliverySvc.GenerateDeliveryTasks(&req)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"plan": plan})
+}
+
 // ChangeTaskStatus 变更派送任务状态
 func (h *DeliveryHandler) ChangeTaskStatus(c *gin.Context) {
 	taskID := c.Param("task_id")
@@ -44,14 +71,14 @@ func (h *DeliveryHandler) ChangeTaskStatus(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
 		return
 	}
-	if err := h.deliverySvc.ChangeTaskStatus(taskID, req.NewStatus); err != nil {
-		ResponseError(c, http.StatusInternalServerError, err)
+	if err := h.deliverySvc.ChangeTaskStatus(middleware.CurrentIdentity(c), taskID, req.NewStatus); err != nil {
+		ResponseError(c, errorStatus(err), err)
 		return
 	}
 	ResponseSuccess(c, gin.H{"msg": "派送任务状态已更新"})
 }
 
-// BindPackages 绑定包裹到派送任务
+// BindPackages 增量绑定包裹到派送任务（已绑定包裹去重，返回本次新绑定的包裹数与绑定后的包裹数量）
 func (h *DeliveryHandler) BindPackages(c *gin.Context) {
 	taskID := c.Param("task_id")
 	var req struct {
@@ -61,11 +88,38 @@ func (h *DeliveryHandler) BindPackages(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
 		return
 	}
-	if err := h.deliverySvc.BindPackagesToTask(taskID, req.PackageIDs); err != nil {
-		ResponseError(c, http.StatusInternalServerError, err)
+	bound, count, err := h.deliverySvc.BindPackagesToTask(taskID, req.PackageIDs)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
 		return
 	}
-	ResponseSuccess(c, gin.H{"msg": "包裹绑定成功", "package_count": len(req.PackageIDs)})
+	ResponseSuccess(c, gin.H{"msg": "包裹绑定成功", "bound_count": bound, "package_count": count})
+}
+
+// UnbindPackages 从派送任务解绑包裹
+// @Summary 从派送任务解绑包裹
+// @Description 调度员从未完成的派送任务解绑包裹（已签收包裹不可解绑），解绑包裹恢复为已到站，其余包裹的签收信息与派送顺序不变
+// @Tags 派送任务管理
+// @Accept json
+// @Produce json
+// @Param task_id path string true "派送任务ID"
+// @Param request body BindPackagesRequest true "解绑的包裹ID列表"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"msg":"包裹解绑成功","package_count":10}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"包裹已签收，不可解绑","data":nil}
+// @Router /delivery/tasks/{task_id}/packages/unbind [post]
+func (h *DeliveryHandler) UnbindPackages(c *gin.Context) {
+	var req BindPackagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	count, err := h.deliverySvc.UnbindPackagesFromTask(middleware.CurrentIdentity(c), c.Param("task_id"), req.PackageIDs)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"msg": "包裹解绑成功", "package_count": count})
 }
 
 // ReportAbnormal 上报派送异常
@@ -73,25 +127,38 @@ func (h *DeliveryHandler) ReportAbnormal(c *gin.Context) {
 	taskID := c.Param("task_id")
 	var req struct {
 		AbnormalType string `json:"abnormal_type"`
-		Reason       string `json:"reason"`
-		Handler      string `json:"handler"`
+		Reason       string `json:"reason" binding:"max=255"` // 与异常记录/包裹异常原因列长度一致
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
 		return
 	}
-	if err := h.deliverySvc.ReportDeliveryAbnormal(taskID, req.AbnormalType, req.Reason, req.Handler); err != nil {
-		ResponseError(c, http.StatusInternalServerError, err)
+	if err := h.deliverySvc.ReportDeliveryAbnormal(middleware.CurrentIdentity(c), taskID, req.AbnormalType, req.Reason); err != nil {
+		ResponseError(c, errorStatus(err), err)
 		return
 	}
 	ResponseSuccess(c, gin.H{"msg": "派送异常已上报"})
 }
 
+// HandleAbnormal 处理派送异常
+func (h *DeliveryHandler) HandleAbnormal(c *gin.Context) {
+	taskID := c.Param("task_id")
+	var req HandleAbnormalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	if err := h.deliverySvc.HandleDeliveryAbnormal(middleware.CurrentIdentity(c), taskID, req.HandleResult, req.NewStatus); err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"msg": "派送异常已处理", "task_id": taskID, "status": req.NewStatus})
+}
+
 // SignPackage 包裹签收
 func (h *DeliveryHandler) SignPackage(c *gin.Context) {
 	taskID := c.Param("task_id")
 	packageID := c.Param("package_id")
-	courierID := c.GetHeader("courier_id") // 从请求头获取派送员ID（身份认证后）
 	var req struct {
 		SignerName  string `json:"signer_name"`
 		SignerPhone string `json:"signer_phone"`
@@ -102,8 +169,8 @@ func (h *DeliveryHandler) SignPackage(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
 		return
 	}
-	if err := h.deliverySvc.SignPackage(taskID, packageID, courierID, req.SignerName, req.SignerPhone, req.SignType, req.Remark); err != nil {
-		ResponseError(c, http.StatusInternalServerError, err)
+	if err := h.deliverySvc.SignPackage(middleware.CurrentIdentity(c), taskID, packageID, req.SignerName, req.SignerPhone, req.SignType, req.Remark); err != nil {
+		ResponseError(c, errorStatus(err), err)
 		return
 	}
 	ResponseSuccess(c, gin.H{"msg": "包裹签收成功", "package_id": packageID})
@@ -111,11 +178,10 @@ func (h *DeliveryHandler) SignPackage(c *gin.Context) {
 
 // GetCourierTaskPackages 派送员查询任务包裹列表
 func (h *DeliveryHandler) GetCourierTaskPackages(c *gin.Context) {
-	courierID := c.GetHeader("courier_id")
 	taskID := c.Param("task_id")
-	packages, err := h.deliverySvc.GetCourierTaskPackages(courierID, taskID)
+	packages, err := h.deliverySvc.GetCourierTaskPackages(middleware.CurrentIdentity(c), taskID)
 	if err != nil {
-		ResponseError(c, http.StatusInternalServerError, err)
+		ResponseError(c, errorStatus(err), err)
 		return
 	}
 	ResponseSuccess(c, gin.H{
@@ -124,3 +190,60 @@ func (h *DeliveryHandler) GetCourierTaskPackages(c *gin.Context) {
 		"packages":      packages,
 	})
 }
+
+// ResequenceTask 重新规划派送顺序
+// @Summary 重新规划派送顺序
+// @Description 派送员跳过停靠点后重新规划：已签收包裹保持原顺序，其余停靠点（同一收件地址合并）从最近签收点或派送网点出发按路线优化排序，跳过的包裹排在最后
+// @Tags 派送任务管理
+// @Accept json
+// @Produce json
+// @Param task_id path string true "派送任务ID"
+// @Param request body ResequenceRequest false "跳过的包裹"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"sequence":{}}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"仅待派送/派送中的任务可重新规划派送顺序","data":nil}
+// @Failure 403 {object} gin.H{"code":403,"msg":"派送任务不属于该派送员","data":nil}
+// @Router /delivery/tasks/{task_id}/sequence [post]
+func (h *DeliveryHandler) ResequenceTask(c *gin.Context) {
+	var req ResequenceRequest
+	// 请求体可为空（不跳过任何包裹）
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+			return
+		}
+	}
+	seq, err := h.deliverySvc.ResequenceTask(middleware.CurrentIdentity(c), c.Param("task_id"), req.SkippedPackageIDs)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"sequence": seq})
+}
+
+// ResequenceRequest 重新规划派送顺序请求参数
+type ResequenceRequest struct {
+	SkippedPackageIDs []string `json:"skipped_package_ids"`
+}
+
+// ListCourierTasks 派送员任务收件箱（支持按状态/创建日期筛选及分页，调度员可查询任意派送员）
+func (h *DeliveryHandler) ListCourierTasks(c *gin.Context) {
+	courierID := c.Param("courier_id")
+	var req service.TaskListReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	tasks, total, err := h.deliverySvc.ListCourierTasks(middleware.CurrentIdentity(c), courierID, &req)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{
+		"courier_id": courierID,
+		"total":      total,
+		"page":       req.Page,
+		"page_size":  req.PageSize,
+		"tasks":      tasks,
+	})
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/LFrankl/fdu-lab3/internal/service"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+	"github.com/gin-gonic/gin"
+)
+
+// DriverHandler 司机档案与排班API处理
+type DriverHandler struct {
+	driverSvc *service.DriverSvc
+}
+
+func NewDriverHandler() *DriverHandler {
+	return &DriverHandler{
+		driverSvc: service.NewDriverSvc(),
+	}
+}
+
+// SetDriver 登记/更新司机档案
+// @Summary 登记/更新司机档案
+// @Description 调度员为司机账号登记准驾车型、常驻节点与每日驾驶时长上限（整体覆盖），创建运输任务时据此校验排班
+// @Tags 司机管理
+// @Accept json
+// @Produce json
+// @Param driver_id path string true "司机ID"
+// @Param request body service.DriverReq true "司机档案"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"driver":{}}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"账号不是启用中的司机","data":nil}
+// @Router /drivers/{driver_id} [put]
+func (h *DriverHandler) SetDriver(c *gin.Context) {
+	var req service.DriverReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	driver, err := h.driverSvc.SetDriver(c.Param("driver_id"), &req)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"driver": driver})
+}
+
+// GetDriver 查询司机档案
+// @Summary 查询司机档案
+// @Tags 司机管理
+// @Produce json
+// @Param driver_id path string true "司机ID"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"driver":{}}}
+// @Failure 404 {object} gin.H{"code":404,"msg":"司机档案不存在","data":nil}
+// @Router /drivers/{driver_id} [get]
+func (h *DriverHandler) GetDriver(c *gin.Context) {
+	driver, err := h.driverSvc.GetDriver(c.Param("driver_id"))
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"driver": driver})
+}
+
+// ListDrivers 查询司机档案列表
+// @Summary 查询司机档案列表
+// @Tags 司机管理
+// @Produce json
+// @Param home_node_id query string false "常驻节点ID"
+// @Param license_class query string false "准驾车型（A1/A2/B1/B2/C1）"
+// @Param status query string false "状态（active/on_leave/disabled）"
+// @Param keyword query string false "姓名关键字"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认20，最大100"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"total":1,"page":1,"page_size":20,"drivers":[]}}
+// @Router /drivers [get]
+func (h *DriverHandler) ListDrivers(c *gin.Context) {
+	var req service.DriverListReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	drivers, total, err := h.driverSvc.ListDrivers(&req)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{
+		"total":     total,
+		"page":      req.Page,
+		"page_size": req.PageSize,
+		"drivers":   drivers,
+	})
+}
+
+// DeleteDriver 删除司机档案
+// @Summary 删除司机档案
+// @Tags 司机管理
+// @Produce json
+// @Param driver_id path string true "司机ID"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"driver_id":"xxx"}}
+// @Failure 409 {object} gin.H{"code":409,"msg":"司机仍有未完成的运输任务，无法删除","data":nil}
+// @Router /drivers/{driver_id} [delete]
+func (h *DriverHandler) DeleteDriver(c *gin.Context) {
+	driverID := c.Param("driver_id")
+	if err := h.driverSvc.DeleteDriver(driverID); err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"driver_id": driverID})
+}
+
+// Availability 查询司机可用性
+// @Summary 查询司机可用性
+// @Description 返回启用中司机的进行中任务、当日已排与剩余驾驶时长及是否可指派（无进行中任务、时长足够、准驾车型相符），可指派的在前
+// @Tags 司机管理
+// @Produce json
+// @Param home_node_id query string false "常驻节点ID"
+// @Param vehicle_id query string false "拟使用车辆ID（校验准驾车型）"
+// @Param hours query number false "拟指派任务驾驶时长（小时）"
+// @Param only_available query bool false "仅返回可指派司机"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"drivers":[]}}
+// @Router /drivers/availability [get]
+func (h *DriverHandler) Availability(c *gin.Context) {
+	var req service.DriverAvailabilityReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	drivers, err := h.driverSvc.DriverAvailability(&req)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"drivers": drivers})
+}
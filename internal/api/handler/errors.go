@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+// errorStatus 将领域错误映射为HTTP状态码，未识别的错误按服务器错误处理
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, errno.ErrTransportTaskConflict), errors.Is(err, errno.ErrDeliveryTaskConflict),
+		errors.Is(err, errno.ErrAbnormalRecordConflict), errors.Is(err, errno.ErrPackageDuplicated),
+		errors.Is(err, errno.ErrNodeExists), errors.Is(err, errno.ErrNodeInUse),
+		errors.Is(err, errno.ErrCourierCapacityExceeded), errors.Is(err, errno.ErrPackageBoundToDeliveryTask),
+		errors.Is(err, errno.ErrVehicleExists), errors.Is(err, errno.ErrVehicleInUse),
+		errors.Is(err, errno.ErrVehicleOverweight), errors.Is(err, errno.ErrVehicleOverVolume),
+		errors.Is(err, errno.ErrDriverInUse), errors.Is(err, errno.ErrDriverBusy), errors.Is(err, errno.ErrDriverHoursExceeded):
+		return http.StatusConflict
+	case errors.Is(err, errno.ErrTransportTaskNotFound), errors.Is(err, errno.ErrDeliveryTaskNotFound),
+		errors.Is(err, errno.ErrPackageNotFound), errors.Is(err, errno.ErrAbnormalRecordNotFound),
+		errors.Is(err, errno.ErrRouteNodeNotFound), errors.Is(err, errno.ErrNodeNotFound),
+		errors.Is(err, errno.ErrCourierAreaNotFound), errors.Is(err, errno.ErrVehicleNotFound),
+		errors.Is(err, errno.ErrDriverNotFound), errors.Is(err, errno.ErrTrackingVerifyFailed):
+		return http.StatusNotFound
+	case errors.Is(err, errno.ErrTransportTaskNotBelongToDriver), errors.Is(err, errno.ErrDeliveryTaskNotBelongToCourier),
+		errors.Is(err, errno.ErrForbidden), errors.Is(err, errno.ErrAbnormalNotAssignee):
+		return http.StatusForbidden
+	case errors.Is(err, errno.ErrParamInvalid),
+		errors.Is(err, errno.ErrTransportStatusInvalid), errors.Is(err, errno.ErrDeliveryStatusInvalid),
+		errors.Is(err, errno.ErrPackageStatusInvalid), errors.Is(err, errno.ErrPackageNotBindable),
+		errors.Is(err, errno.ErrTransportTaskNotAbnormal), errors.Is(err, errno.ErrDeliveryTaskNotAbnormal),
+		errors.Is(err, errno.ErrTransportTaskIsAbnormal), errors.Is(err, errno.ErrDeliveryTaskIsAbnormal),
+		errors.Is(err, errno.ErrAbnormalRecordStatusInvalid), errors.Is(err, errno.ErrAbnormalMethodInvalid),
+		errors.Is(err, errno.ErrAbnormalAssigneeInvalid),
+		errors.Is(err, errno.ErrRouteUnreachable), errors.Is(err, errno.ErrRouteMetricInvalid),
+		errors.Is(err, errno.ErrNodeTypeInvalid), errors.Is(err, errno.ErrNodeParentInvalid),
+		errors.Is(err, errno.ErrNodeDisabled),
+		errors.Is(err, errno.ErrCourierInvalid), errors.Is(err, errno.ErrCourierAreaInvalid),
+		errors.Is(err, errno.ErrCourierAreaMismatch),
+		errors.Is(err, errno.ErrDeliveryTaskNotSequenceable), errors.Is(err, errno.ErrPackageNotBindToDeliveryTask),
+		errors.Is(err, errno.ErrDeliveryTaskNotBindable), errors.Is(err, errno.ErrDeliveryTaskNotUnbindable),
+		errors.Is(err, errno.ErrPackageAlreadySigned), errors.Is(err, errno.ErrPackageNotSigned),
+		errors.Is(err, errno.ErrTransportTaskNotBindable), errors.Is(err, errno.ErrTransportTaskNotUnbindable),
+		errors.Is(err, errno.ErrTransferSameTask), errors.Is(err, errno.ErrPackageNotBindToTask),
+		errors.Is(err, errno.ErrVehicleUnavailable),
+		errors.Is(err, errno.ErrDriverInvalid), errors.Is(err, errno.ErrDriverUnavailable),
+		errors.Is(err, errno.ErrDriverLicenseMismatch), errors.Is(err, errno.ErrTrackingPhoneInvalid),
+		errors.Is(err, errno.ErrImportFileInvalid), errors.Is(err, errno.ErrImportEmpty),
+		errors.Is(err, errno.ErrImportTooManyRows), errors.Is(err, errno.ErrImportHeaderInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, errno.ErrImportTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, errno.ErrTooManyRequests), errors.Is(err, errno.ErrTrackingLocked):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
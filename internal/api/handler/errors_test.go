@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"并发冲突", errno.ErrTransportTaskConflict, http.StatusConflict},
+		{"包裹已绑定其他任务（包装后）", fmt.Errorf("包裹SF001（任务DT001）：%w", errno.ErrPackageBoundToDeliveryTask), http.StatusConflict},
+		{"资源不存在", errno.ErrPackageNotFound, http.StatusNotFound},
+		{"无权访问", errno.ErrTransportTaskNotBelongToDriver, http.StatusForbidden},
+		{"包裹状态不可绑定（包装后）", fmt.Errorf("包裹SF001状态为collected：%w", errno.ErrPackageNotBindable), http.StatusBadRequest},
+		{"导入行数超限（包装后）", fmt.Errorf("%w（10000行）", errno.ErrImportTooManyRows), http.StatusBadRequest},
+		{"导入文件过大（包装后）", fmt.Errorf("%w（10MB）", errno.ErrImportTooLarge), http.StatusRequestEntityTooLarge},
+		{"查询过于频繁", errno.ErrTooManyRequests, http.StatusTooManyRequests},
+		{"运单已锁定", errno.ErrTrackingLocked, http.StatusTooManyRequests},
+		{"未识别的错误", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorStatus(tt.err); got != tt.want {
+				t.Fatalf("errorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
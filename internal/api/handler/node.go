@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/LFrankl/fdu-lab3/internal/service"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+	"github.com/gin-gonic/gin"
+)
+
+// NodeHandler 网络节点API处理
+type NodeHandler struct {
+	nodeSvc *service.NodeSvc
+}
+
+func NewNodeHandler() *NodeHandler {
+	return &NodeHandler{
+		nodeSvc: service.NewNodeSvc(),
+	}
+}
+
+// CreateNode 创建网络节点
+// @Summary 创建网络节点
+// @Description 管理员登记分拣中心/网点（编码、名称唯一；网点须挂靠分拣中心；未提供坐标时按地址解析）
+// @Tags 网络节点管理
+// @Accept json
+// @Produce json
+// @Param request body service.NodeReq true "节点信息"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"node":{}}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"网络节点类型不合法","data":nil}
+// @Failure 409 {object} gin.H{"code":409,"msg":"网络节点名称或编码已存在","data":nil}
+// @Router /nodes [post]
+func (h *NodeHandler) CreateNode(c *gin.Context) {
+	var req service.NodeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	node, err := h.nodeSvc.CreateNode(&req)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"node": node})
+}
+
+// ListNodes 查询网络节点
+// @Summary 查询网络节点
+// @Description 按类型、上级分拣中心、服务区县、名称/编码关键字分页查询网络节点
+// @Tags 网络节点管理
+// @Produce json
+// @Param type query string false "节点类型（sorting_center/station）"
+// @Param parent_id query string false "上级分拣中心ID"
+// @Param district query string false "服务区县"
+// @Param keyword query string false "名称/编码关键字"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认20，最大100"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"total":1,"page":1,"page_size":20,"nodes":[]}}
+// @Router /nodes [get]
+func (h *NodeHandler) ListNodes(c *gin.Context) {
+	var req service.NodeListReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	nodes, total, err := h.nodeSvc.ListNodes(&req)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{
+		"total":     total,
+		"page":      req.Page,
+		"page_size": req.PageSize,
+		"nodes":     nodes,
+	})
+}
+
+// GetNode 查询网络节点详情
+// @Summary 查询网络节点详情
+// @Tags 网络节点管理
+// @Produce json
+// @Param node_id path string true "节点ID"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"node":{}}}
+// @Failure 404 {object} gin.H{"code":404,"msg":"网络节点不存在","data":nil}
+// @Router /nodes/{node_id} [get]
+func (h *NodeHandler) GetNode(c *gin.Context) {
+	node, err := h.nodeSvc.GetNode(c.Param("node_id"))
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"node": node})
+}
+
+// UpdateNode 更新网络节点
+// @Summary 更新网络节点
+// @Description 管理员整体更新节点信息（含停用：status=disabled，停用节点不可再被新任务引用）
+// @Tags 网络节点管理
+// @Accept json
+// @Produce json
+// @Param node_id path string true "节点ID"
+// @Param request body service.NodeReq true "节点信息"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"node":{}}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"网点须挂靠已存在的分拣中心","data":nil}
+// @Failure 404 {object} gin.H{"code":404,"msg":"网络节点不存在","data":nil}
+// @Failure 409 {object} gin.H{"code":409,"msg":"网络节点名称或编码已存在","data":nil}
+// @Router /nodes/{node_id} [put]
+func (h *NodeHandler) UpdateNode(c *gin.Context) {
+	var req service.NodeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	node, err := h.nodeSvc.UpdateNode(c.Param("node_id"), &req)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"node": node})
+}
+
+// DeleteNode 删除网络节点
+// @Summary 删除网络节点
+// @Description 管理员删除节点（软删除），仍有挂靠网点、未完成的运输/派送任务、归属派送员或常驻司机的节点不可删除
+// @Tags 网络节点管理
+// @Produce json
+// @Param node_id path string true "节点ID"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"node_id":"xxx"}}
+// @Failure 404 {object} gin.H{"code":404,"msg":"网络节点不存在","data":nil}
+// @Failure 409 {object} gin.H{"code":409,"msg":"网络节点仍被网点、任务、派送员或司机引用，无法删除：2个挂靠网点","data":nil}
+// @Router /nodes/{node_id} [delete]
+func (h *NodeHandler) DeleteNode(c *gin.Context) {
+	nodeID := c.Param("node_id")
+	if err := h.nodeSvc.DeleteNode(nodeID); err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"node_id": nodeID})
+}
@@ -1,10 +1,15 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
+	"github.com/LFrankl/fdu-lab3/internal/api/middleware"
 	"github.com/LFrankl/fdu-lab3/internal/model"
 	"github.com/LFrankl/fdu-lab3/internal/service"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
 	"github.com/gin-gonic/gin"
 )
 
@@ -50,6 +55,7 @@ func (h *PackageHandler) Sorting(c *gin.Context) {
 			"code": http.StatusBadRequest,
 			"msg":  "packageId不许为空",
 		})
+		return
 	}
 	_, err := h.pkgService.GetPackageDetail(pkgId)
 	if err != nil {
@@ -57,6 +63,7 @@ func (h *PackageHandler) Sorting(c *gin.Context) {
 			"code": http.StatusInternalServerError,
 			"msg":  err.Error(),
 		})
+		return
 	}
 	/*
 		这里模拟一下三种错误的情况，然后写好对应的reason和handle
@@ -69,10 +76,15 @@ func (h *PackageHandler) Sorting(c *gin.Context) {
 	//
 	err = h.pkgService.ChangeStatus(pkgId, "sorted")
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"code": http.StatusInternalServerError,
+		code := http.StatusInternalServerError
+		if errors.Is(err, errno.ErrPackageStatusInvalid) {
+			code = http.StatusBadRequest
+		}
+		c.AbortWithStatusJSON(code, gin.H{
+			"code": code,
 			"msg":  err.Error(),
 		})
+		return
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"code": http.StatusOK,
@@ -80,32 +92,86 @@ func (h *PackageHandler) Sorting(c *gin.Context) {
 	})
 }
 
-//请求体
-
-type changeStatusRequest struct {
-	//PackageId string `json:"package_id" binding:"required"`
+// ChangeStatusRequest 人工变更包裹状态请求体
+type ChangeStatusRequest struct {
 	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
 }
 
 // ChangePackageStatus 改变包裹状态
-// package_id
+// @Summary 人工变更包裹状态
+// @Description 运营人员人工纠正包裹状态，按包裹状态机校验流转合法性，记录审计日志并生成对应轨迹
+// @Tags 包裹管理
+// @Accept json
+// @Produce json
+// @Param package_id path string true "运单号"
+// @Param request body ChangeStatusRequest true "目标状态及变更原因"
+// @Security BearerAuth
+// @Param node_name header string true "节点名称"
+// @Param node_address header string true "节点地址"
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"package_id":"xxx","status":"sorted"}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"参数错误/状态流转不合法","data":nil}
+// @Failure 404 {object} gin.H{"code":404,"msg":"包裹不存在","data":nil}
+// @Failure 500 {object} gin.H{"code":500,"msg":"服务器错误","data":nil}
+// @Router /packages/{package_id}/status [post]
 func (h *PackageHandler) ChangePackageStatus(c *gin.Context) {
 	pkgId := c.Param("package_id")
 	if pkgId == "" {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
-			"code": http.StatusBadRequest,
-			"msg":  "id不能为空",
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "运单号不能为空",
+			"data": nil,
+		})
+		return
+	}
+
+	var req ChangeStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "参数错误：" + err.Error(),
+			"data": nil,
 		})
+		return
 	}
-	err := c.ShouldBindBodyWithJSON(&changeStatusRequest{})
+
+	// 获取请求头信息（操作人取自认证身份）
+	nodeName := c.GetHeader("node_name")
+	nodeAddr := c.GetHeader("node_address")
+	if nodeName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 400,
+			"msg":  "节点信息不能为空",
+			"data": nil,
+		})
+		return
+	}
+
+	pkg, err := h.pkgService.ChangeStatusWithAudit(middleware.CurrentIdentity(c), pkgId, req.Status, req.Reason, nodeName, nodeAddr)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
-			"code": http.StatusBadRequest,
-			"msg":  err.Error(),
+		code := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, errno.ErrPackageStatusInvalid):
+			code = http.StatusBadRequest
+		case errors.Is(err, errno.ErrPackageNotFound):
+			code = http.StatusNotFound
+		}
+		c.JSON(code, gin.H{
+			"code": code,
+			"msg":  "变更状态失败：" + err.Error(),
+			"data": nil,
 		})
+		return
 	}
-	//调用service
 
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"package_id": pkg.PackageID,
+			"status":     pkg.Status,
+		},
+	})
 }
 
 // CreatePackage 创建包裹（揽收）
@@ -115,7 +181,7 @@ func (h *PackageHandler) ChangePackageStatus(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param request body CreatePackageRequest true "包裹信息"
-// @Param operator header string true "操作人"
+// @Security BearerAuth
 // @Param node_name header string true "节点名称"
 // @Param node_address header string true "节点地址"
 // @Success 200 {object} gin.H{"code":0,"msg":"success","data":{}}
@@ -133,15 +199,14 @@ func (h *PackageHandler) CreatePackage(c *gin.Context) {
 		return
 	}
 
-	// 获取请求头信息
-	operator := c.GetHeader("operator")
+	// 获取请求头信息（操作人取自认证身份）
 	nodeName := c.GetHeader("node_name")
 	nodeAddr := c.GetHeader("node_address")
 
-	if operator == "" || nodeName == "" || nodeAddr == "" {
+	if nodeName == "" || nodeAddr == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code": 400,
-			"msg":  "操作人/节点信息不能为空",
+			"msg":  "节点信息不能为空",
 			"data": nil,
 		})
 		return
@@ -165,7 +230,7 @@ func (h *PackageHandler) CreatePackage(c *gin.Context) {
 	}
 
 	// 创建包裹
-	result, err := h.pkgService.CreatePackage(pkg, operator, nodeName, nodeAddr)
+	result, err := h.pkgService.CreatePackage(middleware.CurrentIdentity(c), pkg, nodeName, nodeAddr)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code": 500,
@@ -184,7 +249,7 @@ func (h *PackageHandler) CreatePackage(c *gin.Context) {
 
 // GetPackageDetail 获取包裹详情
 // @Summary 获取包裹详情
-// @Description 根据运单号查询包裹详情及轨迹
+// @Description 根据运单号查询包裹详情及轨迹，含按当前状态预测的送达时间窗口与置信度
 // @Tags 包裹管理
 // @Accept json
 // @Produce json
@@ -221,6 +286,171 @@ func (h *PackageHandler) GetPackageDetail(c *gin.Context) {
 	})
 }
 
+// ETAAccuracyRequest 送达时间预测准确率查询参数
+type ETAAccuracyRequest struct {
+	Days int `form:"days"`
+}
+
+// GetETAAccuracy 查询送达时间预测准确率
+// @Summary 查询送达时间预测准确率
+// @Description 统计近days天内已签收包裹的全部预测记录：实际送达落在预测窗口内的比例与平均绝对误差，按置信度分组
+// @Tags 包裹管理
+// @Produce json
+// @Param days query int false "统计天数，默认30"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"accuracy":{}}}
+// @Router /packages/eta/accuracy [get]
+func (h *PackageHandler) GetETAAccuracy(c *gin.Context) {
+	var req ETAAccuracyRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	report, err := h.pkgService.ETAAccuracy(req.Days)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"accuracy": report})
+}
+
+// SearchPackages 检索包裹
+// @Summary 检索包裹
+// @Description 按收/寄件人电话（精确）、收件人姓名（前缀）、收件城市/区县、状态（逗号分隔多个）、所在节点、创建日期检索包裹。
+// @Description 按创建或更新时间排序，游标分页：has_more为true时携带next_cursor及相同条件获取下一页
+// @Tags 包裹管理
+// @Produce json
+// @Param receiver_phone query string false "收件人电话"
+// @Param sender_phone query string false "寄件人电话"
+// @Param receiver_name query string false "收件人姓名前缀"
+// @Param receiver_city query string false "收件城市"
+// @Param receiver_district query string false "收件区县"
+// @Param status query string false "包裹状态，多个以逗号分隔"
+// @Param current_node_id query string false "当前所在节点ID"
+// @Param start_date query string false "创建日期起（yyyy-MM-dd）"
+// @Param end_date query string false "创建日期止（yyyy-MM-dd，含当天）"
+// @Param sort query string false "排序字段created_at/updated_at，默认created_at"
+// @Param order query string false "排序方向desc/asc，默认desc"
+// @Param cursor query string false "上一页返回的next_cursor"
+// @Param page_size query int false "每页条数，默认20，最大100"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"packages":[],"page_size":20,"has_more":true,"next_cursor":""}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"参数无效","data":nil}
+// @Router /packages [get]
+func (h *PackageHandler) SearchPackages(c *gin.Context) {
+	var req service.PackageSearchReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	result, err := h.pkgService.SearchPackages(&req)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{
+		"packages":    result.Packages,
+		"page_size":   result.PageSize,
+		"has_more":    result.HasMore,
+		"next_cursor": result.NextCursor,
+	})
+}
+
+// ImportPackages 批量导入包裹
+// @Summary 批量导入包裹
+// @Description 上传CSV或XLSX（取第一个工作表）批量创建包裹，表头为创建包裹请求体的字段名（sender_name、receiver_phone、weight等，不区分大小写、顺序不限）。
+// @Description 逐行校验后分批写入包裹与揽收轨迹，返回逐行结果CSV：原始列后追加package_id、result（created/failed）、error；汇总数见响应头X-Import-Total/X-Import-Created/X-Import-Failed
+// @Tags 包裹管理
+// @Accept multipart/form-data
+// @Produce text/csv
+// @Param file formData file true "导入文件（.csv/.xlsx）"
+// @Param node_name header string true "节点名称"
+// @Param node_address header string true "节点地址"
+// @Security BearerAuth
+// @Success 200 {file} file "逐行导入结果CSV"
+// @Failure 400 {object} gin.H{"code":400,"msg":"导入文件表头缺少必填列","data":nil}
+// @Failure 413 {object} gin.H{"code":413,"msg":"导入文件大小超过上限（10MB）","data":nil}
+// @Router /packages/import [post]
+func (h *PackageHandler) ImportPackages(c *gin.Context) {
+	nodeName := c.GetHeader("node_name")
+	nodeAddr := c.GetHeader("node_address")
+	if nodeName == "" || nodeAddr == "" {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	// 限制请求体大小（文件上限外预留1MB给表单字段与分隔符），超限时不再继续接收
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.ImportMaxBytes+1<<20)
+	fh, err := c.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		ResponseError(c, http.StatusRequestEntityTooLarge, fmt.Errorf("%w（%dMB）", errno.ErrImportTooLarge, service.ImportMaxBytes>>20))
+		return
+	}
+	if err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrImportFileInvalid)
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrImportFileInvalid)
+		return
+	}
+	defer f.Close()
+	rows, err := service.ReadImportFile(fh.Filename, f)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+
+	result, err := h.pkgService.ImportPackages(middleware.CurrentIdentity(c), rows, nodeName, nodeAddr)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	data, err := result.CSV()
+	if err != nil {
+		ResponseError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="import_result.csv"`)
+	c.Header("X-Import-Total", strconv.Itoa(result.Total))
+	c.Header("X-Import-Created", strconv.Itoa(result.Created))
+	c.Header("X-Import-Failed", strconv.Itoa(result.Failed))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+}
+
+// PublicTrackingRequest 公开查件参数
+type PublicTrackingRequest struct {
+	PhoneSuffix string `form:"phone_suffix" binding:"required"`
+}
+
+// PublicTracking 公开查件
+// @Summary 公开查件
+// @Description 寄/收件人凭运单号与手机号后4位查询物流进度，无需登录；姓名与手机号脱敏，不返回详细地址、操作人及内部备注。
+// @Description 运单不存在与手机号不匹配返回同一错误；按客户端IP限流，校验失败次数过多时暂时拒绝查询
+// @Tags 公开查件
+// @Produce json
+// @Param package_id path string true "运单号"
+// @Param phone_suffix query string true "收件人或寄件人手机号后4位"
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"tracking":{}}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"请输入收件人或寄件人手机号后4位","data":nil}
+// @Failure 404 {object} gin.H{"code":404,"msg":"运单号或手机号后4位不正确","data":nil}
+// @Failure 429 {object} gin.H{"code":429,"msg":"查询过于频繁，请稍后再试","data":nil}
+// @Router /tracking/{package_id} [get]
+func (h *PackageHandler) PublicTracking(c *gin.Context) {
+	var req PublicTrackingRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrTrackingPhoneInvalid)
+		return
+	}
+	tracking, err := h.pkgService.PublicTracking(c.Param("package_id"), req.PhoneSuffix)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"tracking": tracking})
+}
+
 // HandleSortingAbnormalRequest 分拣异常处理请求
 type HandleSortingAbnormalRequest struct {
 	Reason string `json:"reason" binding:"required"`
@@ -234,7 +464,7 @@ type HandleSortingAbnormalRequest struct {
 // @Produce json
 // @Param package_id path string true "运单号"
 // @Param request body HandleSortingAbnormalRequest true "异常信息"
-// @Param handler header string true "处理人"
+// @Security BearerAuth
 // @Success 200 {object} gin.H{"code":0,"msg":"success","data":nil}
 // @Failure 400 {object} gin.H{"code":400,"msg":"参数错误","data":nil}
 // @Failure 500 {object} gin.H{"code":500,"msg":"服务器错误","data":nil}
@@ -260,17 +490,7 @@ func (h *PackageHandler) HandleSortingAbnormal(c *gin.Context) {
 		return
 	}
 
-	handler := c.GetHeader("handler")
-	if handler == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code": 400,
-			"msg":  "处理人不能为空",
-			"data": nil,
-		})
-		return
-	}
-
-	if err := h.pkgService.HandleSortingAbnormal(packageID, req.Reason, handler); err != nil {
+	if err := h.pkgService.HandleSortingAbnormal(middleware.CurrentIdentity(c), packageID, req.Reason); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code": 500,
 			"msg":  "处理异常失败：" + err.Error(),
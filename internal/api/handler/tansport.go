@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/LFrankl/fdu-lab3/internal/api/middleware"
 	"github.com/LFrankl/fdu-lab3/internal/service"
 	"github.com/LFrankl/fdu-lab3/pkg/errno"
 	"github.com/gin-gonic/gin"
@@ -27,7 +28,7 @@ func NewTransportHandler() *TransportHandler {
 // @Accept json
 // @Produce json
 // @Param request body CreateTransportTaskRequest true "运输任务信息"
-// @Param operator header string true "操作人（调度员ID）"
+// @Security BearerAuth
 // @Success 200 {object} gin.H{"code":0,"msg":"任务创建成功","data":{"task_id":"TRAN20251208120000001","status":"pending"}}
 // @Failure 400 {object} gin.H{"code":400,"msg":"参数错误","data":nil}
 // @Failure 500 {object} gin.H{"code":500,"msg":"服务器错误","data":nil}
@@ -42,7 +43,7 @@ func (h *TransportHandler) CreateTask(c *gin.Context) {
 	}
 	task, err := h.transportSvc.CreateTransportTask(&req)
 	if err != nil {
-		ResponseError(c, http.StatusInternalServerError, err)
+		ResponseError(c, errorStatus(err), err)
 		return
 	}
 	ResponseSuccess(c, gin.H{"task_id": task.TaskID, "status": task.Status})
@@ -56,7 +57,7 @@ func (h *TransportHandler) CreateTask(c *gin.Context) {
 // @Produce json
 // @Param task_id path string true "运输任务ID"
 // @Param request body ChangeTransportTaskStatusRequest true "新状态信息"
-// @Param operator header string true "操作人ID（司机/调度员）"
+// @Security BearerAuth
 // @Success 200 {object} gin.H{"code":0,"msg":"运输任务状态已更新","data":{"task_id":"xxx","new_status":"transporting"}}
 // @Failure 400 {object} gin.H{"code":400,"msg":"参数错误/状态流转不合法","data":nil}
 // @Failure 404 {object} gin.H{"code":404,"msg":"运输任务不存在","data":nil}
@@ -71,8 +72,8 @@ func (h *TransportHandler) ChangeTaskStatus(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
 		return
 	}
-	if err := h.transportSvc.ChangeTaskStatus(taskID, req.NewStatus); err != nil {
-		ResponseError(c, http.StatusInternalServerError, err)
+	if err := h.transportSvc.ChangeTaskStatus(middleware.CurrentIdentity(c), taskID, req.NewStatus); err != nil {
+		ResponseError(c, errorStatus(err), err)
 		return
 	}
 	ResponseSuccess(c, gin.H{"msg": "运输任务状态已更新"})
@@ -80,14 +81,14 @@ func (h *TransportHandler) ChangeTaskStatus(c *gin.Context) {
 
 // BindPackages 绑定包裹到运输任务
 // @Summary 绑定包裹到运输任务
-// @Description 运输调度员将分拣完成的包裹绑定到指定运输任务（仅待执行/运输中任务可绑定）
+// @Description 运输调度员将分拣完成的包裹绑定到指定运输任务（仅待执行/运输中任务可绑定，已绑定包裹去重），返回本次新绑定的包裹数与绑定后的包裹数量
 // @Tags 运输任务管理
 // @Accept json
 // @Produce json
 // @Param task_id path string true "运输任务ID"
 // @Param request body BindPackagesRequest true "绑定的包裹ID列表"
-// @Param operator header string true "操作人（调度员ID）"
-// @Success 200 {object} gin.H{"code":0,"msg":"包裹绑定成功","data":{"task_id":"xxx","package_count":85}}
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"包裹绑定成功","data":{"bound_count":5,"package_count":85}}
 // @Failure 400 {object} gin.H{"code":400,"msg":"参数错误/任务状态不可绑定","data":nil}
 // @Failure 500 {object} gin.H{"code":500,"msg":"服务器错误","data":nil}
 // @Router /transport/tasks/{task_id}/packages/bind [post]
@@ -100,11 +101,85 @@ func (h *TransportHandler) BindPackages(c *gin.Context) {
 		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
 		return
 	}
-	if err := h.transportSvc.BindPackagesToTask(taskID, req.PackageIDs); err != nil {
-		ResponseError(c, http.StatusInternalServerError, err)
+	bound, count, err := h.transportSvc.BindPackagesToTask(taskID, req.PackageIDs)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"msg": "包裹绑定成功", "bound_count": bound, "package_count": count})
+}
+
+// GetTaskLoad 查询运输任务装载率
+// @Summary 查询运输任务装载率
+// @Description 返回任务装载重量/体积（以绑定包裹重量及长×宽×高统计）与车辆载重/容积上限及装载率百分比
+// @Tags 运输任务管理
+// @Produce json
+// @Param task_id path string true "运输任务ID"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"load":{}}}
+// @Failure 404 {object} gin.H{"code":404,"msg":"车辆不存在","data":nil}
+// @Router /transport/tasks/{task_id}/load [get]
+func (h *TransportHandler) GetTaskLoad(c *gin.Context) {
+	load, err := h.transportSvc.GetTaskLoad(middleware.CurrentIdentity(c), c.Param("task_id"))
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
 		return
 	}
-	ResponseSuccess(c, gin.H{"msg": "包裹绑定成功", "package_count": len(req.PackageIDs)})
+	ResponseSuccess(c, gin.H{"load": load})
+}
+
+// UnbindPackages 从运输任务解绑包裹
+// @Summary 从运输任务解绑包裹
+// @Description 调度员从待执行/运输中/异常的运输任务解绑包裹，解绑包裹恢复为已分拣待重新调度（已到站包裹不可解绑）
+// @Tags 运输任务管理
+// @Accept json
+// @Produce json
+// @Param task_id path string true "运输任务ID"
+// @Param request body BindPackagesRequest true "解绑的包裹ID列表"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"msg":"包裹解绑成功","package_count":80}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"运输任务当前状态不可解绑包裹","data":nil}
+// @Router /transport/tasks/{task_id}/packages/unbind [post]
+func (h *TransportHandler) UnbindPackages(c *gin.Context) {
+	var req BindPackagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	count, err := h.transportSvc.UnbindPackagesFromTask(middleware.CurrentIdentity(c), c.Param("task_id"), req.PackageIDs)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"msg": "包裹解绑成功", "package_count": count})
+}
+
+// TransferPackages 运输任务间转运包裹
+// @Summary 运输任务间转运包裹
+// @Description 调度员将指定包裹（或all=true时全部包裹）从当前运输任务原子转至另一运输任务（如车辆故障换车），同步两任务包裹数量与包裹状态并记录交接轨迹
+// @Tags 运输任务管理
+// @Accept json
+// @Produce json
+// @Param task_id path string true "转出运输任务ID"
+// @Param request body service.TransferPackagesReq true "转入任务及包裹"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"transfer":{}}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"转出与转入运输任务不能相同","data":nil}
+// @Failure 404 {object} gin.H{"code":404,"msg":"运输任务不存在","data":nil}
+// @Failure 409 {object} gin.H{"code":409,"msg":"运输任务已被他人修改，请刷新后重试","data":nil}
+// @Router /transport/tasks/{task_id}/packages/transfer [post]
+func (h *TransportHandler) TransferPackages(c *gin.Context) {
+	var req service.TransferPackagesReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	result, err := h.transportSvc.TransferPackages(middleware.CurrentIdentity(c), c.Param("task_id"), &req)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"transfer": result})
 }
 
 // GetDriverTaskPackages 司机查询任务包裹列表
@@ -114,17 +189,16 @@ func (h *TransportHandler) BindPackages(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param task_id path string true "运输任务ID"
-// @Param driver_id header string true "司机ID（身份认证）"
+// @Security BearerAuth
 // @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"task_id":"xxx","package_count":85,"packages":[]}}
 // @Failure 403 {object} gin.H{"code":403,"msg":"任务不属于该司机","data":nil}
 // @Failure 500 {object} gin.H{"code":500,"msg":"服务器错误","data":nil}
 // @Router /transport/tasks/{task_id}/packages [get]
 func (h *TransportHandler) GetDriverTaskPackages(c *gin.Context) {
-	driverID := c.GetHeader("driver_id") // 从请求头获取司机ID（身份认证后）
 	taskID := c.Param("task_id")
-	packages, err := h.transportSvc.GetDriverTaskPackages(driverID, taskID)
+	packages, err := h.transportSvc.GetDriverTaskPackages(middleware.CurrentIdentity(c), taskID)
 	if err != nil {
-		ResponseError(c, http.StatusInternalServerError, err)
+		ResponseError(c, errorStatus(err), err)
 		return
 	}
 	ResponseSuccess(c, gin.H{
@@ -134,6 +208,44 @@ func (h *TransportHandler) GetDriverTaskPackages(c *gin.Context) {
 	})
 }
 
+// ListDriverTasks 司机任务收件箱
+// @Summary 司机任务列表
+// @Description 司机查询本人承接的运输任务，支持按状态、创建日期范围筛选及分页；调度员可查询任意司机
+// @Tags 司机端-运输任务
+// @Accept json
+// @Produce json
+// @Param driver_id path string true "司机ID"
+// @Param status query string false "任务状态"
+// @Param start_date query string false "创建日期起（YYYY-MM-DD）"
+// @Param end_date query string false "创建日期止（YYYY-MM-DD，含当天）"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认20，最大100"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"driver_id":"xxx","total":1,"page":1,"page_size":20,"tasks":[]}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"参数无效","data":nil}
+// @Failure 403 {object} gin.H{"code":403,"msg":"当前角色无权执行该操作","data":nil}
+// @Router /transport/drivers/{driver_id}/tasks [get]
+func (h *TransportHandler) ListDriverTasks(c *gin.Context) {
+	driverID := c.Param("driver_id")
+	var req service.TaskListReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	tasks, total, err := h.transportSvc.ListDriverTasks(middleware.CurrentIdentity(c), driverID, &req)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{
+		"driver_id": driverID,
+		"total":     total,
+		"page":      req.Page,
+		"page_size": req.PageSize,
+		"tasks":     tasks,
+	})
+}
+
 // ReportAbnormal 上报运输异常
 // @Summary 上报运输异常
 // @Description 司机/调度员上报运输任务异常（如路线变更、车辆故障、包裹损坏等），标记任务为异常状态并同步包裹状态
@@ -142,7 +254,7 @@ func (h *TransportHandler) GetDriverTaskPackages(c *gin.Context) {
 // @Produce json
 // @Param task_id path string true "运输任务ID"
 // @Param request body ReportTransportAbnormalRequest true "运输异常信息"
-// @Param operator header string true "操作人ID（司机/调度员）"
+// @Security BearerAuth
 // @Success 200 {object} gin.H{"code":0,"msg":"运输异常已上报","data":{"task_id":"xxx","abnormal_type":"route_change"}}
 // @Failure 400 {object} gin.H{"code":400,"msg":"参数错误","data":nil}
 // @Failure 404 {object} gin.H{"code":404,"msg":"运输任务不存在","data":nil}
@@ -152,20 +264,81 @@ func (h *TransportHandler) ReportAbnormal(c *gin.Context) {
 	taskID := c.Param("task_id")
 	var req struct {
 		AbnormalType string `json:"abnormal_type"`
-		Reason       string `json:"reason"`
-		Handler      string `json:"handler"`
+		Reason       string `json:"reason" binding:"max=255"` // 与异常记录/包裹异常原因列长度一致
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
 		return
 	}
-	if err := h.transportSvc.ReportTransportAbnormal(taskID, req.AbnormalType, req.Reason, req.Handler); err != nil {
-		ResponseError(c, http.StatusInternalServerError, err)
+	if err := h.transportSvc.ReportTransportAbnormal(middleware.CurrentIdentity(c), taskID, req.AbnormalType, req.Reason); err != nil {
+		ResponseError(c, errorStatus(err), err)
 		return
 	}
 	ResponseSuccess(c, gin.H{"msg": "运输异常已上报"})
 }
 
+// PlanRoute 路径规划
+// @Summary 路径规划
+// @Description 在分拣中心/网点路网上按距离、时长或成本计算起止节点间的最优多跳路线
+// @Tags 运输任务管理
+// @Produce json
+// @Param from query string true "起点节点名称"
+// @Param to query string true "终点节点名称"
+// @Param metric query string false "规划指标（distance/time/cost），默认distance"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"route":{"nodes":[],"distance":176,"duration":150,"cost":380},"route_json":"[...]"}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"起止节点之间不存在可达路线","data":nil}
+// @Failure 404 {object} gin.H{"code":404,"msg":"路网中不存在该节点","data":nil}
+// @Router /transport/routes/plan [get]
+func (h *TransportHandler) PlanRoute(c *gin.Context) {
+	route, err := h.transportSvc.PlanRoute(c.Query("from"), c.Query("to"), c.Query("metric"))
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"route": route, "route_json": route.JSON()})
+}
+
+// BindPackagesRequest 绑定/解绑包裹请求参数
+type BindPackagesRequest struct {
+	PackageIDs []string `json:"package_ids"`
+}
+
+// HandleAbnormalRequest 异常处理请求参数
+type HandleAbnormalRequest struct {
+	HandleResult string `json:"handle_result" binding:"required,max=512"` // 与任务处理结果列长度一致，轨迹备注超长时截断
+	NewStatus    string `json:"new_status" binding:"required"`
+}
+
+// HandleAbnormal 处理运输异常
+// @Summary 处理运输异常
+// @Description 调度员记录异常处理结果，将任务恢复为运输中或直接完成，并同步恢复绑定包裹状态
+// @Tags 运输任务管理
+// @Accept json
+// @Produce json
+// @Param task_id path string true "运输任务ID"
+// @Param request body HandleAbnormalRequest true "异常处理结果"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"task_id":"xxx","status":"transporting"}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"运输任务非异常状态，无法处理异常","data":nil}
+// @Failure 404 {object} gin.H{"code":404,"msg":"运输任务不存在","data":nil}
+// @Failure 409 {object} gin.H{"code":409,"msg":"运输任务已被他人修改，请刷新后重试","data":nil}
+// @Failure 500 {object} gin.H{"code":500,"msg":"服务器错误","data":nil}
+// @Router /transport/tasks/{task_id}/abnormal/handle [post]
+func (h *TransportHandler) HandleAbnormal(c *gin.Context) {
+	taskID := c.Param("task_id")
+	var req HandleAbnormalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	if err := h.transportSvc.HandleTransportAbnormal(middleware.CurrentIdentity(c), taskID, req.HandleResult, req.NewStatus); err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"msg": "运输异常已处理", "task_id": taskID, "status": req.NewStatus})
+}
+
 func ResponseSuccess(c *gin.Context, data gin.H) {
 	c.JSON(http.StatusOK, gin.H{
 		"code": 0,
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/LFrankl/fdu-lab3/internal/service"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+	"github.com/gin-gonic/gin"
+)
+
+// VehicleHandler 车辆API处理
+type VehicleHandler struct {
+	vehicleSvc *service.VehicleSvc
+}
+
+func NewVehicleHandler() *VehicleHandler {
+	return &VehicleHandler{
+		vehicleSvc: service.NewVehicleSvc(),
+	}
+}
+
+// CreateVehicle 登记车辆
+// @Summary 登记车辆
+// @Description 登记运输车辆（车牌号唯一，载重kg、容积m³须为正）
+// @Tags 车辆管理
+// @Accept json
+// @Produce json
+// @Param request body service.VehicleReq true "车辆信息"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"vehicle":{}}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"参数无效","data":nil}
+// @Failure 409 {object} gin.H{"code":409,"msg":"车牌号已登记","data":nil}
+// @Router /vehicles [post]
+func (h *VehicleHandler) CreateVehicle(c *gin.Context) {
+	var req service.VehicleReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	vehicle, err := h.vehicleSvc.CreateVehicle(&req)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"vehicle": vehicle})
+}
+
+// ListVehicles 查询车辆
+// @Summary 查询车辆
+// @Description 按车型、状态、是否冷链、车牌关键字分页查询车辆
+// @Tags 车辆管理
+// @Produce json
+// @Param type query string false "车型"
+// @Param status query string false "车辆状态（active/maintenance/disabled）"
+// @Param cold_chain query bool false "是否冷链车"
+// @Param keyword query string false "车牌关键字"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认20，最大100"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"total":1,"page":1,"page_size":20,"vehicles":[]}}
+// @Router /vehicles [get]
+func (h *VehicleHandler) ListVehicles(c *gin.Context) {
+	var req service.VehicleListReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	vehicles, total, err := h.vehicleSvc.ListVehicles(&req)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{
+		"total":     total,
+		"page":      req.Page,
+		"page_size": req.PageSize,
+		"vehicles":  vehicles,
+	})
+}
+
+// GetVehicle 查询车辆详情
+// @Summary 查询车辆详情
+// @Tags 车辆管理
+// @Produce json
+// @Param vehicle_id path string true "车辆ID"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"vehicle":{}}}
+// @Failure 404 {object} gin.H{"code":404,"msg":"车辆不存在","data":nil}
+// @Router /vehicles/{vehicle_id} [get]
+func (h *VehicleHandler) GetVehicle(c *gin.Context) {
+	vehicle, err := h.vehicleSvc.GetVehicle(c.Param("vehicle_id"))
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"vehicle": vehicle})
+}
+
+// UpdateVehicle 更新车辆
+// @Summary 更新车辆
+// @Description 整体更新车辆信息（含维修/停用：status=maintenance/disabled，不可再指派新运输任务）
+// @Tags 车辆管理
+// @Accept json
+// @Produce json
+// @Param vehicle_id path string true "车辆ID"
+// @Param request body service.VehicleReq true "车辆信息"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"vehicle":{}}}
+// @Failure 400 {object} gin.H{"code":400,"msg":"参数无效","data":nil}
+// @Failure 404 {object} gin.H{"code":404,"msg":"车辆不存在","data":nil}
+// @Failure 409 {object} gin.H{"code":409,"msg":"车牌号已登记","data":nil}
+// @Router /vehicles/{vehicle_id} [put]
+func (h *VehicleHandler) UpdateVehicle(c *gin.Context) {
+	var req service.VehicleReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ResponseError(c, http.StatusBadRequest, errno.ErrParamInvalid)
+		return
+	}
+	vehicle, err := h.vehicleSvc.UpdateVehicle(c.Param("vehicle_id"), &req)
+	if err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"vehicle": vehicle})
+}
+
+// DeleteVehicle 删除车辆
+// @Summary 删除车辆
+// @Description 删除车辆（软删除），仍有未完成运输任务的车辆不可删除
+// @Tags 车辆管理
+// @Produce json
+// @Param vehicle_id path string true "车辆ID"
+// @Security BearerAuth
+// @Success 200 {object} gin.H{"code":0,"msg":"success","data":{"vehicle_id":"xxx"}}
+// @Failure 404 {object} gin.H{"code":404,"msg":"车辆不存在","data":nil}
+// @Failure 409 {object} gin.H{"code":409,"msg":"车辆仍有未完成的运输任务，无法删除","data":nil}
+// @Router /vehicles/{vehicle_id} [delete]
+func (h *VehicleHandler) DeleteVehicle(c *gin.Context) {
+	vehicleID := c.Param("vehicle_id")
+	if err := h.vehicleSvc.DeleteVehicle(vehicleID); err != nil {
+		ResponseError(c, errorStatus(err), err)
+		return
+	}
+	ResponseSuccess(c, gin.H{"vehicle_id": vehicleID})
+}
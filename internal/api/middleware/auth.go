@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/LFrankl/fdu-lab3/internal/auth"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+	"github.com/gin-gonic/gin"
+)
+
+// identityKey gin上下文中保存调用方身份的键
+const identityKey = "auth_identity"
+
+// Auth 认证中间件：校验Authorization: Bearer <token>并将调用方身份写入上下文
+func Auth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		tokenStr, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || tokenStr == "" {
+			abort(c, http.StatusUnauthorized, errno.ErrUnauthorized)
+			return
+		}
+		identity, err := auth.ParseToken(tokenStr)
+		if err != nil {
+			abort(c, http.StatusUnauthorized, err)
+			return
+		}
+		c.Set(identityKey, identity)
+		c.Next()
+	}
+}
+
+// RequireRoles 鉴权中间件：仅允许指定角色访问（管理员始终放行），需挂在Auth之后
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity := CurrentIdentity(c)
+		if identity == nil {
+			abort(c, http.StatusUnauthorized, errno.ErrUnauthorized)
+			return
+		}
+		if !identity.HasRole(roles...) {
+			abort(c, http.StatusForbidden, errno.ErrForbidden)
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentIdentity 获取认证中间件解析出的调用方身份，未认证时返回nil
+func CurrentIdentity(c *gin.Context) *auth.Identity {
+	v, ok := c.Get(identityKey)
+	if !ok {
+		return nil
+	}
+	identity, _ := v.(*auth.Identity)
+	return identity
+}
+
+// abort 以统一响应格式终止请求
+func abort(c *gin.Context, code int, err error) {
+	c.AbortWithStatusJSON(code, gin.H{
+		"code": code,
+		"msg":  err.Error(),
+		"data": nil,
+	})
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+	"github.com/gin-gonic/gin"
+)
+
+// windowCounter 按键（客户端IP、运单号等）统计固定时间窗口内的次数
+type windowCounter struct {
+	mu        sync.Mutex
+	window    time.Duration
+	counts    map[string]*windowCount
+	lastSweep time.Time
+}
+
+// windowCount 单个键在当前窗口内的计数
+type windowCount struct {
+	start time.Time
+	n     int
+}
+
+func newWindowCounter(window time.Duration) *windowCounter {
+	return &windowCounter{window: window, counts: make(map[string]*windowCount), lastSweep: time.Now()}
+}
+
+// add 计数加一并返回当前窗口内的次数
+func (w *windowCounter) add(key string) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now()
+	w.sweep(now)
+	cnt, ok := w.counts[key]
+	if !ok || now.Sub(cnt.start) >= w.window {
+		cnt = &windowCount{start: now}
+		w.counts[key] = cnt
+	}
+	cnt.n++
+	return cnt.n
+}
+
+// get 返回当前窗口内的次数
+func (w *windowCounter) get(key string) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	cnt, ok := w.counts[key]
+	if !ok || time.Since(cnt.start) >= w.window {
+		return 0
+	}
+	return cnt.n
+}
+
+// sweep 每个窗口清理一次过期计数，避免内存随访问的键增长
+func (w *windowCounter) sweep(now time.Time) {
+	if now.Sub(w.lastSweep) < w.window {
+		return
+	}
+	for key, cnt := range w.counts {
+		if now.Sub(cnt.start) >= w.window {
+			delete(w.counts, key)
+		}
+	}
+	w.lastSweep = now
+}
+
+// RateLimit 限流中间件：同一客户端IP每个窗口内最多limit次请求，超出返回429（limit<=0时不限流）
+func RateLimit(limit int, window time.Duration) gin.HandlerFunc {
+	counter := newWindowCounter(window)
+	return func(c *gin.Context) {
+		if limit > 0 && counter.add(c.ClientIP()) > limit {
+			abort(c, http.StatusTooManyRequests, errno.ErrTooManyRequests)
+			return
+		}
+		c.Next()
+	}
+}
+
+// ByClientIP 按客户端IP计数
+func ByClientIP(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// ByParam 按路径参数（如运单号）计数
+func ByParam(name string) func(c *gin.Context) string {
+	return func(c *gin.Context) string {
+		return strings.TrimSpace(c.Param(name))
+	}
+}
+
+// FailureLimit 失败限流中间件：同一键（由key从请求中取得）每个窗口内响应为指定状态码（如校验失败）的次数达到limit后，
+// 窗口内的后续请求直接返回429及lockedErr，用于阻止枚举（limit<=0时不限制；计数保存在进程内存中）
+func FailureLimit(limit int, window time.Duration, key func(c *gin.Context) string, lockedErr error, statuses ...int) gin.HandlerFunc {
+	counter := newWindowCounter(window)
+	failed := make(map[int]bool, len(statuses))
+	for _, s := range statuses {
+		failed[s] = true
+	}
+	return func(c *gin.Context) {
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+		k := key(c)
+		if counter.get(k) >= limit {
+			abort(c, http.StatusTooManyRequests, lockedErr)
+			return
+		}
+		c.Next()
+		if failed[c.Writer.Status()] {
+			counter.add(k)
+		}
+	}
+}
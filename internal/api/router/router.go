@@ -1,14 +1,28 @@
 package router
 
 import (
+	"log"
+	"net/http"
+	"time"
+
 	"github.com/LFrankl/fdu-lab3/config"
 	"github.com/LFrankl/fdu-lab3/internal/api/handler"
+	"github.com/LFrankl/fdu-lab3/internal/api/middleware"
+	"github.com/LFrankl/fdu-lab3/internal/auth"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+// 公开查件未配置时的限流阈值
+const (
+	defaultTrackingRateLimit = 20 // 每IP每分钟请求数
+	defaultTrackingFailLimit = 10 // 每IP每小时校验失败次数
+	defaultTrackingLockLimit = 5  // 每运单每小时校验失败次数
+)
+
 // SetupRouter 配置路由
 func SetupRouter() *gin.Engine {
 	// 设置运行模式
@@ -17,6 +31,10 @@ func SetupRouter() *gin.Engine {
 	}
 
 	r := gin.Default()
+	// 仅信任配置的反向代理转发的客户端IP，避免伪造X-Forwarded-For绕过限流
+	if err := r.SetTrustedProxies(config.Cfg.App.TrustedProxies); err != nil {
+		log.Fatalf("设置可信代理失败: %v", err)
+	}
 
 	// 中间件
 	//r.Use(middleware.Logger())
@@ -31,60 +49,189 @@ func SetupRouter() *gin.Engine {
 	})
 
 	// 初始化处理器
+	authHandler := handler.NewAuthHandler()
 	pkgHandler := handler.NewPackageHandler()
 	transportHandler := handler.NewTransportHandler()
 	deliveryHandler := handler.NewDeliveryHandler()
+	abnormalHandler := handler.NewAbnormalHandler()
+	nodeHandler := handler.NewNodeHandler()
+	courierHandler := handler.NewCourierHandler()
+	vehicleHandler := handler.NewVehicleHandler()
+	driverHandler := handler.NewDriverHandler()
 
 	// API路由组
 	api := r.Group("/api/v1")
 	{
+		// 认证（登录无需令牌）
+		authGroup := api.Group("/auth")
+		{
+			authGroup.POST("/login", authHandler.Login)
+			authGroup.POST("/accounts", middleware.Auth(), middleware.RequireRoles(auth.RoleAdmin), authHandler.CreateAccount)
+		}
+
+		// 公开查件（无需令牌，按客户端IP限流并限制校验失败次数，单个运单校验失败过多时锁定）
+		rateLimit, failLimit, lockLimit := config.Cfg.Tracking.RateLimit, config.Cfg.Tracking.FailLimit, config.Cfg.Tracking.LockLimit
+		if rateLimit <= 0 {
+			rateLimit = defaultTrackingRateLimit
+		}
+		if failLimit <= 0 {
+			failLimit = defaultTrackingFailLimit
+		}
+		if lockLimit <= 0 {
+			lockLimit = defaultTrackingLockLimit
+		}
+		api.GET("/tracking/:package_id",
+			middleware.RateLimit(rateLimit, time.Minute),
+			middleware.FailureLimit(failLimit, time.Hour, middleware.ByClientIP, errno.ErrTooManyRequests,
+				http.StatusBadRequest, http.StatusNotFound),
+			middleware.FailureLimit(lockLimit, time.Hour, middleware.ByParam("package_id"), errno.ErrTrackingLocked,
+				http.StatusNotFound),
+			pkgHandler.PublicTracking)
+
+		// 以下路由均需携带有效令牌，各子组声明允许的角色（管理员始终放行）
+		secured := api.Group("", middleware.Auth())
+
 		// 包裹管理
-		packages := api.Group("/packages")
+		packages := secured.Group("/packages")
 		{
-			packages.POST("", pkgHandler.CreatePackage)
 			packages.GET("/:package_id", pkgHandler.GetPackageDetail)
-			packages.POST("/sorting/:package_id", pkgHandler.Sorting)
-			packages.POST("/:package_id/abnormal/sorting", pkgHandler.HandleSortingAbnormal)
-			packages.POST("/:package_id/status", pkgHandler.ChangePackageStatus)
+			// 包裹检索（调度员）
+			packages.GET("", middleware.RequireRoles(auth.RoleDispatcher), pkgHandler.SearchPackages)
+			// 送达时间预测准确率（调度员）
+			packages.GET("/eta/accuracy", middleware.RequireRoles(auth.RoleDispatcher), pkgHandler.GetETAAccuracy)
+			packages.POST("", middleware.RequireRoles(auth.RoleCollector), pkgHandler.CreatePackage)
+			// 批量导入（商家运单CSV/XLSX）
+			packages.POST("/import", middleware.RequireRoles(auth.RoleCollector), pkgHandler.ImportPackages)
+
+			sorting := packages.Group("", middleware.RequireRoles(auth.RoleSorter))
+			{
+				sorting.POST("/sorting/:package_id", pkgHandler.Sorting)
+				sorting.POST("/:package_id/abnormal/sorting", pkgHandler.HandleSortingAbnormal)
+			}
+			// 人工纠正包裹状态（运营）
+			packages.POST("/:package_id/status", middleware.RequireRoles(auth.RoleAdmin), pkgHandler.ChangePackageStatus)
 		}
 
 		// 运输调度
-		transport := api.Group("/transport")
+		transport := secured.Group("/transport")
+		{
+			dispatch := transport.Group("", middleware.RequireRoles(auth.RoleDispatcher))
+			{
+				// 创建运输任务
+				dispatch.POST("/tasks", transportHandler.CreateTask)
+				// 绑定包裹到任务
+				dispatch.POST("/tasks/:task_id/packages/bind", transportHandler.BindPackages)
+				// 从任务解绑包裹
+				dispatch.POST("/tasks/:task_id/packages/unbind", transportHandler.UnbindPackages)
+				// 转运包裹至其他任务（如车辆故障换车）
+				dispatch.POST("/tasks/:task_id/packages/transfer", transportHandler.TransferPackages)
+				// 处理运输异常
+				dispatch.POST("/tasks/:task_id/abnormal/handle", transportHandler.HandleAbnormal)
+				// 路径规划
+				dispatch.GET("/routes/plan", transportHandler.PlanRoute)
+			}
+			onRoad := transport.Group("", middleware.RequireRoles(auth.RoleDispatcher, auth.RoleDriver))
+			{
+				// 变更任务状态
+				onRoad.PUT("/tasks/:task_id/status", transportHandler.ChangeTaskStatus)
+				// 司机查询任务包裹列表
+				onRoad.GET("/tasks/:task_id/packages", transportHandler.GetDriverTaskPackages)
+				// 查询任务装载率
+				onRoad.GET("/tasks/:task_id/load", transportHandler.GetTaskLoad)
+				// 上报运输异常
+				onRoad.POST("/tasks/:task_id/abnormal", transportHandler.ReportAbnormal)
+				// 司机任务收件箱
+				onRoad.GET("/drivers/:driver_id/tasks", transportHandler.ListDriverTasks)
+			}
+		}
+
+		// 网络节点（分拣中心/网点）：登录即可查询，维护仅管理员
+		nodes := secured.Group("/nodes")
+		{
+			nodes.GET("", nodeHandler.ListNodes)
+			nodes.GET("/:node_id", nodeHandler.GetNode)
+			nodes.POST("", middleware.RequireRoles(auth.RoleAdmin), nodeHandler.CreateNode)
+			nodes.PUT("/:node_id", middleware.RequireRoles(auth.RoleAdmin), nodeHandler.UpdateNode)
+			nodes.DELETE("/:node_id", middleware.RequireRoles(auth.RoleAdmin), nodeHandler.DeleteNode)
+		}
+
+		// 车辆：调度员/司机可查询，维护仅管理员
+		vehicles := secured.Group("/vehicles", middleware.RequireRoles(auth.RoleDispatcher, auth.RoleDriver))
 		{
-			// 创建运输任务
-			transport.POST("/tasks", transportHandler.CreateTask)
-			// 变更任务状态
-			transport.PUT("/tasks/:task_id/status", transportHandler.ChangeTaskStatus)
-			// 绑定包裹到任务
-			transport.POST("/tasks/:task_id/packages/bind", transportHandler.BindPackages)
-			// 司机查询任务包裹列表
-			transport.GET("/tasks/:task_id/packages", transportHandler.GetDriverTaskPackages)
-			// 上报运输异常
-			transport.POST("/tasks/:task_id/abnormal", transportHandler.ReportAbnormal)
+			vehicles.GET("", vehicleHandler.ListVehicles)
+			vehicles.GET("/:vehicle_id", vehicleHandler.GetVehicle)
+			vehicles.POST("", middleware.RequireRoles(auth.RoleAdmin), vehicleHandler.CreateVehicle)
+			vehicles.PUT("/:vehicle_id", middleware.RequireRoles(auth.RoleAdmin), vehicleHandler.UpdateVehicle)
+			vehicles.DELETE("/:vehicle_id", middleware.RequireRoles(auth.RoleAdmin), vehicleHandler.DeleteVehicle)
 		}
-		delivery := r.Group("/api/v1/delivery")
+
+		// 司机档案登记与排班查询（调度员）
+		drivers := secured.Group("/drivers", middleware.RequireRoles(auth.RoleDispatcher))
+		{
+			drivers.GET("", driverHandler.ListDrivers)
+			drivers.GET("/availability", driverHandler.Availability)
+			drivers.GET("/:driver_id", driverHandler.GetDriver)
+			drivers.PUT("/:driver_id", driverHandler.SetDriver)
+			drivers.DELETE("/:driver_id", driverHandler.DeleteDriver)
+		}
+
+		// 派送员服务范围登记与推荐（调度员）
+		couriers := secured.Group("/couriers", middleware.RequireRoles(auth.RoleDispatcher))
+		{
+			couriers.GET("/areas", courierHandler.ListAreas)
+			couriers.GET("/eligible", courierHandler.Eligible)
+			couriers.GET("/:courier_id/area", courierHandler.GetArea)
+			couriers.PUT("/:courier_id/area", courierHandler.SetArea)
+			couriers.DELETE("/:courier_id/area", courierHandler.DeleteArea)
+		}
+
+		// 异常处理台（分拣员/调度员查询与处理，指派/关闭/重新打开仅调度员）
+		abnormal := secured.Group("/abnormal-records", middleware.RequireRoles(auth.RoleSorter, auth.RoleDispatcher))
+		{
+			abnormal.GET("", abnormalHandler.ListRecords)
+			abnormal.GET("/:record_id", abnormalHandler.GetRecord)
+			abnormal.POST("/:record_id/resolve", abnormalHandler.ResolveRecord)
+
+			desk := abnormal.Group("", middleware.RequireRoles(auth.RoleDispatcher))
+			{
+				desk.POST("/:record_id/assign", abnormalHandler.AssignRecord)
+				desk.POST("/:record_id/close", abnormalHandler.CloseRecord)
+				desk.POST("/:record_id/reopen", abnormalHandler.ReopenRecord)
+			}
+		}
+
+		// 派送管理
+		delivery := secured.Group("/delivery")
 		{
-			// 创建派送任务
-			delivery.POST("/tasks", deliveryHandler.CreateTask)
-			// 变更任务状态
-			delivery.PUT("/tasks/:task_id/status", deliveryHandler.ChangeTaskStatus)
-			// 绑定包裹到任务
-			delivery.POST("/tasks/:task_id/packages/bind", deliveryHandler.BindPackages)
-			// 上报派送异常
-			delivery.POST("/tasks/:task_id/abnormal", deliveryHandler.ReportAbnormal)
+			dispatch := delivery.Group("", middleware.RequireRoles(auth.RoleDispatcher))
+			{
+				// 创建派送任务
+				dispatch.POST("/tasks", deliveryHandler.CreateTask)
+				// 按网点自动生成派送任务（支持试运行）
+				dispatch.POST("/tasks/generate", deliveryHandler.GenerateTasks)
+				// 绑定包裹到任务
+				dispatch.POST("/tasks/:task_id/packages/bind", deliveryHandler.BindPackages)
+				// 从任务解绑包裹
+				dispatch.POST("/tasks/:task_id/packages/unbind", deliveryHandler.UnbindPackages)
+				// 处理派送异常
+				dispatch.POST("/tasks/:task_id/abnormal/handle", deliveryHandler.HandleAbnormal)
+			}
+			onRoad := delivery.Group("", middleware.RequireRoles(auth.RoleDispatcher, auth.RoleCourier))
+			{
+				// 变更任务状态
+				onRoad.PUT("/tasks/:task_id/status", deliveryHandler.ChangeTaskStatus)
+				// 上报派送异常
+				onRoad.POST("/tasks/:task_id/abnormal", deliveryHandler.ReportAbnormal)
+				// 派送员查询任务包裹列表
+				onRoad.GET("/tasks/:task_id/packages", deliveryHandler.GetCourierTaskPackages)
+				// 重新规划派送顺序（跳过停靠点后）
+				onRoad.POST("/tasks/:task_id/sequence", deliveryHandler.ResequenceTask)
+				// 派送员任务收件箱
+				onRoad.GET("/couriers/:courier_id/tasks", deliveryHandler.ListCourierTasks)
+			}
 			// 包裹签收
-			delivery.POST("/tasks/:task_id/packages/:package_id/sign", deliveryHandler.SignPackage)
-			// 派送员查询任务包裹列表
-			delivery.GET("/tasks/:task_id/packages", deliveryHandler.GetCourierTaskPackages)
+			delivery.POST("/tasks/:task_id/packages/:package_id/sign", middleware.RequireRoles(auth.RoleCourier), deliveryHandler.SignPackage)
 		}
-		//
-		//// 派送管理
-		//delivery := api.Group("/delivery")
-		//{
-		//	delivery.POST("/tasks", deliveryHandler.CreateDeliveryTask)
-		//	delivery.POST("/packages/:package_id/abnormal", deliveryHandler.HandleDeliveryAbnormal)
-		//	delivery.GET("/couriers/:courier_id/tasks", deliveryHandler.GetCourierTasks)
-		//}
 	}
 
 	return r
@@ -0,0 +1,51 @@
+package auth
+
+// 系统角色
+const (
+	RoleCollector  = "collector"  // 揽收员
+	RoleSorter     = "sorter"     // 分拣员
+	RoleDispatcher = "dispatcher" // 调度员
+	RoleDriver     = "driver"     // 司机
+	RoleCourier    = "courier"    // 派送员
+	RoleAdmin      = "admin"      // 管理员
+)
+
+// Identity 调用方身份（由认证中间件从令牌中解析）
+type Identity struct {
+	UserID string `json:"user_id"` // 账号ID（司机/派送员即DriverID/CourierID）
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+}
+
+// HasRole 是否具备任一指定角色，管理员拥有全部权限
+func (i *Identity) HasRole(roles ...string) bool {
+	if i == nil {
+		return false
+	}
+	if i.Role == RoleAdmin {
+		return true
+	}
+	for _, r := range roles {
+		if i.Role == r {
+			return true
+		}
+	}
+	return false
+}
+
+// OperatorName 记录轨迹/异常时使用的操作人名称
+func (i *Identity) OperatorName() string {
+	if i.Name != "" {
+		return i.Name
+	}
+	return i.UserID
+}
+
+// ValidRole 是否为系统定义的角色
+func ValidRole(role string) bool {
+	switch role {
+	case RoleCollector, RoleSorter, RoleDispatcher, RoleDriver, RoleCourier, RoleAdmin:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/LFrankl/fdu-lab3/config"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// defaultTokenTTL 未配置时的令牌有效期
+const defaultTokenTTL = 24 * time.Hour
+
+// Claims JWT载荷
+type Claims struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// IssueToken 为身份签发HS256令牌
+func IssueToken(id *Identity) (string, time.Time, error) {
+	ttl := time.Duration(config.Cfg.Auth.TokenTTL) * time.Second
+	if ttl <= 0 {
+		ttl = defaultTokenTTL
+	}
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims := Claims{
+		Name: id.Name,
+		Role: id.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   id.UserID,
+			Issuer:    config.Cfg.App.Name,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.Cfg.Auth.JWTSecret))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// ParseToken 校验令牌签名与有效期并解析身份
+func ParseToken(tokenStr string) (*Identity, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(tokenStr, &claims, func(t *jwt.Token) (interface{}, error) {
+		return []byte(config.Cfg.Auth.JWTSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, errno.ErrTokenExpired
+		}
+		return nil, errno.ErrTokenInvalid
+	}
+	if claims.Subject == "" || !ValidRole(claims.Role) {
+		return nil, errno.ErrTokenInvalid
+	}
+	return &Identity{UserID: claims.Subject, Name: claims.Name, Role: claims.Role}, nil
+}
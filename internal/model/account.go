@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Account 系统账号（揽收员/分拣员/调度员/司机/派送员/管理员）
+type Account struct {
+	UserID       string         `gorm:"primaryKey;size:32;comment:账号ID（司机/派送员即司机ID/派送员ID）"`
+	Name         string         `gorm:"size:64;not null;comment:姓名"`
+	Role         string         `gorm:"size:20;not null;comment:角色（collector/sorter/dispatcher/driver/courier/admin）"`
+	PasswordHash string         `gorm:"size:128;not null;comment:密码哈希（bcrypt）"`
+	Status       string         `gorm:"size:20;not null;default:active;comment:账号状态（active/disabled）"`
+	CreatedAt    time.Time      `gorm:"autoCreateTime;comment:创建时间"`
+	UpdatedAt    time.Time      `gorm:"autoUpdateTime;comment:更新时间"`
+	DeletedAt    gorm.DeletedAt `gorm:"index;comment:删除时间"`
+}
+
+// TableName 表名
+func (a *Account) TableName() string {
+	return "accounts"
+}
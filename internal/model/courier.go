@@ -0,0 +1,118 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+	"gorm.io/gorm"
+)
+
+// CourierArea 派送员服务范围（派送员负责的区县/多边形及运力上限）
+type CourierArea struct {
+	CourierID      string         `gorm:"primaryKey;size:32;comment:派送员ID"`
+	CourierName    string         `gorm:"size:64;not null;comment:派送员姓名"`
+	StationID      string         `gorm:"size:32;index;comment:所属网点ID（为空不限网点）"`
+	Districts      []string       `gorm:"serializer:json;type:json;comment:负责区县列表"`
+	Polygon        []GeoPoint     `gorm:"serializer:json;type:json;comment:负责范围多边形（经纬度顶点）"`
+	MaxActiveTasks int            `gorm:"not null;default:0;comment:未完成任务数上限（0不限）"`
+	MaxPackages    int            `gorm:"not null;default:0;comment:未完成任务包裹总量上限（0不限）"`
+	Status         string         `gorm:"size:20;not null;default:active;comment:状态（active/disabled）"`
+	CreatedAt      time.Time      `gorm:"autoCreateTime;comment:创建时间"`
+	UpdatedAt      time.Time      `gorm:"autoUpdateTime;comment:更新时间"`
+	DeletedAt      gorm.DeletedAt `gorm:"index;comment:删除时间"`
+}
+
+// GeoPoint 经纬度坐标点（值对象）
+type GeoPoint struct {
+	Longitude float64 `json:"longitude"`
+	Latitude  float64 `json:"latitude"`
+}
+
+// TableName 表名
+func (a *CourierArea) TableName() string {
+	return "courier_areas"
+}
+
+// Normalize 规整负责区县（去除首尾空白、空项与重复项）
+func (a *CourierArea) Normalize() {
+	seen := make(map[string]struct{}, len(a.Districts))
+	districts := make([]string, 0, len(a.Districts))
+	for _, d := range a.Districts {
+		d = strings.TrimSpace(d)
+		if _, ok := seen[d]; ok || d == "" {
+			continue
+		}
+		seen[d] = struct{}{}
+		districts = append(districts, d)
+	}
+	a.Districts = districts
+}
+
+// Validate 校验服务范围（至少包含区县或3个顶点以上的多边形，运力上限非负）
+func (a *CourierArea) Validate() error {
+	if len(a.Districts) == 0 && len(a.Polygon) < 3 {
+		return errno.ErrCourierAreaInvalid
+	}
+	if len(a.Polygon) > 0 && len(a.Polygon) < 3 {
+		return errno.ErrCourierAreaInvalid
+	}
+	if a.MaxActiveTasks < 0 || a.MaxPackages < 0 {
+		return errno.ErrParamInvalid
+	}
+	if a.Status != "" && a.Status != "active" && a.Status != "disabled" {
+		return errno.ErrParamInvalid
+	}
+	return nil
+}
+
+// Covers 服务范围是否覆盖派送区域：区域名称包含负责区县，或区域坐标落在多边形内（坐标为0时不做多边形判断）
+func (a *CourierArea) Covers(area string, lng, lat float64) bool {
+	for _, d := range a.Districts {
+		if strings.Contains(area, d) {
+			return true
+		}
+	}
+	if len(a.Polygon) < 3 || (lng == 0 && lat == 0) {
+		return false
+	}
+	return a.containsPoint(lng, lat)
+}
+
+// containsPoint 射线法判断坐标是否落在多边形内
+func (a *CourierArea) containsPoint(lng, lat float64) bool {
+	inside := false
+	n := len(a.Polygon)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		pi, pj := a.Polygon[i], a.Polygon[j]
+		if (pi.Latitude > lat) != (pj.Latitude > lat) &&
+			lng < (pj.Longitude-pi.Longitude)*(lat-pi.Latitude)/(pj.Latitude-pi.Latitude)+pi.Longitude {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// CheckTaskCapacity 校验未完成任务数（含新增任务）不超过上限
+func (a *CourierArea) CheckTaskCapacity(activeTasks int) error {
+	if a.MaxActiveTasks > 0 && activeTasks > a.MaxActiveTasks {
+		return errno.ErrCourierCapacityExceeded
+	}
+	return nil
+}
+
+// CheckPackageCapacity 校验未完成任务包裹总数（含新增包裹）不超过上限
+func (a *CourierArea) CheckPackageCapacity(packages int) error {
+	if a.MaxPackages > 0 && packages > a.MaxPackages {
+		return errno.ErrCourierCapacityExceeded
+	}
+	return nil
+}
+
+// HasCapacity 当前负载下能否再承接一个任务
+func (a *CourierArea) HasCapacity(activeTasks, packages int) bool {
+	if a.MaxPackages > 0 && packages >= a.MaxPackages {
+		return false
+	}
+	return a.CheckTaskCapacity(activeTasks+1) == nil
+}
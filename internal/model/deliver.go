@@ -16,8 +16,10 @@ type DeliveryTask struct {
 	CourierName  string         `gorm:"size:64;not null;comment:派送员姓名"`
 	Status       string         `gorm:"size:20;not null;default:pending;comment:任务状态（pending/delivering/completed/abnormal）"`
 	PackageCount int            `gorm:"not null;default:0;comment:绑定包裹数量"`
-	StartTime    time.Time      `gorm:"comment:派送开始时间"`
-	CompleteTime time.Time      `gorm:"comment:派送完成时间"`
+	Version      int            `gorm:"not null;default:0;comment:乐观锁版本号"`
+	StartTime    time.Time      `gorm:"default:NULL;comment:派送开始时间"`
+	CompleteTime time.Time      `gorm:"default:NULL;comment:派送完成时间"`
+	StartNodeID  string         `gorm:"size:32;index;comment:派送起点节点ID"`
 	StartNode    string         `gorm:"size:64;not null;comment:派送起点（派送网点）"`
 	CreatedAt    time.Time      `gorm:"autoCreateTime;comment:创建时间"`
 	UpdatedAt    time.Time      `gorm:"autoUpdateTime;comment:更新时间"`
@@ -43,7 +45,7 @@ type DeliveryAbnormal struct {
 	AbnormalType   string    `gorm:"size:20;comment:异常类型（receiver_absent/address_error/package_damage）"`
 	AbnormalReason string    `gorm:"size:512;comment:异常原因"`
 	Handler        string    `gorm:"size:64;comment:处理人"`
-	HandleTime     time.Time `gorm:"comment:处理时间"`
+	HandleTime     time.Time `gorm:"default:NULL;comment:处理时间"`
 	HandleResult   string    `gorm:"size:512;comment:处理结果（如二次派送/退回）"`
 }
 
@@ -51,7 +53,7 @@ type DeliveryAbnormal struct {
 type SignInfo struct {
 	SignerName  string    `gorm:"size:64;comment:签收人姓名"`
 	SignerPhone string    `gorm:"size:20;comment:签收人电话（脱敏）"`
-	SignTime    time.Time `gorm:"comment:签收时间"`
+	SignTime    time.Time `gorm:"default:NULL;comment:签收时间"`
 	SignType    string    `gorm:"size:20;comment:签收类型（person/signboard/agent）"` // 本人/柜机/代签
 	SignRemark  string    `gorm:"size:512;comment:签收备注"`
 }
@@ -59,10 +61,10 @@ type SignInfo struct {
 // ChangeStatus ========== 领域行为方法 ==========
 // ChangeStatus 派送任务状态变更（核心业务行为）
 func (d *DeliveryTask) ChangeStatus(newStatus string) error {
-	// 状态流转规则：pending → delivering → completed
+	// 状态流转规则：pending → delivering → completed（abnormal仅能经ReportAbnormal进入）
 	statusFlow := map[string][]string{
-		"pending":    {"delivering", "abnormal"},
-		"delivering": {"completed", "abnormal"},
+		"pending":    {"delivering"},
+		"delivering": {"completed"},
 		"abnormal":   {"delivering", "completed"},
 		"completed":  {}, // 已完成状态不可变更
 	}
@@ -89,20 +91,41 @@ func (d *DeliveryTask) ChangeStatus(newStatus string) error {
 	return nil
 }
 
-// BindPackage 绑定包裹到派送任务（核心业务行为）
+// BindPackage 绑定包裹到派送任务（核心业务行为，packageIDs为新增包裹）
 func (d *DeliveryTask) BindPackage(packageIDs []string) error {
 	// 业务规则：仅pending状态可绑定包裹
 	if d.Status != "pending" {
 		return errno.ErrDeliveryTaskNotBindable
 	}
 	// 更新包裹数量
-	d.PackageCount = len(packageIDs)
+	d.PackageCount += len(packageIDs)
+	d.UpdatedAt = time.Now()
+	return nil
+}
+
+// UnbindPackage 从派送任务解绑包裹（核心业务行为）
+func (d *DeliveryTask) UnbindPackage(packageIDs []string) error {
+	// 业务规则：已完成的任务不可解绑
+	if d.Status != "pending" && d.Status != "delivering" && d.Status != "abnormal" {
+		return errno.ErrDeliveryTaskNotUnbindable
+	}
+	d.PackageCount -= len(packageIDs)
+	if d.PackageCount < 0 {
+		d.PackageCount = 0
+	}
 	d.UpdatedAt = time.Now()
 	return nil
 }
 
 // ReportAbnormal 上报派送异常（核心业务行为）
-func (d *DeliveryTask) ReportAbnormal(abnormalType, reason string, handler string) {
+func (d *DeliveryTask) ReportAbnormal(abnormalType, reason string, handler string) error {
+	// 业务规则：仅未完成且非异常的任务可上报异常
+	if d.Status == "abnormal" {
+		return errno.ErrDeliveryTaskIsAbnormal
+	}
+	if d.Status != "pending" && d.Status != "delivering" {
+		return errno.ErrDeliveryStatusInvalid
+	}
 	d.Status = "abnormal"
 	d.Abnormal = DeliveryAbnormal{
 		AbnormalType:   abnormalType,
@@ -111,19 +134,22 @@ func (d *DeliveryTask) ReportAbnormal(abnormalType, reason string, handler strin
 		HandleTime:     time.Now(),
 	}
 	d.UpdatedAt = time.Now()
+	return nil
 }
 
 // HandleAbnormal 处理派送异常（核心业务行为）
-func (d *DeliveryTask) HandleAbnormal(result string, newStatus string) error {
+func (d *DeliveryTask) HandleAbnormal(result, newStatus, handler string) error {
 	if d.Status != "abnormal" {
 		return errno.ErrDeliveryTaskNotAbnormal
 	}
-	// 更新异常处理结果
-	d.Abnormal.HandleResult = result
 	// 恢复任务状态
 	if err := d.ChangeStatus(newStatus); err != nil {
 		return err
 	}
+	// 记录异常处理结果、处理人及处理时间
+	d.Abnormal.HandleResult = result
+	d.Abnormal.Handler = handler
+	d.Abnormal.HandleTime = time.Now()
 	d.UpdatedAt = time.Now()
 	return nil
 }
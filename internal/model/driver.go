@@ -0,0 +1,66 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+	"gorm.io/gorm"
+)
+
+// Driver 司机档案（DriverID即司机账号ID，运输任务通过DriverID引用）
+type Driver struct {
+	DriverID      string         `gorm:"primaryKey;size:32;comment:司机ID"`
+	DriverName    string         `gorm:"size:64;not null;comment:司机姓名"`
+	Phone         string         `gorm:"size:20;comment:联系电话"`
+	LicenseClass  string         `gorm:"size:8;not null;comment:准驾车型（A1/A2/B1/B2/C1）"`
+	HomeNodeID    string         `gorm:"size:32;index;comment:常驻节点ID"`
+	MaxDailyHours float64        `gorm:"not null;default:8;comment:每日驾驶时长上限（小时）"`
+	Status        string         `gorm:"size:20;not null;default:active;index;comment:状态（active/on_leave/disabled）"`
+	CreatedAt     time.Time      `gorm:"autoCreateTime;comment:创建时间"`
+	UpdatedAt     time.Time      `gorm:"autoUpdateTime;comment:更新时间"`
+	DeletedAt     gorm.DeletedAt `gorm:"index;comment:删除时间"`
+}
+
+// heavyVehicleLicenses 重型货车所需准驾车型，其余车型任一准驾车型均可驾驶
+var heavyVehicleLicenses = map[string]bool{"A1": true, "A2": true, "B2": true}
+
+// TableName 表名
+func (d *Driver) TableName() string {
+	return "drivers"
+}
+
+// Validate 校验司机档案（准驾车型合法、时长上限在(0,24]内、状态合法）
+func (d *Driver) Validate() error {
+	d.LicenseClass = strings.ToUpper(strings.TrimSpace(d.LicenseClass))
+	switch d.LicenseClass {
+	case "A1", "A2", "B1", "B2", "C1":
+	default:
+		return errno.ErrParamInvalid
+	}
+	if d.MaxDailyHours <= 0 || d.MaxDailyHours > 24 {
+		return errno.ErrParamInvalid
+	}
+	switch d.Status {
+	case "active", "on_leave", "disabled":
+	default:
+		return errno.ErrParamInvalid
+	}
+	return nil
+}
+
+// CanDrive 准驾车型是否允许驾驶该车型（重型货车须A1/A2/B2）
+func (d *Driver) CanDrive(vehicleType string) bool {
+	if vehicleType == "heavy_truck" {
+		return heavyVehicleLicenses[d.LicenseClass]
+	}
+	return true
+}
+
+// CheckDailyHours 校验当日已排驾驶时长加上新任务时长不超过上限
+func (d *Driver) CheckDailyHours(scheduled, added float64) error {
+	if scheduled+added > d.MaxDailyHours {
+		return errno.ErrDriverHoursExceeded
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// PackageETA 包裹送达时间预测记录（每次包裹状态变更预测一次，签收后回填实际送达时间用于评估预测准确率）
+type PackageETA struct {
+	ID                uint      `gorm:"primaryKey;autoIncrement;comment:自增ID"`
+	PackageID         string    `gorm:"size:32;not null;index;comment:运单号"`
+	PackageStatus     string    `gorm:"size:20;not null;comment:预测时包裹状态"`
+	Basis             string    `gorm:"size:32;not null;comment:预测依据（transport_task/lane_history/blended/route_distance/current_node/delivery_sequence/default）"`
+	EstimatedTime     time.Time `gorm:"not null;comment:预测送达时间"`
+	WindowStart       time.Time `gorm:"not null;comment:送达窗口开始"`
+	WindowEnd         time.Time `gorm:"not null;comment:送达窗口结束"`
+	Confidence        string    `gorm:"size:10;not null;index;comment:置信度（high/medium/low）"`
+	PredictedAt       time.Time `gorm:"not null;index;comment:预测时间"`
+	ActualDeliveredAt time.Time `gorm:"default:NULL;comment:实际送达时间（签收后回填）"`
+}
+
+// TableName 表名
+func (e *PackageETA) TableName() string {
+	return "package_etas"
+}
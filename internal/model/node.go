@@ -0,0 +1,83 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+	"gorm.io/gorm"
+)
+
+// Node 物流网络节点（分拣中心/网点），任务与轨迹通过NodeID引用
+type Node struct {
+	NodeID           string         `gorm:"primaryKey;size:32;comment:节点ID"`
+	Code             string         `gorm:"size:32;not null;uniqueIndex;comment:节点编码"`
+	Name             string         `gorm:"size:64;not null;uniqueIndex;comment:节点名称"`
+	Type             string         `gorm:"size:20;not null;index;comment:节点类型（sorting_center/station）"`
+	Address          string         `gorm:"size:255;comment:节点地址"`
+	Longitude        float64        `gorm:"comment:经度"`
+	Latitude         float64        `gorm:"comment:纬度"`
+	ParentID         string         `gorm:"size:32;index;comment:上级分拣中心ID（网点必填）"`
+	OpeningHours     string         `gorm:"size:64;comment:营业时间（如08:00-20:00）"`
+	ServiceDistricts []string       `gorm:"serializer:json;type:json;comment:服务区县列表"`
+	Status           string         `gorm:"size:20;not null;default:active;comment:节点状态（active/disabled）"`
+	CreatedAt        time.Time      `gorm:"autoCreateTime;comment:创建时间"`
+	UpdatedAt        time.Time      `gorm:"autoUpdateTime;comment:更新时间"`
+	DeletedAt        gorm.DeletedAt `gorm:"index;comment:删除时间"`
+}
+
+// TableName 表名
+func (n *Node) TableName() string {
+	return "nodes"
+}
+
+// Normalize 规整节点名称、编码与服务区县（去除首尾空白、空项与重复项）
+func (n *Node) Normalize() {
+	n.Name = strings.TrimSpace(n.Name)
+	n.Code = strings.TrimSpace(n.Code)
+	n.Address = strings.TrimSpace(n.Address)
+	seen := make(map[string]struct{}, len(n.ServiceDistricts))
+	districts := make([]string, 0, len(n.ServiceDistricts))
+	for _, d := range n.ServiceDistricts {
+		d = strings.TrimSpace(d)
+		if _, ok := seen[d]; ok || d == "" {
+			continue
+		}
+		seen[d] = struct{}{}
+		districts = append(districts, d)
+	}
+	n.ServiceDistricts = districts
+}
+
+// Validate 校验节点基本信息（类型合法、名称与编码非空、网点须挂靠上级分拣中心）
+func (n *Node) Validate() error {
+	if n.Name == "" || n.Code == "" {
+		return errno.ErrParamInvalid
+	}
+	switch n.Type {
+	case "sorting_center":
+	case "station":
+		if n.ParentID == "" {
+			return errno.ErrNodeParentInvalid
+		}
+	default:
+		return errno.ErrNodeTypeInvalid
+	}
+	if n.ParentID != "" && n.ParentID == n.NodeID {
+		return errno.ErrNodeParentInvalid
+	}
+	if n.Status != "" && n.Status != "active" && n.Status != "disabled" {
+		return errno.ErrParamInvalid
+	}
+	return nil
+}
+
+// ServesDistrict 节点是否服务指定区县
+func (n *Node) ServesDistrict(district string) bool {
+	for _, d := range n.ServiceDistricts {
+		if d == district {
+			return true
+		}
+	}
+	return false
+}
@@ -3,31 +3,36 @@ package model
 import (
 	"time"
 
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
 	"gorm.io/gorm"
 )
 
 // Package 包裹核心信息
+// 检索索引：收/寄件人电话、收件人姓名、收件城市、状态各与创建时间组成联合索引，等值筛选后可直接按创建时间有序翻页
 type Package struct {
-	PackageID        string         `gorm:"primaryKey;size:32;comment:运单号"`
-	SenderName       string         `gorm:"size:64;not null;comment:寄件人姓名"`
-	SenderPhone      string         `gorm:"size:20;not null;comment:寄件人电话"`
-	SenderAddress    string         `gorm:"size:255;not null;comment:寄件人地址"`
-	ReceiverName     string         `gorm:"size:64;not null;comment:收件人姓名"`
-	ReceiverPhone    string         `gorm:"size:20;not null;comment:收件人电话"`
-	ReceiverAddress  string         `gorm:"size:255;not null;comment:收件人地址"`
-	ReceiverProvince string         `gorm:"size:32;not null;comment:收件人省份"`
-	ReceiverCity     string         `gorm:"size:32;not null;comment:收件人城市"`
-	ReceiverDistrict string         `gorm:"size:32;not null;comment:收件人区县"`
-	Weight           float64        `gorm:"not null;comment:包裹重量(kg)"`
-	Length           float64        `gorm:"comment:长度(cm)"`
-	Width            float64        `gorm:"comment:宽度(cm)"`
-	Height           float64        `gorm:"comment:高度(cm)"`
-	Status           string         `gorm:"size:20;not null;default:pending;comment:包裹状态"`
-	AbnormalReason   string         `gorm:"size:255;comment:异常原因"`
-	AbnormalHandler  string         `gorm:"size:64;comment:异常处理人"`
-	CreatedAt        time.Time      `gorm:"autoCreateTime;comment:创建时间"`
-	UpdatedAt        time.Time      `gorm:"autoUpdateTime;comment:更新时间"`
-	DeletedAt        gorm.DeletedAt `gorm:"index;comment:删除时间"`
+	PackageID         string         `gorm:"primaryKey;size:32;comment:运单号"`
+	SenderName        string         `gorm:"size:64;not null;comment:寄件人姓名"`
+	SenderPhone       string         `gorm:"size:20;not null;index:idx_packages_sender_phone,priority:1;comment:寄件人电话"`
+	SenderAddress     string         `gorm:"size:255;not null;comment:寄件人地址"`
+	ReceiverName      string         `gorm:"size:64;not null;index:idx_packages_receiver_name,priority:1;comment:收件人姓名"`
+	ReceiverPhone     string         `gorm:"size:20;not null;index:idx_packages_receiver_phone,priority:1;comment:收件人电话"`
+	ReceiverAddress   string         `gorm:"size:255;not null;comment:收件人地址"`
+	ReceiverProvince  string         `gorm:"size:32;not null;comment:收件人省份"`
+	ReceiverCity      string         `gorm:"size:32;not null;index:idx_packages_receiver_city,priority:1;comment:收件人城市"`
+	ReceiverDistrict  string         `gorm:"size:32;not null;comment:收件人区县"`
+	ReceiverLongitude float64        `gorm:"comment:收件地址经度（揽收时解析，解析失败为0）"`
+	ReceiverLatitude  float64        `gorm:"comment:收件地址纬度（揽收时解析，解析失败为0）"`
+	Weight            float64        `gorm:"not null;comment:包裹重量(kg)"`
+	Length            float64        `gorm:"comment:长度(cm)"`
+	Width             float64        `gorm:"comment:宽度(cm)"`
+	Height            float64        `gorm:"comment:高度(cm)"`
+	Status            string         `gorm:"size:20;not null;default:pending;index:idx_packages_status,priority:1;comment:包裹状态"`
+	CurrentNodeID     string         `gorm:"size:32;index;comment:当前所在节点ID（运输到站时更新）"`
+	AbnormalReason    string         `gorm:"size:255;comment:异常原因"`
+	AbnormalHandler   string         `gorm:"size:64;comment:异常处理人"`
+	CreatedAt         time.Time      `gorm:"autoCreateTime;index:idx_packages_created_at;index:idx_packages_sender_phone,priority:2;index:idx_packages_receiver_name,priority:2;index:idx_packages_receiver_phone,priority:2;index:idx_packages_receiver_city,priority:2;index:idx_packages_status,priority:2;comment:创建时间"`
+	UpdatedAt         time.Time      `gorm:"autoUpdateTime;index:idx_packages_updated_at;comment:更新时间"`
+	DeletedAt         gorm.DeletedAt `gorm:"index;comment:删除时间"`
 }
 
 // TableName 表名
@@ -35,11 +40,56 @@ func (p *Package) TableName() string {
 	return "packages"
 }
 
+// ChangeStatus 包裹状态变更（核心业务行为）
+func (p *Package) ChangeStatus(newStatus string) error {
+	// 状态流转规则：pending → collected → sorted → transporting → arrived → delivering → delivered
+	statusFlow := map[string][]string{
+		"pending":            {"collected"},
+		"collected":          {"sorted", "abnormal"},
+		"abnormal":           {"collected", "sorted", "returning"}, // 分拣异常处理后重新分拣、直接放行或退回寄件人
+		"sorted":             {"transporting", "transport_abnormal"},
+		"transporting":       {"arrived", "transport_abnormal", "sorted"}, // 从运输任务解绑后待重新调度
+		"transport_abnormal": {"transporting", "arrived", "sorted", "returning"},
+		"arrived":            {"sorted", "delivering", "delivery_abnormal"}, // 中转到站后可再次分拣进入下一段运输
+		"delivering":         {"delivered", "delivery_abnormal", "arrived"}, // 从派送任务解绑后退回网点
+		"delivery_abnormal":  {"delivering", "arrived", "delivered", "returning"},
+		"delivered":          {}, // 已签收状态不可变更
+		"returning":          {"returned"},
+		"returned":           {}, // 已退回寄件人状态不可变更
+	}
+	// 校验状态流转合法性
+	allowedStatus := statusFlow[p.Status]
+	allow := false
+	for _, s := range allowedStatus {
+		if s == newStatus {
+			allow = true
+			break
+		}
+	}
+	if !allow {
+		return errno.ErrPackageStatusInvalid
+	}
+	p.Status = newStatus
+	p.UpdatedAt = time.Now()
+	return nil
+}
+
+// MarkAbnormal 标记包裹异常（核心业务行为）
+func (p *Package) MarkAbnormal(abnormalStatus, reason, handler string) error {
+	if err := p.ChangeStatus(abnormalStatus); err != nil {
+		return err
+	}
+	p.AbnormalReason = reason
+	p.AbnormalHandler = handler
+	return nil
+}
+
 // PackageTrace 包裹轨迹
 type PackageTrace struct {
 	TraceID       string         `gorm:"primaryKey;size:32;comment:轨迹ID"`
 	PackageID     string         `gorm:"size:32;not null;index;comment:运单号"`
 	NodeType      string         `gorm:"size:20;not null;comment:节点类型"`
+	NodeID        string         `gorm:"size:32;index;comment:网络节点ID（非登记节点如收件地址为空）"`
 	NodeName      string         `gorm:"size:64;not null;comment:节点名称"`
 	NodeAddress   string         `gorm:"size:255;comment:节点地址"`
 	Longitude     float64        `gorm:"comment:经度"`
@@ -56,21 +106,156 @@ func (pt *PackageTrace) TableName() string {
 	return "package_traces"
 }
 
+// PackageStatusLog 包裹状态变更审计记录
+type PackageStatusLog struct {
+	LogID      string    `gorm:"primaryKey;size:32;comment:审计记录ID"`
+	PackageID  string    `gorm:"size:32;not null;index;comment:运单号"`
+	FromStatus string    `gorm:"size:20;not null;comment:变更前状态"`
+	ToStatus   string    `gorm:"size:20;not null;comment:变更后状态"`
+	Operator   string    `gorm:"size:64;not null;comment:操作人"`
+	Reason     string    `gorm:"size:255;not null;comment:变更原因"`
+	CreatedAt  time.Time `gorm:"autoCreateTime;comment:创建时间"`
+}
+
+// TableName 表名
+func (l *PackageStatusLog) TableName() string {
+	return "package_status_logs"
+}
+
 // AbnormalRecord 异常记录
 type AbnormalRecord struct {
 	RecordID         string         `gorm:"primaryKey;size:32;comment:异常记录ID"`
 	PackageID        string         `gorm:"size:32;not null;index;comment:运单号"`
-	AbnormalType     string         `gorm:"size:20;not null;comment:异常类型"`
+	AbnormalType     string         `gorm:"size:20;not null;index;comment:异常类型（sorting/transport/delivery）"`
+	SubType          string         `gorm:"size:32;comment:细分类型（如vehicle_fault/receiver_absent）"`
 	AbnormalReason   string         `gorm:"size:255;not null;comment:异常原因"`
-	ProcessingMethod string         `gorm:"size:255;comment:处理方式"`
+	TaskID           string         `gorm:"size:32;index;comment:关联运输/派送任务ID"`
+	NodeName         string         `gorm:"size:64;index;comment:发生异常的节点名称"`
+	Reporter         string         `gorm:"size:64;comment:上报人"`
+	AssigneeID       string         `gorm:"size:32;index;comment:指派处理人账号ID"`
+	ProcessingMethod string         `gorm:"size:255;comment:处理方式（relabel/resort/return_to_sender）"`
+	ProcessingRemark string         `gorm:"size:255;comment:处理说明"`
 	Processor        string         `gorm:"size:64;comment:处理人"`
-	ProcessingTime   time.Time      `gorm:"comment:处理时间"`
-	Status           string         `gorm:"size:20;not null;default:pending;comment:处理状态"`
+	ProcessingTime   time.Time      `gorm:"default:NULL;comment:处理时间"`
+	Status           string         `gorm:"size:20;not null;default:pending;index;comment:处理状态（pending/processing/resolved/closed）"`
 	CreatedAt        time.Time      `gorm:"autoCreateTime;comment:创建时间"`
 	UpdatedAt        time.Time      `gorm:"autoUpdateTime;comment:更新时间"`
 	DeletedAt        gorm.DeletedAt `gorm:"index;comment:删除时间"`
 }
 
+// abnormalResolveStatus 包裹异常状态 → 处理方式 → 处理后包裹回到的状态（须为Package.ChangeStatus允许的流转）
+var abnormalResolveStatus = map[string]map[string]string{
+	// 分拣异常：重新贴标后放行，或退回待分拣
+	"abnormal": {
+		"relabel":          "sorted",
+		"resort":           "collected",
+		"return_to_sender": "returning",
+	},
+	// 运输异常：重新贴标或分拣后回到已分拣，等待重新调度运输
+	"transport_abnormal": {
+		"relabel":          "sorted",
+		"resort":           "sorted",
+		"return_to_sender": "returning",
+	},
+	// 派送异常：重新贴标或分拣后回到网点，等待重新派送
+	"delivery_abnormal": {
+		"relabel":          "arrived",
+		"resort":           "arrived",
+		"return_to_sender": "returning",
+	},
+}
+
+// ValidAbnormalMethod 校验处理方式，不合法返回errno.ErrAbnormalMethodInvalid
+func ValidAbnormalMethod(method string) error {
+	if _, ok := abnormalResolveStatus["abnormal"][method]; !ok {
+		return errno.ErrAbnormalMethodInvalid
+	}
+	return nil
+}
+
+// AbnormalResolveStatus 按包裹当前异常状态查询处理方式对应的包裹状态；
+// 处理方式不合法返回errno.ErrAbnormalMethodInvalid，包裹不处于异常状态返回errno.ErrPackageStatusInvalid
+func AbnormalResolveStatus(pkgStatus, method string) (string, error) {
+	if err := ValidAbnormalMethod(method); err != nil {
+		return "", err
+	}
+	status, ok := abnormalResolveStatus[pkgStatus][method]
+	if !ok {
+		return "", errno.ErrPackageStatusInvalid
+	}
+	return status, nil
+}
+
+// changeStatus 异常记录状态变更
+func (ar *AbnormalRecord) changeStatus(newStatus string) error {
+	// 状态流转规则：pending → processing → resolved → closed，已处理/已关闭可重新打开回到processing
+	statusFlow := map[string][]string{
+		"pending":    {"processing"},
+		"processing": {"processing", "resolved"}, // 处理中允许改派
+		"resolved":   {"closed", "processing"},
+		"closed":     {"processing"},
+	}
+	allowedStatus := statusFlow[ar.Status]
+	allow := false
+	for _, s := range allowedStatus {
+		if s == newStatus {
+			allow = true
+			break
+		}
+	}
+	if !allow {
+		return errno.ErrAbnormalRecordStatusInvalid
+	}
+	ar.Status = newStatus
+	ar.UpdatedAt = time.Now()
+	return nil
+}
+
+// Assign 指派（或改派）处理人
+func (ar *AbnormalRecord) Assign(assigneeID, assigneeName string) error {
+	if err := ar.changeStatus("processing"); err != nil {
+		return err
+	}
+	ar.AssigneeID = assigneeID
+	ar.Processor = assigneeName
+	return nil
+}
+
+// Resolve 按处理方式完成处理，记录处理人与处理时间
+func (ar *AbnormalRecord) Resolve(method, remark, processor string) error {
+	if err := ValidAbnormalMethod(method); err != nil {
+		return err
+	}
+	if err := ar.changeStatus("resolved"); err != nil {
+		return err
+	}
+	ar.ProcessingMethod = method
+	ar.ProcessingRemark = remark
+	ar.Processor = processor
+	ar.ProcessingTime = time.Now()
+	return nil
+}
+
+// Close 关闭已处理的异常记录
+func (ar *AbnormalRecord) Close(remark string) error {
+	if err := ar.changeStatus("closed"); err != nil {
+		return err
+	}
+	if remark != "" {
+		ar.ProcessingRemark = remark
+	}
+	return nil
+}
+
+// Reopen 重新打开已处理/已关闭的异常记录，保留原处理人
+func (ar *AbnormalRecord) Reopen(reason string) error {
+	if err := ar.changeStatus("processing"); err != nil {
+		return err
+	}
+	ar.ProcessingRemark = "重新打开：" + reason
+	return nil
+}
+
 // TableName 表名
 func (ar *AbnormalRecord) TableName() string {
 	return "abnormal_records"
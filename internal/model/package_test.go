@@ -0,0 +1,123 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+func TestPackageChangeStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		wantErr error
+	}{
+		{"揽收", "pending", "collected", nil},
+		{"分拣", "collected", "sorted", nil},
+		{"分拣异常", "collected", "abnormal", nil},
+		{"发车", "sorted", "transporting", nil},
+		{"到站", "transporting", "arrived", nil},
+		{"解绑回到已分拣", "transporting", "sorted", nil},
+		{"运输异常", "transporting", "transport_abnormal", nil},
+		{"开始派送", "arrived", "delivering", nil},
+		{"签收", "delivering", "delivered", nil},
+		{"派送异常", "delivering", "delivery_abnormal", nil},
+		{"派送异常后退回", "delivery_abnormal", "returning", nil},
+		{"退回完成", "returning", "returned", nil},
+		{"未揽收不可分拣", "pending", "sorted", errno.ErrPackageStatusInvalid},
+		{"已签收不可回退", "delivered", "collected", errno.ErrPackageStatusInvalid},
+		{"已分拣不可直接派送", "sorted", "delivering", errno.ErrPackageStatusInvalid},
+		{"运输异常不可回到揽收", "transport_abnormal", "collected", errno.ErrPackageStatusInvalid},
+		{"派送异常不可回到已分拣", "delivery_abnormal", "sorted", errno.ErrPackageStatusInvalid},
+		{"已退回为终态", "returned", "delivering", errno.ErrPackageStatusInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg := &Package{Status: tt.from}
+			err := pkg.ChangeStatus(tt.to)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ChangeStatus(%q → %q) error = %v, want %v", tt.from, tt.to, err, tt.wantErr)
+			}
+			want := tt.to
+			if tt.wantErr != nil {
+				want = tt.from
+			}
+			if pkg.Status != want {
+				t.Fatalf("status = %q, want %q", pkg.Status, want)
+			}
+		})
+	}
+}
+
+func TestAbnormalResolveStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		pkgStatus string
+		method    string
+		want      string
+		wantErr   error
+	}{
+		{"分拣异常重新贴标", "abnormal", "relabel", "sorted", nil},
+		{"分拣异常重新分拣", "abnormal", "resort", "collected", nil},
+		{"分拣异常退回", "abnormal", "return_to_sender", "returning", nil},
+		{"运输异常重新贴标", "transport_abnormal", "relabel", "sorted", nil},
+		{"运输异常重新分拣", "transport_abnormal", "resort", "sorted", nil},
+		{"运输异常退回", "transport_abnormal", "return_to_sender", "returning", nil},
+		{"派送异常重新贴标", "delivery_abnormal", "relabel", "arrived", nil},
+		{"派送异常重新分拣", "delivery_abnormal", "resort", "arrived", nil},
+		{"派送异常退回", "delivery_abnormal", "return_to_sender", "returning", nil},
+		{"处理方式不合法", "abnormal", "discard", "", errno.ErrAbnormalMethodInvalid},
+		{"包裹不处于异常状态", "sorted", "relabel", "", errno.ErrPackageStatusInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AbnormalResolveStatus(tt.pkgStatus, tt.method)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AbnormalResolveStatus(%q, %q) error = %v, want %v", tt.pkgStatus, tt.method, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("AbnormalResolveStatus(%q, %q) = %q, want %q", tt.pkgStatus, tt.method, got, tt.want)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			// 处理后的状态须为包裹状态机允许的流转
+			pkg := &Package{Status: tt.pkgStatus}
+			if err := pkg.ChangeStatus(got); err != nil {
+				t.Fatalf("Package.ChangeStatus(%q → %q) error = %v", tt.pkgStatus, got, err)
+			}
+		})
+	}
+}
+
+func TestAbnormalRecordLifecycle(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  string
+		op      func(ar *AbnormalRecord) error
+		want    string
+		wantErr error
+	}{
+		{"指派待处理记录", "pending", func(ar *AbnormalRecord) error { return ar.Assign("u1", "张三") }, "processing", nil},
+		{"改派处理中记录", "processing", func(ar *AbnormalRecord) error { return ar.Assign("u2", "李四") }, "processing", nil},
+		{"处理处理中记录", "processing", func(ar *AbnormalRecord) error { return ar.Resolve("relabel", "", "张三") }, "resolved", nil},
+		{"未指派不可处理", "pending", func(ar *AbnormalRecord) error { return ar.Resolve("relabel", "", "张三") }, "pending", errno.ErrAbnormalRecordStatusInvalid},
+		{"处理方式不合法", "processing", func(ar *AbnormalRecord) error { return ar.Resolve("discard", "", "张三") }, "processing", errno.ErrAbnormalMethodInvalid},
+		{"关闭已处理记录", "resolved", func(ar *AbnormalRecord) error { return ar.Close("") }, "closed", nil},
+		{"未处理不可关闭", "processing", func(ar *AbnormalRecord) error { return ar.Close("") }, "processing", errno.ErrAbnormalRecordStatusInvalid},
+		{"重新打开已关闭记录", "closed", func(ar *AbnormalRecord) error { return ar.Reopen("") }, "processing", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ar := &AbnormalRecord{Status: tt.status}
+			if err := tt.op(ar); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if ar.Status != tt.want {
+				t.Fatalf("status = %q, want %q", ar.Status, tt.want)
+			}
+		})
+	}
+}
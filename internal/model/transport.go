@@ -1,6 +1,7 @@
 package model
 
 import (
+	"math"
 	"time"
 
 	"github.com/LFrankl/fdu-lab3/pkg/errno"
@@ -11,13 +12,18 @@ import (
 // TransportTask 运输任务（领域实体：有唯一标识，承载核心业务行为）
 type TransportTask struct {
 	TaskID           string         `gorm:"primaryKey;size:32;comment:运输任务ID"` // 唯一标识
+	StartNodeID      string         `gorm:"size:32;index;comment:出发节点ID"`
 	StartNode        string         `gorm:"size:64;not null;comment:出发节点（分拣中心/网点）"`
+	EndNodeID        string         `gorm:"size:32;index;comment:到达节点ID"`
 	EndNode          string         `gorm:"size:64;not null;comment:到达节点（分拣中心/网点）"`
 	Status           string         `gorm:"size:20;not null;default:pending;comment:任务状态（pending/transporting/arrived/completed/abnormal）"`
 	VehicleID        string         `gorm:"size:32;not null;comment:运输车辆ID"`
 	DriverID         string         `gorm:"size:32;comment:司机ID"`
 	DriverName       string         `gorm:"size:64;comment:司机姓名"`
 	PackageCount     int            `gorm:"not null;default:0;comment:绑定包裹数量"`
+	LoadWeight       float64        `gorm:"not null;default:0;comment:装载重量(kg)"`
+	LoadVolume       float64        `gorm:"not null;default:0;comment:装载体积(m³)"`
+	Version          int            `gorm:"not null;default:0;comment:乐观锁版本号"`
 	EstimatedTime    time.Time      `gorm:"comment:预计到达时间"`
 	ActualArriveTime time.Time      `gorm:"default:NULL;comment:实际到达时间"`
 	CreatedAt        time.Time      `gorm:"autoCreateTime;comment:创建时间"`
@@ -55,11 +61,11 @@ type TransportAbnormal struct {
 
 // ChangeStatus 运输任务状态变更（核心业务行为）
 func (t *TransportTask) ChangeStatus(newStatus string) error {
-	// 状态流转规则：pending → transporting → arrived → completed
+	// 状态流转规则：pending → transporting → arrived → completed（abnormal仅能经ReportAbnormal进入）
 	statusFlow := map[string][]string{
-		"pending":      {"transporting", "abnormal"},
-		"transporting": {"arrived", "abnormal"},
-		"arrived":      {"completed", "abnormal"},
+		"pending":      {"transporting"},
+		"transporting": {"arrived"},
+		"arrived":      {"completed"},
 		"abnormal":     {"transporting", "completed"},
 		"completed":    {}, // 已完成状态不可变更
 	}
@@ -77,8 +83,8 @@ func (t *TransportTask) ChangeStatus(newStatus string) error {
 	}
 	// 更新状态
 	t.Status = newStatus
-	// 若状态为arrived，记录实际到达时间
-	if newStatus == "arrived" {
+	// 若状态为arrived（或异常处理后直接完成），记录实际到达时间
+	if newStatus == "arrived" || (newStatus == "completed" && t.ActualArriveTime.IsZero()) {
 		t.ActualArriveTime = time.Now()
 	}
 	return nil
@@ -96,8 +102,56 @@ func (t *TransportTask) BindPackage(packageIDs []string) error {
 	return nil
 }
 
+// Utilization 按车辆上限计算装载率（重量/体积百分比，保留两位小数）
+func (t *TransportTask) Utilization(v *Vehicle) (weightPct, volumePct float64) {
+	percent := func(load, max float64) float64 {
+		if max <= 0 {
+			return 0
+		}
+		return math.Round(load/max*10000) / 100
+	}
+	return percent(t.LoadWeight, v.MaxWeight), percent(t.LoadVolume, v.MaxVolume)
+}
+
+// DrivingHours 任务驾驶时长（小时）：自创建起至实际到达时间，未到达时至预计到达时间，未知时为0
+func (t *TransportTask) DrivingHours() float64 {
+	start := t.CreatedAt
+	if start.IsZero() {
+		start = time.Now()
+	}
+	end := t.EstimatedTime
+	if !t.ActualArriveTime.IsZero() {
+		end = t.ActualArriveTime
+	}
+	if end.Before(start) {
+		return 0
+	}
+	return end.Sub(start).Hours()
+}
+
+// UnbindPackage 从运输任务解绑包裹（核心业务行为）
+func (t *TransportTask) UnbindPackage(packageIDs []string) error {
+	// 业务规则：已到站/已完成的任务不可解绑（包裹已卸车）
+	if t.Status != "pending" && t.Status != "transporting" && t.Status != "abnormal" {
+		return errno.ErrTransportTaskNotUnbindable
+	}
+	t.PackageCount -= len(packageIDs)
+	if t.PackageCount < 0 {
+		t.PackageCount = 0
+	}
+	t.UpdatedAt = time.Now()
+	return nil
+}
+
 // ReportAbnormal 上报运输异常（核心业务行为）
-func (t *TransportTask) ReportAbnormal(abnormalType, reason string, handler string) {
+func (t *TransportTask) ReportAbnormal(abnormalType, reason string, handler string) error {
+	// 业务规则：仅未完成且非异常的任务可上报异常
+	if t.Status == "abnormal" {
+		return errno.ErrTransportTaskIsAbnormal
+	}
+	if t.Status != "pending" && t.Status != "transporting" && t.Status != "arrived" {
+		return errno.ErrTransportStatusInvalid
+	}
 	t.Status = "abnormal"
 	t.Abnormal = TransportAbnormal{
 		AbnormalType:   abnormalType,
@@ -106,19 +160,22 @@ func (t *TransportTask) ReportAbnormal(abnormalType, reason string, handler stri
 		HandleTime:     time.Now(),
 	}
 	t.UpdatedAt = time.Now()
+	return nil
 }
 
 // HandleAbnormal 处理运输异常（核心业务行为）
-func (t *TransportTask) HandleAbnormal(result string, newStatus string) error {
+func (t *TransportTask) HandleAbnormal(result, newStatus, handler string) error {
 	if t.Status != "abnormal" {
 		return errno.ErrTransportTaskNotAbnormal
 	}
-	// 更新异常处理结果
-	t.Abnormal.HandleResult = result
 	// 恢复任务状态
 	if err := t.ChangeStatus(newStatus); err != nil {
 		return err
 	}
+	// 记录异常处理结果、处理人及处理时间
+	t.Abnormal.HandleResult = result
+	t.Abnormal.Handler = handler
+	t.Abnormal.HandleTime = time.Now()
 	t.UpdatedAt = time.Now()
 	return nil
 }
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+	"gorm.io/gorm"
+)
+
+// Vehicle 运输车辆（运输任务通过VehicleID引用）
+type Vehicle struct {
+	VehicleID string         `gorm:"primaryKey;size:32;comment:车辆ID"`
+	Plate     string         `gorm:"size:20;not null;uniqueIndex;comment:车牌号"`
+	Type      string         `gorm:"size:32;not null;comment:车型（如van/light_truck/heavy_truck）"`
+	MaxWeight float64        `gorm:"not null;comment:最大载重(kg)"`
+	MaxVolume float64        `gorm:"not null;comment:最大容积(m³)"`
+	ColdChain bool           `gorm:"not null;default:false;comment:是否冷链车"`
+	Status    string         `gorm:"size:20;not null;default:active;index;comment:车辆状态（active/maintenance/disabled）"`
+	CreatedAt time.Time      `gorm:"autoCreateTime;comment:创建时间"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime;comment:更新时间"`
+	DeletedAt gorm.DeletedAt `gorm:"index;comment:删除时间"`
+}
+
+// TableName 表名
+func (v *Vehicle) TableName() string {
+	return "vehicles"
+}
+
+// Validate 校验车辆信息（车牌与车型非空、载重与容积为正、状态合法）
+func (v *Vehicle) Validate() error {
+	v.Plate = strings.ToUpper(strings.TrimSpace(v.Plate))
+	v.Type = strings.TrimSpace(v.Type)
+	if v.Plate == "" || v.Type == "" || v.MaxWeight <= 0 || v.MaxVolume <= 0 {
+		return errno.ErrParamInvalid
+	}
+	switch v.Status {
+	case "active", "maintenance", "disabled":
+	default:
+		return errno.ErrParamInvalid
+	}
+	return nil
+}
+
+// CheckLoad 校验装载重量(kg)与体积(m³)不超过车辆上限
+func (v *Vehicle) CheckLoad(weight, volume float64) error {
+	if weight > v.MaxWeight {
+		return errno.ErrVehicleOverweight
+	}
+	if volume > v.MaxVolume {
+		return errno.ErrVehicleOverVolume
+	}
+	return nil
+}
+
+// PackageVolume 包裹体积(m³)：长×宽×高(cm³)/1e6，尺寸缺失时为0
+func PackageVolume(pkg *Package) float64 {
+	return pkg.Length * pkg.Width * pkg.Height / 1e6
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"gorm.io/gorm"
+)
+
+// AccountRepo 账号数据访问接口
+type AccountRepo interface {
+	// Create 创建账号
+	Create(account *model.Account) error
+	// GetByID 根据账号ID查询，不存在时返回nil
+	GetByID(userID string) (*model.Account, error)
+}
+
+// accountRepo 实现AccountRepo接口
+type accountRepo struct {
+	db *gorm.DB
+}
+
+// NewAccountRepo 创建仓储实例
+func NewAccountRepo(db *gorm.DB) AccountRepo {
+	return &accountRepo{db: db}
+}
+
+// Create 创建账号
+func (r *accountRepo) Create(account *model.Account) error {
+	return r.db.Create(account).Error
+}
+
+// GetByID 根据账号ID查询，不存在时返回nil
+func (r *accountRepo) GetByID(userID string) (*model.Account, error) {
+	var account model.Account
+	if err := r.db.Where("user_id = ?", userID).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &account, nil
+}
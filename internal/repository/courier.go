@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CourierAreaQuery 派送员服务范围查询条件（分页从1开始）
+type CourierAreaQuery struct {
+	StationID string
+	District  string // 负责区县
+	Status    string
+	Page      int
+	PageSize  int
+}
+
+// apply 追加网点/区县/状态筛选
+func (q CourierAreaQuery) apply(db *gorm.DB) *gorm.DB {
+	if q.StationID != "" {
+		db = db.Where("station_id = ?", q.StationID)
+	}
+	if q.District != "" {
+		db = db.Where("JSON_CONTAINS(districts, JSON_QUOTE(?))", q.District)
+	}
+	if q.Status != "" {
+		db = db.Where("status = ?", q.Status)
+	}
+	return db
+}
+
+// CourierAreaRepo 派送员服务范围数据访问接口
+type CourierAreaRepo interface {
+	// Save 新增或整体更新服务范围
+	Save(area *model.CourierArea) error
+	// GetByCourierID 根据派送员ID查询服务范围
+	GetByCourierID(courierID string) (*model.CourierArea, error)
+	// LockByID 在事务中查询并锁定服务范围，串行化同一派送员的任务创建与包裹绑定
+	LockByID(courierID string) (*model.CourierArea, error)
+	// List 分页查询服务范围（按派送员ID排序），返回当页记录与总数
+	List(query CourierAreaQuery) ([]*model.CourierArea, int64, error)
+	// ListActive 查询启用中的服务范围（stationID为空时不限网点）
+	ListActive(stationID string) ([]*model.CourierArea, error)
+	// CountByStationID 统计归属网点的服务范围数
+	CountByStationID(stationID string) (int64, error)
+	// Delete 删除服务范围（软删除）
+	Delete(courierID string) error
+}
+
+// courierAreaRepo 实现CourierAreaRepo接口
+type courierAreaRepo struct {
+	db *gorm.DB
+}
+
+// NewCourierAreaRepo 创建仓储实例，db可为全局连接或事务句柄
+func NewCourierAreaRepo(db *gorm.DB) CourierAreaRepo {
+	return &courierAreaRepo{db: db}
+}
+
+// Save 新增或整体更新服务范围（含已删除记录时恢复）
+func (r *courierAreaRepo) Save(area *model.CourierArea) error {
+	area.DeletedAt = gorm.DeletedAt{}
+	return r.db.Unscoped().Save(area).Error
+}
+
+// GetByCourierID 根据派送员ID查询服务范围
+func (r *courierAreaRepo) GetByCourierID(courierID string) (*model.CourierArea, error) {
+	return r.first(r.db, courierID)
+}
+
+// LockByID 查询并加行锁（SELECT ... FOR UPDATE），须在事务句柄上调用
+func (r *courierAreaRepo) LockByID(courierID string) (*model.CourierArea, error) {
+	return r.first(r.db.Clauses(clause.Locking{Strength: "UPDATE"}), courierID)
+}
+
+// first 按派送员ID查询单条服务范围
+func (r *courierAreaRepo) first(db *gorm.DB, courierID string) (*model.CourierArea, error) {
+	var area model.CourierArea
+	if err := db.Where("courier_id = ?", courierID).First(&area).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errno.ErrCourierAreaNotFound
+		}
+		return nil, err
+	}
+	return &area, nil
+}
+
+// List 分页查询服务范围（按派送员ID排序），返回当页记录与总数
+func (r *courierAreaRepo) List(query CourierAreaQuery) ([]*model.CourierArea, int64, error) {
+	db := query.apply(r.db.Model(&model.CourierArea{}))
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var areas []*model.CourierArea
+	if err := paginate(db, query.Page, query.PageSize).Order("courier_id ASC").Find(&areas).Error; err != nil {
+		return nil, 0, err
+	}
+	return areas, total, nil
+}
+
+// ListActive 查询启用中的服务范围（stationID为空时不限网点；未绑定网点的派送员对任意网点可用）
+func (r *courierAreaRepo) ListActive(stationID string) ([]*model.CourierArea, error) {
+	db := r.db.Where("status = ?", "active")
+	if stationID != "" {
+		db = db.Where("station_id = ? OR station_id = ''", stationID)
+	}
+	var areas []*model.CourierArea
+	if err := db.Order("courier_id ASC").Find(&areas).Error; err != nil {
+		return nil, err
+	}
+	return areas, nil
+}
+
+// CountByStationID 统计归属网点的服务范围数（含停用）
+func (r *courierAreaRepo) CountByStationID(stationID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.CourierArea{}).Where("station_id = ?", stationID).Count(&count).Error
+	return count, err
+}
+
+// Delete 删除服务范围（软删除）
+func (r *courierAreaRepo) Delete(courierID string) error {
+	return r.db.Where("courier_id = ?", courierID).Delete(&model.CourierArea{}).Error
+}
@@ -5,49 +5,72 @@ import (
 	"time"
 
 	"github.com/LFrankl/fdu-lab3/internal/model"
-	"github.com/LFrankl/fdu-lab3/pkg/db"
 	"github.com/LFrankl/fdu-lab3/pkg/errno"
 	"gorm.io/gorm"
 )
 
+// CourierLoad 派送员当前负载（未完成任务数及其包裹总数）
+type CourierLoad struct {
+	CourierID string
+	Tasks     int
+	Packages  int
+}
+
 // DeliveryRepo 派送领域数据访问接口
 type DeliveryRepo interface {
 	// CreateTask 创建派送任务
 	CreateTask(task *model.DeliveryTask) error
 	// GetTaskByID 根据ID查询派送任务
 	GetTaskByID(taskID string) (*model.DeliveryTask, error)
-	// GetTasksByCourierID 根据派送员ID查询任务
-	GetTasksByCourierID(courierID string, status string) ([]*model.DeliveryTask, error)
-	// UpdateTask 更新派送任务
+	// ListTasksByCourierID 分页查询派送员任务（按创建时间倒序），返回当页任务与总数
+	ListTasksByCourierID(courierID string, query TaskListQuery) ([]*model.DeliveryTask, int64, error)
+	// UpdateTask 更新派送任务（乐观锁，版本冲突返回errno.ErrDeliveryTaskConflict）
 	UpdateTask(task *model.DeliveryTask) error
-	// BindPackages 绑定包裹到派送任务
+	// BindPackages 增量绑定包裹到派送任务（已绑定包裹去重，新包裹派送顺序接在末尾）
 	BindPackages(taskID string, packageIDs []string) error
+	// UnbindPackages 从派送任务解绑包裹
+	UnbindPackages(taskID string, packageIDs []string) error
+	// FindOpenTaskIDsByPackageIDs 查询包裹已绑定的其他未完成派送任务（包裹运单号→任务ID）
+	FindOpenTaskIDsByPackageIDs(packageIDs []string, excludeTaskID string) (map[string]string, error)
 	// GetPackageIDsByTaskID 查询派送任务绑定的包裹列表
 	GetPackageIDsByTaskID(taskID string) ([]string, error)
 	// CountPackagesByTaskID 统计派送任务包裹数量
 	CountPackagesByTaskID(taskID string) (int, error)
+	// CountPackagesByTaskIDs 批量统计派送任务包裹数量
+	CountPackagesByTaskIDs(taskIDs []string) (map[string]int, error)
+	// CountOpenLoadByCourierIDs 批量统计派送员未完成任务（pending/delivering/abnormal）数及其包裹数
+	CountOpenLoadByCourierIDs(courierIDs []string) (map[string]CourierLoad, error)
+	// CountOpenTasksByNodeID 统计从节点出发的未完成（pending/delivering/abnormal）派送任务数
+	CountOpenTasksByNodeID(nodeID string) (int64, error)
 	// SignPackage 包裹签收
 	SignPackage(deliveryTaskID, packageID, signerName, signerPhone, signType, remark string) error
+	// ListTaskPackages 查询派送任务全部包裹关联记录（按派送顺序）
+	ListTaskPackages(taskID string) ([]*model.DeliveryTaskPackage, error)
+	// UpdateDeliveryOrders 批量更新派送顺序（包裹运单号→顺序号）
+	UpdateDeliveryOrders(taskID string, orders map[string]int) error
 	// GetDeliveryTaskPackage 查询派送任务-包裹关联记录
 	GetDeliveryTaskPackage(deliveryTaskID, packageID string) (*model.DeliveryTaskPackage, error)
 }
 
 // deliveryRepo 实现DeliveryRepo接口
-type deliveryRepo struct{}
+type deliveryRepo struct {
+	db *gorm.DB
+}
 
-func NewDeliveryRepo() DeliveryRepo {
-	return &deliveryRepo{}
+// NewDeliveryRepo 创建仓储实例，db可为全局连接或事务句柄
+func NewDeliveryRepo(db *gorm.DB) DeliveryRepo {
+	return &deliveryRepo{db: db}
 }
 
 // CreateTask 创建派送任务
 func (r *deliveryRepo) CreateTask(task *model.DeliveryTask) error {
-	return db.DB.Create(task).Error
+	return r.db.Create(task).Error
 }
 
 // GetTaskByID 根据ID查询派送任务
 func (r *deliveryRepo) GetTaskByID(taskID string) (*model.DeliveryTask, error) {
 	var task model.DeliveryTask
-	if err := db.DB.Where("task_id = ?", taskID).First(&task).Error; err != nil {
+	if err := r.db.Where("task_id = ?", taskID).First(&task).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errno.ErrDeliveryTaskNotFound
 		}
@@ -56,47 +79,117 @@ func (r *deliveryRepo) GetTaskByID(taskID string) (*model.DeliveryTask, error) {
 	return &task, nil
 }
 
-// GetTasksByCourierID 根据派送员ID查询任务
-func (r *deliveryRepo) GetTasksByCourierID(courierID string, status string) ([]*model.DeliveryTask, error) {
-	var tasks []*model.DeliveryTask
-	query := db.DB.Where("courier_id = ?", courierID)
-	if status != "" {
-		query = query.Where("status = ?", status)
+// ListTasksByCourierID 分页查询派送员任务（按创建时间倒序），返回当页任务与总数
+func (r *deliveryRepo) ListTasksByCourierID(courierID string, query TaskListQuery) ([]*model.DeliveryTask, int64, error) {
+	db := query.apply(r.db.Model(&model.DeliveryTask{}).Where("courier_id = ?", courierID))
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
 	}
-	if err := query.Find(&tasks).Error; err != nil {
-		return nil, err
+	var tasks []*model.DeliveryTask
+	if err := query.paginate(db).Order("created_at DESC").Find(&tasks).Error; err != nil {
+		return nil, 0, err
 	}
-	return tasks, nil
+	return tasks, total, nil
 }
 
-// UpdateTask 更新派送任务
+// UpdateTask 更新派送任务（乐观锁：仅当库中版本号与task.Version一致时更新，并递增版本号）
 func (r *deliveryRepo) UpdateTask(task *model.DeliveryTask) error {
-	return db.DB.Save(task).Error
+	oldVersion := task.Version
+	task.Version++
+	// 整体更新全部列（结构体Updates会跳过零值，包裹数清零、处理结果清空将无法写入）
+	omit := omitColumns(map[string]bool{
+		"start_time":    task.StartTime.IsZero(),
+		"complete_time": task.CompleteTime.IsZero(),
+		"handle_time":   task.Abnormal.HandleTime.IsZero(),
+	})
+	result := r.db.Model(task).Select("*").Omit(omit...).Where("version = ?", oldVersion).Updates(task)
+	if result.Error != nil {
+		task.Version = oldVersion
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// 版本号已被其他写操作推进，本次修改作废
+		task.Version = oldVersion
+		return errno.ErrDeliveryTaskConflict
+	}
+	return nil
 }
 
 // BindPackages 绑定包裹到派送任务
 func (r *deliveryRepo) BindPackages(taskID string, packageIDs []string) error {
-	// 批量创建关联记录
+	// 1. 查询已绑定包裹及当前最大派送顺序（已有关联的签收信息与顺序保持不变）
+	var existing []*model.DeliveryTaskPackage
+	if err := r.db.Where("delivery_task_id = ?", taskID).Find(&existing).Error; err != nil {
+		return err
+	}
+	bound := make(map[string]struct{}, len(existing))
+	maxOrder := 0
+	for _, dtp := range existing {
+		bound[dtp.PackageID] = struct{}{}
+		if dtp.DeliveryOrder > maxOrder {
+			maxOrder = dtp.DeliveryOrder
+		}
+	}
+	// 2. 过滤重复/空包裹ID，新包裹按绑定顺序接在末尾
 	var taskPackages []*model.DeliveryTaskPackage
-	for i, pkgID := range packageIDs {
+	for _, pkgID := range packageIDs {
+		if _, ok := bound[pkgID]; ok || pkgID == "" {
+			continue
+		}
+		bound[pkgID] = struct{}{}
+		maxOrder++
 		taskPackages = append(taskPackages, &model.DeliveryTaskPackage{
 			DeliveryTaskID: taskID,
 			PackageID:      pkgID,
-			DeliveryOrder:  i + 1, // 按绑定顺序生成派送顺序
+			DeliveryOrder:  maxOrder,
 			AddedTime:      time.Now(),
 		})
 	}
-	// 先删除旧关联，再新增（避免重复绑定）
-	if err := db.DB.Where("delivery_task_id = ?", taskID).Delete(&model.DeliveryTaskPackage{}).Error; err != nil {
-		return err
+	if len(taskPackages) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(taskPackages, len(taskPackages)).Error
+}
+
+// UnbindPackages 从派送任务解绑包裹（软删除关联记录）
+func (r *deliveryRepo) UnbindPackages(taskID string, packageIDs []string) error {
+	if len(packageIDs) == 0 {
+		return nil
+	}
+	return r.db.Where("delivery_task_id = ? AND package_id IN ?", taskID, packageIDs).
+		Delete(&model.DeliveryTaskPackage{}).Error
+}
+
+// FindOpenTaskIDsByPackageIDs 查询包裹已绑定的其他未完成（pending/delivering/abnormal）派送任务
+func (r *deliveryRepo) FindOpenTaskIDsByPackageIDs(packageIDs []string, excludeTaskID string) (map[string]string, error) {
+	result := make(map[string]string)
+	if len(packageIDs) == 0 {
+		return result, nil
+	}
+	var rows []struct {
+		PackageID string
+		TaskID    string
+	}
+	err := r.db.Table("delivery_task_packages AS dtp").
+		Select("dtp.package_id AS package_id, t.task_id AS task_id").
+		Joins("JOIN delivery_tasks AS t ON t.task_id = dtp.delivery_task_id").
+		Where("dtp.package_id IN ? AND dtp.delivery_task_id <> ?", packageIDs, excludeTaskID).
+		Where("dtp.deleted_at IS NULL AND t.deleted_at IS NULL AND t.status IN ?", []string{"pending", "delivering", "abnormal"}).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
 	}
-	return db.DB.CreateInBatches(taskPackages, len(taskPackages)).Error
+	for _, row := range rows {
+		result[row.PackageID] = row.TaskID
+	}
+	return result, nil
 }
 
 // GetPackageIDsByTaskID 查询派送任务绑定的包裹列表
 func (r *deliveryRepo) GetPackageIDsByTaskID(taskID string) ([]string, error) {
 	var pkgIDs []string
-	err := db.DB.Model(&model.DeliveryTaskPackage{}).
+	err := r.db.Model(&model.DeliveryTaskPackage{}).
 		Where("delivery_task_id = ?", taskID).
 		Order("delivery_order ASC"). // 按派送顺序排序
 		Pluck("package_id", &pkgIDs).Error
@@ -106,16 +199,68 @@ func (r *deliveryRepo) GetPackageIDsByTaskID(taskID string) ([]string, error) {
 // CountPackagesByTaskID 统计派送任务包裹数量
 func (r *deliveryRepo) CountPackagesByTaskID(taskID string) (int, error) {
 	var count int64
-	err := db.DB.Model(&model.DeliveryTaskPackage{}).
+	err := r.db.Model(&model.DeliveryTaskPackage{}).
 		Where("delivery_task_id = ?", taskID).
 		Count(&count).Error
 	return int(count), err
 }
 
+// CountPackagesByTaskIDs 批量统计派送任务包裹数量
+func (r *deliveryRepo) CountPackagesByTaskIDs(taskIDs []string) (map[string]int, error) {
+	counts := make(map[string]int, len(taskIDs))
+	if len(taskIDs) == 0 {
+		return counts, nil
+	}
+	var rows []packageCountRow
+	err := r.db.Model(&model.DeliveryTaskPackage{}).
+		Select("delivery_task_id AS task_id, COUNT(*) AS count").
+		Where("delivery_task_id IN ?", taskIDs).
+		Group("delivery_task_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.TaskID] = row.Count
+	}
+	return counts, nil
+}
+
+// CountOpenLoadByCourierIDs 批量统计派送员未完成任务数及其包裹数（包裹数以关联表为准）
+func (r *deliveryRepo) CountOpenLoadByCourierIDs(courierIDs []string) (map[string]CourierLoad, error) {
+	loads := make(map[string]CourierLoad, len(courierIDs))
+	if len(courierIDs) == 0 {
+		return loads, nil
+	}
+	var rows []CourierLoad
+	err := r.db.Table("delivery_tasks AS t").
+		Select("t.courier_id AS courier_id, COUNT(DISTINCT t.task_id) AS tasks, COUNT(p.id) AS packages").
+		Joins("LEFT JOIN delivery_task_packages AS p ON p.delivery_task_id = t.task_id AND p.deleted_at IS NULL").
+		Where("t.courier_id IN ? AND t.status IN ? AND t.deleted_at IS NULL", courierIDs, []string{"pending", "delivering", "abnormal"}).
+		Group("t.courier_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		loads[row.CourierID] = row
+	}
+	return loads, nil
+}
+
+// CountOpenTasksByNodeID 统计从节点出发的未完成派送任务数
+func (r *deliveryRepo) CountOpenTasksByNodeID(nodeID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.DeliveryTask{}).
+		Where("start_node_id = ? AND status IN ?", nodeID, []string{"pending", "delivering", "abnormal"}).
+		Count(&count).Error
+	return count, err
+}
+
 // SignPackage 包裹签收
 func (r *deliveryRepo) SignPackage(deliveryTaskID, packageID, signerName, signerPhone, signType, remark string) error {
 	var dtp model.DeliveryTaskPackage
-	if err := db.DB.Where("delivery_task_id = ? AND package_id = ?", deliveryTaskID, packageID).First(&dtp).Error; err != nil {
+	if err := r.db.Where("delivery_task_id = ? AND package_id = ?", deliveryTaskID, packageID).First(&dtp).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errno.ErrPackageNotBindToDeliveryTask
 		}
@@ -123,13 +268,35 @@ func (r *deliveryRepo) SignPackage(deliveryTaskID, packageID, signerName, signer
 	}
 	// 执行签收行为
 	dtp.SignPackage(signerName, signerPhone, signType, remark)
-	return db.DB.Save(&dtp).Error
+	return r.db.Save(&dtp).Error
+}
+
+// ListTaskPackages 查询派送任务全部包裹关联记录（按派送顺序）
+func (r *deliveryRepo) ListTaskPackages(taskID string) ([]*model.DeliveryTaskPackage, error) {
+	var dtps []*model.DeliveryTaskPackage
+	err := r.db.Where("delivery_task_id = ?", taskID).
+		Order("delivery_order ASC, id ASC").
+		Find(&dtps).Error
+	return dtps, err
+}
+
+// UpdateDeliveryOrders 批量更新派送顺序（包裹运单号→顺序号）
+func (r *deliveryRepo) UpdateDeliveryOrders(taskID string, orders map[string]int) error {
+	for pkgID, order := range orders {
+		err := r.db.Model(&model.DeliveryTaskPackage{}).
+			Where("delivery_task_id = ? AND package_id = ?", taskID, pkgID).
+			Update("delivery_order", order).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // GetDeliveryTaskPackage 查询派送任务-包裹关联记录
 func (r *deliveryRepo) GetDeliveryTaskPackage(deliveryTaskID, packageID string) (*model.DeliveryTaskPackage, error) {
 	var dtp model.DeliveryTaskPackage
-	if err := db.DB.Where("delivery_task_id = ? AND package_id = ?", deliveryTaskID, packageID).First(&dtp).Error; err != nil {
+	if err := r.db.Where("delivery_task_id = ? AND package_id = ?", deliveryTaskID, packageID).First(&dtp).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errno.ErrPackageNotBindToDeliveryTask
 		}
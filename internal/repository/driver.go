@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DriverQuery 司机档案查询条件（分页从1开始）
+type DriverQuery struct {
+	HomeNodeID   string
+	LicenseClass string
+	Status       string
+	Keyword      string // 姓名模糊匹配
+	Page         int
+	PageSize     int
+}
+
+// apply 追加常驻节点/准驾车型/状态/姓名筛选
+func (q DriverQuery) apply(db *gorm.DB) *gorm.DB {
+	if q.HomeNodeID != "" {
+		db = db.Where("home_node_id = ?", q.HomeNodeID)
+	}
+	if q.LicenseClass != "" {
+		db = db.Where("license_class = ?", q.LicenseClass)
+	}
+	if q.Status != "" {
+		db = db.Where("status = ?", q.Status)
+	}
+	if q.Keyword != "" {
+		db = db.Where("driver_name LIKE ?", "%"+escapeLike(q.Keyword)+"%")
+	}
+	return db
+}
+
+// DriverRepo 司机档案数据访问接口
+type DriverRepo interface {
+	// Save 新增或整体更新司机档案
+	Save(driver *model.Driver) error
+	// GetByID 根据司机ID查询档案
+	GetByID(driverID string) (*model.Driver, error)
+	// LockByID 在事务中查询并锁定司机档案，串行化同一司机的排班
+	LockByID(driverID string) (*model.Driver, error)
+	// List 分页查询司机档案（按司机ID排序），返回当页记录与总数
+	List(query DriverQuery) ([]*model.Driver, int64, error)
+	// ListAll 查询符合条件的全部司机档案（忽略分页）
+	ListAll(query DriverQuery) ([]*model.Driver, error)
+	// CountByHomeNodeID 统计常驻节点的司机档案数
+	CountByHomeNodeID(nodeID string) (int64, error)
+	// Delete 删除司机档案（软删除）
+	Delete(driverID string) error
+}
+
+// driverRepo 实现DriverRepo接口
+type driverRepo struct {
+	db *gorm.DB
+}
+
+// NewDriverRepo 创建仓储实例，db可为全局连接或事务句柄
+func NewDriverRepo(db *gorm.DB) DriverRepo {
+	return &driverRepo{db: db}
+}
+
+// Save 新增或整体更新司机档案（含已删除记录时恢复）
+func (r *driverRepo) Save(driver *model.Driver) error {
+	driver.DeletedAt = gorm.DeletedAt{}
+	return r.db.Unscoped().Save(driver).Error
+}
+
+// GetByID 根据司机ID查询档案
+func (r *driverRepo) GetByID(driverID string) (*model.Driver, error) {
+	return r.first(r.db, driverID)
+}
+
+// LockByID 查询并加行锁（SELECT ... FOR UPDATE），须在事务句柄上调用
+func (r *driverRepo) LockByID(driverID string) (*model.Driver, error) {
+	return r.first(r.db.Clauses(clause.Locking{Strength: "UPDATE"}), driverID)
+}
+
+// first 按司机ID查询单条档案
+func (r *driverRepo) first(db *gorm.DB, driverID string) (*model.Driver, error) {
+	var driver model.Driver
+	if err := db.Where("driver_id = ?", driverID).First(&driver).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errno.ErrDriverNotFound
+		}
+		return nil, err
+	}
+	return &driver, nil
+}
+
+// List 分页查询司机档案（按司机ID排序），返回当页记录与总数
+func (r *driverRepo) List(query DriverQuery) ([]*model.Driver, int64, error) {
+	db := query.apply(r.db.Model(&model.Driver{}))
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var drivers []*model.Driver
+	if err := paginate(db, query.Page, query.PageSize).Order("driver_id ASC").Find(&drivers).Error; err != nil {
+		return nil, 0, err
+	}
+	return drivers, total, nil
+}
+
+// ListAll 查询符合条件的全部司机档案（按司机ID排序）
+func (r *driverRepo) ListAll(query DriverQuery) ([]*model.Driver, error) {
+	var drivers []*model.Driver
+	err := query.apply(r.db.Model(&model.Driver{})).Order("driver_id ASC").Find(&drivers).Error
+	return drivers, err
+}
+
+// CountByHomeNodeID 统计常驻节点的司机档案数（含停用）
+func (r *driverRepo) CountByHomeNodeID(nodeID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Driver{}).Where("home_node_id = ?", nodeID).Count(&count).Error
+	return count, err
+}
+
+// Delete 删除司机档案（软删除）
+func (r *driverRepo) Delete(driverID string) error {
+	return r.db.Where("driver_id = ?", driverID).Delete(&model.Driver{}).Error
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"gorm.io/gorm"
+)
+
+// LaneTransit 线路历史运输时效（样本数与平均耗时）
+type LaneTransit struct {
+	Samples    int64
+	AvgMinutes float64
+}
+
+// ETAAccuracy 某置信度下的送达时间预测准确率统计
+type ETAAccuracy struct {
+	Confidence          string
+	Samples             int64
+	Hits                int64 // 实际送达时间落在预测窗口内的记录数
+	MeanAbsErrorMinutes float64
+}
+
+// ETARepo 包裹送达时间预测数据访问接口
+type ETARepo interface {
+	// Create 保存一次预测
+	Create(eta *model.PackageETA) error
+	// CreateBatch 批量保存预测
+	CreateBatch(etas []*model.PackageETA) error
+	// GetLatest 查询包裹最近一次预测，无预测时返回nil
+	GetLatest(packageID string) (*model.PackageETA, error)
+	// FillActualDelivered 为包裹尚未回填的预测记录回填实际送达时间
+	FillActualDelivered(packageID string, deliveredAt time.Time) error
+	// LaneTransit 统计线路（出发节点→到达节点）自since起的历史运输时效
+	LaneTransit(fromNodeID, toNodeID string, since time.Time) (LaneTransit, error)
+	// Accuracy 按置信度统计自since起、已回填实际送达时间的预测准确率
+	Accuracy(since time.Time) ([]ETAAccuracy, error)
+}
+
+// etaRepo 实现ETARepo接口
+type etaRepo struct {
+	db *gorm.DB
+}
+
+// NewETARepo 创建仓储实例，db可为全局连接或事务句柄
+func NewETARepo(db *gorm.DB) ETARepo {
+	return &etaRepo{db: db}
+}
+
+// Create 保存一次预测
+func (r *etaRepo) Create(eta *model.PackageETA) error {
+	return r.db.Create(eta).Error
+}
+
+// CreateBatch 批量保存预测
+func (r *etaRepo) CreateBatch(etas []*model.PackageETA) error {
+	if len(etas) == 0 {
+		return nil
+	}
+	return r.db.Create(etas).Error
+}
+
+// GetLatest 查询包裹最近一次预测，无预测时返回nil
+func (r *etaRepo) GetLatest(packageID string) (*model.PackageETA, error) {
+	var eta model.PackageETA
+	if err := r.db.Where("package_id = ?", packageID).Order("predicted_at DESC, id DESC").First(&eta).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &eta, nil
+}
+
+// FillActualDelivered 为包裹尚未回填的预测记录回填实际送达时间
+func (r *etaRepo) FillActualDelivered(packageID string, deliveredAt time.Time) error {
+	return r.db.Model(&model.PackageETA{}).
+		Where("package_id = ? AND actual_delivered_at IS NULL", packageID).
+		Update("actual_delivered_at", deliveredAt).Error
+}
+
+// LaneTransit 以包裹轨迹中同一包裹的出发（transporting）与到站（arrived）记录计算线路历史运输时效
+func (r *etaRepo) LaneTransit(fromNodeID, toNodeID string, since time.Time) (LaneTransit, error) {
+	var lane LaneTransit
+	err := r.db.Table("package_traces AS d").
+		Select("COUNT(*) AS samples, COALESCE(AVG(TIMESTAMPDIFF(MINUTE, d.operation_time, a.operation_time)), 0) AS avg_minutes").
+		Joins("JOIN package_traces AS a ON a.package_id = d.package_id AND a.node_type = ? AND a.node_id = ? AND a.operation_time > d.operation_time AND a.deleted_at IS NULL", "arrived", toNodeID).
+		Where("d.node_type = ? AND d.node_id = ? AND d.operation_time >= ? AND d.deleted_at IS NULL", "transporting", fromNodeID, since).
+		Scan(&lane).Error
+	return lane, err
+}
+
+// Accuracy 按置信度统计预测准确率：命中窗口数与预测时间的平均绝对误差（分钟）
+func (r *etaRepo) Accuracy(since time.Time) ([]ETAAccuracy, error) {
+	var rows []ETAAccuracy
+	err := r.db.Model(&model.PackageETA{}).
+		Select("confidence, COUNT(*) AS samples, "+
+			"SUM(CASE WHEN actual_delivered_at BETWEEN window_start AND window_end THEN 1 ELSE 0 END) AS hits, "+
+			"AVG(ABS(TIMESTAMPDIFF(MINUTE, estimated_time, actual_delivered_at))) AS mean_abs_error_minutes").
+		Where("actual_delivered_at IS NOT NULL AND predicted_at >= ?", since).
+		Group("confidence").
+		Scan(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+	"gorm.io/gorm"
+)
+
+// NodeListQuery 网络节点查询条件（分页从1开始）
+type NodeListQuery struct {
+	Type     string
+	ParentID string
+	District string // 服务区县
+	Keyword  string // 名称/编码模糊匹配
+	Page     int
+	PageSize int
+}
+
+// apply 追加类型/上级/服务区县/关键字筛选
+func (q NodeListQuery) apply(db *gorm.DB) *gorm.DB {
+	if q.Type != "" {
+		db = db.Where("type = ?", q.Type)
+	}
+	if q.ParentID != "" {
+		db = db.Where("parent_id = ?", q.ParentID)
+	}
+	if q.District != "" {
+		db = db.Where("JSON_CONTAINS(service_districts, JSON_QUOTE(?))", q.District)
+	}
+	if q.Keyword != "" {
+		like := "%" + escapeLike(q.Keyword) + "%"
+		db = db.Where("name LIKE ? OR code LIKE ?", like, like)
+	}
+	return db
+}
+
+// NodeRepo 网络节点数据访问接口
+type NodeRepo interface {
+	// Create 创建节点
+	Create(node *model.Node) error
+	// GetByID 根据ID查询节点
+	GetByID(nodeID string) (*model.Node, error)
+	// GetByName 根据名称查询节点
+	GetByName(name string) (*model.Node, error)
+	// ExistsByNameOrCode 名称或编码是否已被其他节点占用（excludeID为空时不排除）
+	ExistsByNameOrCode(name, code, excludeID string) (bool, error)
+	// List 分页查询节点（按编码排序），返回当页节点与总数
+	List(query NodeListQuery) ([]*model.Node, int64, error)
+	// CountChildren 统计挂靠在该节点下的网点数量
+	CountChildren(nodeID string) (int64, error)
+	// Update 更新节点
+	Update(node *model.Node) error
+	// Delete 删除节点（软删除）
+	Delete(nodeID string) error
+}
+
+// nodeRepo 实现NodeRepo接口
+type nodeRepo struct {
+	db *gorm.DB
+}
+
+// NewNodeRepo 创建仓储实例，db可为全局连接或事务句柄
+func NewNodeRepo(db *gorm.DB) NodeRepo {
+	return &nodeRepo{db: db}
+}
+
+// Create 创建节点
+func (r *nodeRepo) Create(node *model.Node) error {
+	return r.db.Create(node).Error
+}
+
+// GetByID 根据ID查询节点
+func (r *nodeRepo) GetByID(nodeID string) (*model.Node, error) {
+	var node model.Node
+	if err := r.db.Where("node_id = ?", nodeID).First(&node).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errno.ErrNodeNotFound
+		}
+		return nil, err
+	}
+	return &node, nil
+}
+
+// GetByName 根据名称查询节点
+func (r *nodeRepo) GetByName(name string) (*model.Node, error) {
+	var node model.Node
+	if err := r.db.Where("name = ?", name).First(&node).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errno.ErrNodeNotFound
+		}
+		return nil, err
+	}
+	return &node, nil
+}
+
+// ExistsByNameOrCode 名称或编码是否已被其他节点占用（含已删除节点，与唯一索引保持一致）
+func (r *nodeRepo) ExistsByNameOrCode(name, code, excludeID string) (bool, error) {
+	db := r.db.Unscoped().Model(&model.Node{}).Where("name = ? OR code = ?", name, code)
+	if excludeID != "" {
+		db = db.Where("node_id <> ?", excludeID)
+	}
+	var count int64
+	if err := db.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// List 分页查询节点（按编码排序），返回当页节点与总数
+func (r *nodeRepo) List(query NodeListQuery) ([]*model.Node, int64, error) {
+	db := query.apply(r.db.Model(&model.Node{}))
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var nodes []*model.Node
+	if err := paginate(db, query.Page, query.PageSize).Order("code ASC").Find(&nodes).Error; err != nil {
+		return nil, 0, err
+	}
+	return nodes, total, nil
+}
+
+// CountChildren 统计挂靠在该节点下的网点数量
+func (r *nodeRepo) CountChildren(nodeID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Node{}).Where("parent_id = ?", nodeID).Count(&count).Error
+	return count, err
+}
+
+// Update 更新节点（整体保存，支持清空可选字段）
+func (r *nodeRepo) Update(node *model.Node) error {
+	return r.db.Save(node).Error
+}
+
+// Delete 删除节点（软删除）
+func (r *nodeRepo) Delete(nodeID string) error {
+	return r.db.Where("node_id = ?", nodeID).Delete(&model.Node{}).Error
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"gorm.io/gorm"
+)
+
+func TestNodeListQueryKeyword(t *testing.T) {
+	db := dryRunDB(t)
+	got := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var nodes []*model.Node
+		return NodeListQuery{Keyword: "100%_"}.apply(tx.Model(&model.Node{})).Find(&nodes)
+	})
+	want := "SELECT * FROM `nodes` WHERE (name LIKE '%100\\%\\_%' OR code LIKE '%100\\%\\_%') AND `nodes`.`deleted_at` IS NULL"
+	if got != want {
+		t.Fatalf("SQL =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -1,22 +1,140 @@
 package repository
 
 import (
+	"errors"
 	"time"
 
 	"github.com/LFrankl/fdu-lab3/internal/model"
 	"github.com/LFrankl/fdu-lab3/internal/util"
-	"github.com/LFrankl/fdu-lab3/pkg/db"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
 	"gorm.io/gorm"
 )
 
+// AbnormalRecordQuery 异常记录查询条件（分页从1开始）
+type AbnormalRecordQuery struct {
+	AbnormalType  string
+	NodeName      string
+	Statuses      []string // 为空不限
+	AssigneeID    string
+	CreatedBefore time.Time // 创建时间上界（不含），用于按时长筛选积压记录，零值不限
+	Page          int
+	PageSize      int
+}
+
+// apply 追加类型/节点/状态/处理人/时长筛选
+func (q AbnormalRecordQuery) apply(db *gorm.DB) *gorm.DB {
+	if q.AbnormalType != "" {
+		db = db.Where("abnormal_type = ?", q.AbnormalType)
+	}
+	if q.NodeName != "" {
+		db = db.Where("node_name = ?", q.NodeName)
+	}
+	if len(q.Statuses) > 0 {
+		db = db.Where("status IN ?", q.Statuses)
+	}
+	if q.AssigneeID != "" {
+		db = db.Where("assignee_id = ?", q.AssigneeID)
+	}
+	if !q.CreatedBefore.IsZero() {
+		db = db.Where("created_at < ?", q.CreatedBefore)
+	}
+	return db
+}
+
+// paginate 追加分页
+func (q AbnormalRecordQuery) paginate(db *gorm.DB) *gorm.DB {
+	return paginate(db, q.Page, q.PageSize)
+}
+
+// PackageSearchQuery 包裹检索条件（按游标分页，排序键为SortField与运单号）
+type PackageSearchQuery struct {
+	ReceiverPhone    string
+	SenderPhone      string
+	ReceiverName     string // 前缀匹配
+	ReceiverCity     string
+	ReceiverDistrict string
+	Statuses         []string // 为空不限
+	CurrentNodeID    string
+	StartTime        time.Time // 创建时间下界（含），零值不限
+	EndTime          time.Time // 创建时间上界（不含），零值不限
+	SortField        string    // created_at/updated_at
+	Desc             bool
+	After            *PackageCursor // 上一页最后一条的排序值，nil为首页
+	Limit            int
+}
+
+// PackageCursor 包裹检索游标：上一页最后一条的排序字段值与运单号
+type PackageCursor struct {
+	SortValue time.Time
+	PackageID string
+}
+
+// apply 追加电话/姓名/地区/状态/节点/创建时间筛选
+func (q PackageSearchQuery) apply(db *gorm.DB) *gorm.DB {
+	if q.ReceiverPhone != "" {
+		db = db.Where("receiver_phone = ?", q.ReceiverPhone)
+	}
+	if q.SenderPhone != "" {
+		db = db.Where("sender_phone = ?", q.SenderPhone)
+	}
+	if q.ReceiverName != "" {
+		db = db.Where("receiver_name LIKE ?", escapeLike(q.ReceiverName)+"%")
+	}
+	if q.ReceiverCity != "" {
+		db = db.Where("receiver_city = ?", q.ReceiverCity)
+	}
+	if q.ReceiverDistrict != "" {
+		db = db.Where("receiver_district = ?", q.ReceiverDistrict)
+	}
+	if len(q.Statuses) > 0 {
+		db = db.Where("status IN ?", q.Statuses)
+	}
+	if q.CurrentNodeID != "" {
+		db = db.Where("current_node_id = ?", q.CurrentNodeID)
+	}
+	if !q.StartTime.IsZero() {
+		db = db.Where("created_at >= ?", q.StartTime)
+	}
+	if !q.EndTime.IsZero() {
+		db = db.Where("created_at < ?", q.EndTime)
+	}
+	return db
+}
+
+// seek 追加游标条件与排序（排序字段相同时按运单号），不使用偏移量，翻页开销与页码无关
+func (q PackageSearchQuery) seek(db *gorm.DB) *gorm.DB {
+	field, cmp, dir := "created_at", ">", "ASC"
+	if q.SortField == "updated_at" {
+		field = "updated_at"
+	}
+	if q.Desc {
+		cmp, dir = "<", "DESC"
+	}
+	if q.After != nil {
+		db = db.Where(field+" "+cmp+" ? OR ("+field+" = ? AND package_id "+cmp+" ?)",
+			q.After.SortValue, q.After.SortValue, q.After.PackageID)
+	}
+	return db.Order(field + " " + dir).Order("package_id " + dir).Limit(q.Limit)
+}
+
 // PackageRepository 包裹数据访问接口
 type PackageRepository interface {
 	Create(pkg *model.Package) error
+	CreateBatch(pkgs []*model.Package) error
 	GetByID(packageID string) (*model.Package, error)
 	UpdateStatus(packageID, status, reason, handler string) error
+	UpdateCurrentNode(packageIDs []string, nodeID string) error
+	ListPendingDeliveryAtNode(nodeID string, districts []string) ([]*model.Package, error)
 	CreateTrace(trace *model.PackageTrace) error
+	CreateTraces(traces []*model.PackageTrace) error
 	GetTracesByPackageID(packageID string) ([]model.PackageTrace, error)
 	CreateAbnormalRecord(record *model.AbnormalRecord) error
+	GetAbnormalRecordByID(recordID string) (*model.AbnormalRecord, error)
+	GetAbnormalRecordsByPackageID(packageID string) ([]model.AbnormalRecord, error)
+	ListAbnormalRecords(query AbnormalRecordQuery) ([]*model.AbnormalRecord, int64, error)
+	UpdateAbnormalRecord(record *model.AbnormalRecord, fromStatus string) error
+	CreateStatusLog(log *model.PackageStatusLog) error
+	Search(query PackageSearchQuery) ([]*model.Package, error)
 }
 
 // packageRepository 实现
@@ -25,10 +143,10 @@ type packageRepository struct {
 	idGen *util.IDGenerator
 }
 
-// NewPackageRepository 创建包裹仓库实例
-func NewPackageRepository() PackageRepository {
+// NewPackageRepository 创建包裹仓库实例，db可为全局连接或事务句柄
+func NewPackageRepository(db *gorm.DB) PackageRepository {
 	return &packageRepository{
-		db:    db.DB,
+		db:    db,
 		idGen: util.NewIDGenerator(),
 	}
 }
@@ -38,10 +156,27 @@ func (r *packageRepository) Create(pkg *model.Package) error {
 	return r.db.Create(pkg).Error
 }
 
+// CreateBatch 批量创建包裹（单条INSERT语句），运单号重复时返回errno.ErrPackageDuplicated
+func (r *packageRepository) CreateBatch(pkgs []*model.Package) error {
+	if len(pkgs) == 0 {
+		return nil
+	}
+	if err := r.db.Create(pkgs).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return errno.ErrPackageDuplicated
+		}
+		return err
+	}
+	return nil
+}
+
 // GetByID 根据运单号获取包裹
 func (r *packageRepository) GetByID(packageID string) (*model.Package, error) {
 	var pkg model.Package
 	if err := r.db.Where("package_id = ?", packageID).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errno.ErrPackageNotFound
+		}
 		return nil, err
 	}
 	return &pkg, nil
@@ -65,6 +200,36 @@ func (r *packageRepository) UpdateStatus(packageID, status, reason, handler stri
 		Updates(updateData).Error
 }
 
+// UpdateCurrentNode 批量更新包裹当前所在节点
+func (r *packageRepository) UpdateCurrentNode(packageIDs []string, nodeID string) error {
+	if len(packageIDs) == 0 {
+		return nil
+	}
+	return r.db.Model(&model.Package{}).
+		Where("package_id IN ?", packageIDs).
+		Updates(map[string]interface{}{"current_node_id": nodeID, "updated_at": time.Now()}).Error
+}
+
+// ListPendingDeliveryAtNode 查询在节点待派送的包裹：已到站（arrived）、位于该节点（未记录所在节点的按收件区县归属），且未绑定未完成的派送任务
+func (r *packageRepository) ListPendingDeliveryAtNode(nodeID string, districts []string) ([]*model.Package, error) {
+	db := r.db.Where("status = ?", "arrived")
+	if len(districts) > 0 {
+		db = db.Where("current_node_id = ? OR (current_node_id = '' AND receiver_district IN ?)", nodeID, districts)
+	} else {
+		db = db.Where("current_node_id = ?", nodeID)
+	}
+	bound := r.db.Table("delivery_task_packages AS dtp").
+		Select("1").
+		Joins("JOIN delivery_tasks AS t ON t.task_id = dtp.delivery_task_id").
+		Where("dtp.package_id = packages.package_id AND dtp.deleted_at IS NULL AND t.deleted_at IS NULL").
+		Where("t.status IN ?", []string{"pending", "delivering", "abnormal"})
+	var pkgs []*model.Package
+	err := db.Where("NOT EXISTS (?)", bound).
+		Order("receiver_district ASC, package_id ASC").
+		Find(&pkgs).Error
+	return pkgs, err
+}
+
 // CreateTrace 创建包裹轨迹
 func (r *packageRepository) CreateTrace(trace *model.PackageTrace) error {
 	if trace.TraceID == "" {
@@ -76,6 +241,29 @@ func (r *packageRepository) CreateTrace(trace *model.PackageTrace) error {
 	return r.db.Create(trace).Error
 }
 
+// CreateTraces 批量创建包裹轨迹（未指定ID与操作时间的补全），轨迹ID重复时返回errno.ErrPackageDuplicated
+func (r *packageRepository) CreateTraces(traces []*model.PackageTrace) error {
+	if len(traces) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for _, trace := range traces {
+		if trace.TraceID == "" {
+			trace.TraceID = r.idGen.GenerateTraceID()
+		}
+		if trace.OperationTime.IsZero() {
+			trace.OperationTime = now
+		}
+	}
+	if err := r.db.Create(traces).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return errno.ErrPackageDuplicated
+		}
+		return err
+	}
+	return nil
+}
+
 // GetTracesByPackageID 获取包裹轨迹
 func (r *packageRepository) GetTracesByPackageID(packageID string) ([]model.PackageTrace, error) {
 	var traces []model.PackageTrace
@@ -94,3 +282,71 @@ func (r *packageRepository) CreateAbnormalRecord(record *model.AbnormalRecord) e
 	}
 	return r.db.Create(record).Error
 }
+
+// GetAbnormalRecordByID 根据ID查询异常记录
+func (r *packageRepository) GetAbnormalRecordByID(recordID string) (*model.AbnormalRecord, error) {
+	var record model.AbnormalRecord
+	if err := r.db.Where("record_id = ?", recordID).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errno.ErrAbnormalRecordNotFound
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// GetAbnormalRecordsByPackageID 获取包裹全部异常记录（按上报时间正序）
+func (r *packageRepository) GetAbnormalRecordsByPackageID(packageID string) ([]model.AbnormalRecord, error) {
+	var records []model.AbnormalRecord
+	if err := r.db.Where("package_id = ?", packageID).
+		Order("created_at ASC").
+		Find(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// ListAbnormalRecords 分页查询异常记录（按创建时间正序，积压最久的在前），返回当页记录与总数
+func (r *packageRepository) ListAbnormalRecords(query AbnormalRecordQuery) ([]*model.AbnormalRecord, int64, error) {
+	db := query.apply(r.db.Model(&model.AbnormalRecord{}))
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var records []*model.AbnormalRecord
+	if err := query.paginate(db).Order("created_at ASC").Find(&records).Error; err != nil {
+		return nil, 0, err
+	}
+	return records, total, nil
+}
+
+// UpdateAbnormalRecord 更新异常记录（仅当库中状态仍为fromStatus时更新，否则返回errno.ErrAbnormalRecordConflict）
+func (r *packageRepository) UpdateAbnormalRecord(record *model.AbnormalRecord, fromStatus string) error {
+	// 整体更新全部列（结构体Updates会跳过零值，重新打开时清空的处理方式/处理人将无法写入）
+	omit := omitColumns(map[string]bool{"processing_time": record.ProcessingTime.IsZero()})
+	result := r.db.Model(record).Select("*").Omit(omit...).Where("status = ?", fromStatus).Updates(record)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errno.ErrAbnormalRecordConflict
+	}
+	return nil
+}
+
+// CreateStatusLog 创建状态变更审计记录
+func (r *packageRepository) CreateStatusLog(log *model.PackageStatusLog) error {
+	if log.LogID == "" {
+		log.LogID = r.idGen.GenerateStatusLogID()
+	}
+	return r.db.Create(log).Error
+}
+
+// Search 按条件检索包裹（游标分页），返回至多query.Limit条
+func (r *packageRepository) Search(query PackageSearchQuery) ([]*model.Package, error) {
+	var pkgs []*model.Package
+	if err := query.seek(query.apply(r.db.Model(&model.Package{}))).Find(&pkgs).Error; err != nil {
+		return nil, err
+	}
+	return pkgs, nil
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"gorm.io/gorm"
+)
+
+func TestPackageSearchQuerySQL(t *testing.T) {
+	db := dryRunDB(t)
+	after := &PackageCursor{SortValue: time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC), PackageID: "SF001"}
+	tests := []struct {
+		name  string
+		query PackageSearchQuery
+		want  string
+	}{
+		{"首页默认按创建时间升序", PackageSearchQuery{Limit: 21},
+			"SELECT * FROM `packages` WHERE `packages`.`deleted_at` IS NULL ORDER BY created_at ASC,package_id ASC LIMIT 21"},
+		{"按更新时间降序翻页", PackageSearchQuery{SortField: "updated_at", Desc: true, After: after, Limit: 11},
+			"SELECT * FROM `packages` WHERE (updated_at < '2024-05-01 08:00:00' OR (updated_at = '2024-05-01 08:00:00' AND package_id < 'SF001')) AND `packages`.`deleted_at` IS NULL ORDER BY updated_at DESC,package_id DESC LIMIT 11"},
+		{"筛选条件与游标组合", PackageSearchQuery{ReceiverName: "张_%", Statuses: []string{"sorted", "arrived"},
+			StartTime: after.SortValue, After: after, Limit: 2},
+			"SELECT * FROM `packages` WHERE receiver_name LIKE '张\\_\\%%' AND status IN ('sorted','arrived') AND created_at >= '2024-05-01 08:00:00' AND (created_at > '2024-05-01 08:00:00' OR (created_at = '2024-05-01 08:00:00' AND package_id > 'SF001')) AND `packages`.`deleted_at` IS NULL ORDER BY created_at ASC,package_id ASC LIMIT 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+				var pkgs []*model.Package
+				return tt.query.seek(tt.query.apply(tx.Model(&model.Package{}))).Find(&pkgs)
+			})
+			if got != tt.want {
+				t.Fatalf("SQL =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
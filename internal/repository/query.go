@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TaskListQuery 任务列表查询条件（分页从1开始）
+type TaskListQuery struct {
+	Status    string
+	StartTime time.Time // 创建时间下界（含），零值不限
+	EndTime   time.Time // 创建时间上界（不含），零值不限
+	Page      int
+	PageSize  int
+}
+
+// apply 追加状态与时间范围筛选
+func (q TaskListQuery) apply(db *gorm.DB) *gorm.DB {
+	if q.Status != "" {
+		db = db.Where("status = ?", q.Status)
+	}
+	if !q.StartTime.IsZero() {
+		db = db.Where("created_at >= ?", q.StartTime)
+	}
+	if !q.EndTime.IsZero() {
+		db = db.Where("created_at < ?", q.EndTime)
+	}
+	return db
+}
+
+// paginate 追加分页
+func (q TaskListQuery) paginate(db *gorm.DB) *gorm.DB {
+	return paginate(db, q.Page, q.PageSize)
+}
+
+// escapeLike 转义LIKE通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
+
+// omitColumns 整体更新（Select("*")）时不写入的列：创建时间及标记为true的列（如零值时间，表中默认NULL，写入零日期会被严格模式拒绝）
+func omitColumns(conditional map[string]bool) []string {
+	omit := []string{"created_at"}
+	for column, skip := range conditional {
+		if skip {
+			omit = append(omit, column)
+		}
+	}
+	return omit
+}
+
+// paginate 按页码与每页条数追加偏移与条数限制
+func paginate(db *gorm.DB, page, pageSize int) *gorm.DB {
+	return db.Offset((page - 1) * pageSize).Limit(pageSize)
+}
+
+// packageCountRow 按任务分组统计包裹数量的结果行
+type packageCountRow struct {
+	TaskID string
+	Count  int
+}
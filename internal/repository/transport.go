@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/LFrankl/fdu-lab3/internal/model"
-	"github.com/LFrankl/fdu-lab3/pkg/db"
 	"github.com/LFrankl/fdu-lab3/pkg/errno"
 	"gorm.io/gorm"
 )
@@ -17,34 +16,53 @@ type TransportRepo interface {
 	CreateTask(task *model.TransportTask) error
 	// GetTaskByID 根据ID查询运输任务
 	GetTaskByID(taskID string) (*model.TransportTask, error)
-	// GetTasksByDriverID 根据司机ID查询任务
-	GetTasksByDriverID(driverID string, status string) ([]*model.TransportTask, error)
-	// UpdateTask 更新运输任务
+	// ListTasksByDriverID 分页查询司机任务（按创建时间倒序），返回当页任务与总数
+	ListTasksByDriverID(driverID string, query TaskListQuery) ([]*model.TransportTask, int64, error)
+	// UpdateTask 更新运输任务（乐观锁，版本冲突返回errno.ErrTransportTaskConflict）
 	UpdateTask(task *model.TransportTask) error
-	// BindPackages 绑定包裹到运输任务
+	// BindPackages 绑定包裹到运输任务（仅新增关联，包裹数量由调用方重新统计后随任务更新）
 	BindPackages(taskID string, packageIDs []string) error
+	// UnbindPackages 从运输任务解绑包裹（包裹数量由调用方重新统计后随任务更新）
+	UnbindPackages(taskID string, packageIDs []string) error
 	// GetPackageIDsByTaskID 查询运输任务绑定的包裹列表
 	GetPackageIDsByTaskID(taskID string) ([]string, error)
 	// CountPackagesByTaskID 统计运输任务包裹数量
 	CountPackagesByTaskID(taskID string) (int, error)
+	// CountPackagesByTaskIDs 批量统计运输任务包裹数量
+	CountPackagesByTaskIDs(taskIDs []string) (map[string]int, error)
+	// SumLoadByTaskID 统计运输任务装载重量(kg)与体积(m³)
+	SumLoadByTaskID(taskID string) (weight, volume float64, err error)
+	// CountOpenTasksByVehicleID 统计车辆未完成（pending/transporting/arrived/abnormal）的运输任务数
+	CountOpenTasksByVehicleID(vehicleID string) (int64, error)
+	// CountOpenTasksByNodeID 统计以节点为出发或到达节点的未完成（pending/transporting/arrived/abnormal）运输任务数
+	CountOpenTasksByNodeID(nodeID string) (int64, error)
+	// ListActiveTasksByDriverIDs 查询司机进行中（pending/transporting/abnormal）的运输任务
+	ListActiveTasksByDriverIDs(driverIDs []string) ([]*model.TransportTask, error)
+	// ListDriverTasksSince 查询司机自since起创建的运输任务（用于统计当日驾驶时长）
+	ListDriverTasksSince(driverIDs []string, since time.Time) ([]*model.TransportTask, error)
+	// FindOpenTaskByPackageID 查询包裹绑定的进行中（pending/transporting/abnormal）运输任务，未绑定时返回nil
+	FindOpenTaskByPackageID(packageID string) (*model.TransportTask, error)
 }
 
 // transportRepo 实现TransportRepo接口
-type transportRepo struct{}
+type transportRepo struct {
+	db *gorm.DB
+}
 
-func NewTransportRepo() TransportRepo {
-	return &transportRepo{}
+// NewTransportRepo 创建仓储实例，db可为全局连接或事务句柄
+func NewTransportRepo(db *gorm.DB) TransportRepo {
+	return &transportRepo{db: db}
 }
 
 // CreateTask 创建运输任务
 func (r *transportRepo) CreateTask(task *model.TransportTask) error {
-	return db.DB.Create(task).Error
+	return r.db.Create(task).Error
 }
 
 // GetTaskByID 根据ID查询运输任务
 func (r *transportRepo) GetTaskByID(taskID string) (*model.TransportTask, error) {
 	var task model.TransportTask
-	if err := db.DB.Where("task_id = ?", taskID).First(&task).Error; err != nil {
+	if err := r.db.Where("task_id = ?", taskID).First(&task).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errno.ErrTransportTaskNotFound
 		}
@@ -53,22 +71,42 @@ func (r *transportRepo) GetTaskByID(taskID string) (*model.TransportTask, error)
 	return &task, nil
 }
 
-// GetTasksByDriverID 根据司机ID查询任务
-func (r *transportRepo) GetTasksByDriverID(driverID string, status string) ([]*model.TransportTask, error) {
-	var tasks []*model.TransportTask
-	query := db.DB.Where("driver_id = ?", driverID)
-	if status != "" {
-		query = query.Where("status = ?", status)
+// ListTasksByDriverID 分页查询司机任务（按创建时间倒序），返回当页任务与总数
+func (r *transportRepo) ListTasksByDriverID(driverID string, query TaskListQuery) ([]*model.TransportTask, int64, error) {
+	db := query.apply(r.db.Model(&model.TransportTask{}).Where("driver_id = ?", driverID))
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
 	}
-	if err := query.Find(&tasks).Error; err != nil {
-		return nil, err
+	var tasks []*model.TransportTask
+	if err := query.paginate(db).Order("created_at DESC").Find(&tasks).Error; err != nil {
+		return nil, 0, err
 	}
-	return tasks, nil
+	return tasks, total, nil
 }
 
-// UpdateTask 更新运输任务
+// UpdateTask 更新运输任务（乐观锁：仅当库中版本号与task.Version一致时更新，并递增版本号）
 func (r *transportRepo) UpdateTask(task *model.TransportTask) error {
-	return db.DB.Updates(task).Error
+	oldVersion := task.Version
+	task.Version++
+	// 整体更新全部列（结构体Updates会跳过零值，包裹数/载重清零、处理结果清空将无法写入）
+	omit := omitColumns(map[string]bool{
+		"estimated_time":     task.EstimatedTime.IsZero(),
+		"actual_arrive_time": task.ActualArriveTime.IsZero(),
+		"handle_time":        task.Abnormal.HandleTime.IsZero(),
+		"route_json":         task.Route.RouteJSON == "",
+	})
+	result := r.db.Model(task).Select("*").Omit(omit...).Where("version = ?", oldVersion).Updates(task)
+	if result.Error != nil {
+		task.Version = oldVersion
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// 版本号已被其他写操作推进，本次修改作废
+		task.Version = oldVersion
+		return errno.ErrTransportTaskConflict
+	}
+	return nil
 }
 
 // GetBoundPackageCount 查询任务已绑定的包裹总数
@@ -77,7 +115,7 @@ func (r *transportRepo) GetBoundPackageCount(taskID string) (int, error) {
 		return 0, errors.New("任务ID不能为空")
 	}
 	var count int64
-	err := db.DB.Model(&model.TransportTaskPackage{}).
+	err := r.db.Model(&model.TransportTaskPackage{}).
 		Where("transport_task_id = ?", taskID).
 		Count(&count).Error
 	if err != nil {
@@ -86,16 +124,6 @@ func (r *transportRepo) GetBoundPackageCount(taskID string) (int, error) {
 	return int(count), nil
 }
 
-// UpdatePackageCount 单独更新任务的包裹数量
-func (r *transportRepo) UpdatePackageCount(taskID string, count int) error {
-	if taskID == "" {
-		return errors.New("任务ID不能为空")
-	}
-	return db.DB.Model(&model.TransportTask{}).
-		Where("task_id = ?", taskID).
-		Update("package_count", count).Error
-}
-
 // BindPackages 绑定包裹到运输任务
 func (r *transportRepo) BindPackages(taskID string, packageIDs []string) error {
 	// 1. 入参基础校验
@@ -108,7 +136,7 @@ func (r *transportRepo) BindPackages(taskID string, packageIDs []string) error {
 
 	// 2. 查询该任务已绑定的包裹ID（用于过滤重复）
 	var existPkgIDs []string
-	err := db.DB.Model(&model.TransportTaskPackage{}).
+	err := r.db.Model(&model.TransportTaskPackage{}).
 		Where("transport_task_id = ?", taskID).
 		Pluck("package_id", &existPkgIDs).Error
 	if err != nil {
@@ -145,30 +173,30 @@ func (r *transportRepo) BindPackages(taskID string, packageIDs []string) error {
 	// 6. 批量新增新包裹关联
 	batchSize := 100
 	if len(newTaskPackages) <= batchSize {
-		err = db.DB.Create(newTaskPackages).Error
+		err = r.db.Create(newTaskPackages).Error
 	} else {
-		err = db.DB.CreateInBatches(newTaskPackages, batchSize).Error
+		err = r.db.CreateInBatches(newTaskPackages, batchSize).Error
 	}
 	if err != nil {
 		return fmt.Errorf("新增包裹关联失败：%w", err)
 	}
 
-	// 7. 核心：重新计算并更新任务的包裹数量
-	totalCount, err := r.CountPackagesByTaskID(taskID)
-	if err != nil {
-		return fmt.Errorf("获取包裹总数失败：%w", err)
-	}
-	if err = r.UpdatePackageCount(taskID, totalCount); err != nil {
-		return fmt.Errorf("更新包裹数量失败：%w", err)
-	}
-
 	return nil
 }
 
+// UnbindPackages 从运输任务解绑包裹（软删除关联记录）
+func (r *transportRepo) UnbindPackages(taskID string, packageIDs []string) error {
+	if len(packageIDs) == 0 {
+		return nil
+	}
+	return r.db.Where("transport_task_id = ? AND package_id IN ?", taskID, packageIDs).
+		Delete(&model.TransportTaskPackage{}).Error
+}
+
 // GetPackageIDsByTaskID 查询运输任务绑定的包裹列表
 func (r *transportRepo) GetPackageIDsByTaskID(taskID string) ([]string, error) {
 	var pkgIDs []string
-	err := db.DB.Model(&model.TransportTaskPackage{}).
+	err := r.db.Model(&model.TransportTaskPackage{}).
 		Where("transport_task_id = ?", taskID).
 		Pluck("package_id", &pkgIDs).Error
 	return pkgIDs, err
@@ -177,8 +205,98 @@ func (r *transportRepo) GetPackageIDsByTaskID(taskID string) ([]string, error) {
 // CountPackagesByTaskID 统计运输任务包裹数量
 func (r *transportRepo) CountPackagesByTaskID(taskID string) (int, error) {
 	var count int64
-	err := db.DB.Model(&model.TransportTaskPackage{}).
+	err := r.db.Model(&model.TransportTaskPackage{}).
 		Where("transport_task_id = ?", taskID).
 		Count(&count).Error
 	return int(count), err
 }
+
+// CountPackagesByTaskIDs 批量统计运输任务包裹数量
+func (r *transportRepo) CountPackagesByTaskIDs(taskIDs []string) (map[string]int, error) {
+	counts := make(map[string]int, len(taskIDs))
+	if len(taskIDs) == 0 {
+		return counts, nil
+	}
+	var rows []packageCountRow
+	err := r.db.Model(&model.TransportTaskPackage{}).
+		Select("transport_task_id AS task_id, COUNT(*) AS count").
+		Where("transport_task_id IN ?", taskIDs).
+		Group("transport_task_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.TaskID] = row.Count
+	}
+	return counts, nil
+}
+
+// SumLoadByTaskID 统计运输任务装载重量(kg)与体积(m³，长×宽×高cm³/1e6)，以关联表为准（已删除的包裹不计入）
+func (r *transportRepo) SumLoadByTaskID(taskID string) (weight, volume float64, err error) {
+	var row struct {
+		Weight float64
+		Volume float64
+	}
+	err = r.db.Table("transport_task_packages AS ttp").
+		Select("COALESCE(SUM(p.weight), 0) AS weight, COALESCE(SUM(p.length * p.width * p.height), 0) / 1000000 AS volume").
+		Joins("JOIN packages AS p ON p.package_id = ttp.package_id AND p.deleted_at IS NULL").
+		Where("ttp.transport_task_id = ? AND ttp.deleted_at IS NULL", taskID).
+		Scan(&row).Error
+	return row.Weight, row.Volume, err
+}
+
+// CountOpenTasksByVehicleID 统计车辆未完成的运输任务数
+func (r *transportRepo) CountOpenTasksByVehicleID(vehicleID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.TransportTask{}).
+		Where("vehicle_id = ? AND status IN ?", vehicleID, []string{"pending", "transporting", "arrived", "abnormal"}).
+		Count(&count).Error
+	return count, err
+}
+
+// CountOpenTasksByNodeID 统计以节点为出发或到达节点的未完成运输任务数
+func (r *transportRepo) CountOpenTasksByNodeID(nodeID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.TransportTask{}).
+		Where("start_node_id = ? OR end_node_id = ?", nodeID, nodeID).
+		Where("status IN ?", []string{"pending", "transporting", "arrived", "abnormal"}).
+		Count(&count).Error
+	return count, err
+}
+
+// ListActiveTasksByDriverIDs 查询司机进行中的运输任务（已到站即视为司机空闲）
+func (r *transportRepo) ListActiveTasksByDriverIDs(driverIDs []string) ([]*model.TransportTask, error) {
+	var tasks []*model.TransportTask
+	if len(driverIDs) == 0 {
+		return tasks, nil
+	}
+	err := r.db.Where("driver_id IN ? AND status IN ?", driverIDs, []string{"pending", "transporting", "abnormal"}).
+		Order("created_at ASC").Find(&tasks).Error
+	return tasks, err
+}
+
+// ListDriverTasksSince 查询司机自since起创建的运输任务
+func (r *transportRepo) ListDriverTasksSince(driverIDs []string, since time.Time) ([]*model.TransportTask, error) {
+	var tasks []*model.TransportTask
+	if len(driverIDs) == 0 {
+		return tasks, nil
+	}
+	err := r.db.Where("driver_id IN ? AND created_at >= ?", driverIDs, since).Find(&tasks).Error
+	return tasks, err
+}
+
+// FindOpenTaskByPackageID 查询包裹绑定的进行中运输任务（取最近创建的一条），未绑定时返回nil
+func (r *transportRepo) FindOpenTaskByPackageID(packageID string) (*model.TransportTask, error) {
+	var tasks []*model.TransportTask
+	err := r.db.Model(&model.TransportTask{}).
+		Joins("JOIN transport_task_packages AS ttp ON ttp.transport_task_id = transport_tasks.task_id AND ttp.deleted_at IS NULL").
+		Where("ttp.package_id = ? AND transport_tasks.status IN ?", packageID, []string{"pending", "transporting", "abnormal"}).
+		Order("transport_tasks.created_at DESC").
+		Limit(1).
+		Find(&tasks).Error
+	if err != nil || len(tasks) == 0 {
+		return nil, err
+	}
+	return tasks[0], nil
+}
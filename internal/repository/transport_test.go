@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sqlRecorder 记录试运行生成的SQL
+type sqlRecorder struct {
+	logger.Interface
+	sql []string
+}
+
+func (r *sqlRecorder) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	sql, _ := fc()
+	r.sql = append(r.sql, sql)
+}
+
+func TestUpdateTaskVersionConflict(t *testing.T) {
+	rec := &sqlRecorder{Interface: logger.Discard}
+	// 试运行不写库（跳过默认事务，避免连接数据库），影响行数为0，等同于版本号已被其他写操作推进
+	db := dryRunDB(t).Session(&gorm.Session{SkipDefaultTransaction: true, Logger: rec})
+
+	transport := &model.TransportTask{TaskID: "T1", Status: "transporting", Version: 3}
+	if err := NewTransportRepo(db).UpdateTask(transport); !errors.Is(err, errno.ErrTransportTaskConflict) {
+		t.Fatalf("TransportRepo.UpdateTask() error = %v, want %v", err, errno.ErrTransportTaskConflict)
+	}
+	if transport.Version != 3 {
+		t.Fatalf("冲突后运输任务版本号 = %d, want 3", transport.Version)
+	}
+	delivery := &model.DeliveryTask{TaskID: "D1", Status: "delivering", Version: 5}
+	if err := NewDeliveryRepo(db).UpdateTask(delivery); !errors.Is(err, errno.ErrDeliveryTaskConflict) {
+		t.Fatalf("DeliveryRepo.UpdateTask() error = %v, want %v", err, errno.ErrDeliveryTaskConflict)
+	}
+	if delivery.Version != 5 {
+		t.Fatalf("冲突后派送任务版本号 = %d, want 5", delivery.Version)
+	}
+	// 条件为读取时的版本号，写入递增后的版本号
+	wants := []string{"`version`=4", "version = 3", "`version`=6", "version = 5"}
+	all := strings.Join(rec.sql, "\n")
+	for _, want := range wants {
+		if !strings.Contains(all, want) {
+			t.Fatalf("SQL缺少%q：\n%s", want, all)
+		}
+	}
+}
+
+func TestSumLoadByTaskIDExcludesDeletedPackages(t *testing.T) {
+	rec := &sqlRecorder{Interface: logger.Discard}
+	db := dryRunDB(t).Session(&gorm.Session{Logger: rec})
+	// 试运行不支持Scan取值，仅校验生成的SQL
+	_, _, _ = NewTransportRepo(db).SumLoadByTaskID("T1")
+	if len(rec.sql) != 1 || !strings.Contains(rec.sql[0], "JOIN packages AS p ON p.package_id = ttp.package_id AND p.deleted_at IS NULL") {
+		t.Fatalf("SQL = %q, want 关联包裹时排除已删除包裹", rec.sql)
+	}
+}
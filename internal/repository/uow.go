@@ -0,0 +1,52 @@
+package repository
+
+import "gorm.io/gorm"
+
+// Repositories 共享同一数据库句柄的仓储集合
+type Repositories struct {
+	Package   PackageRepository
+	Transport TransportRepo
+	Delivery  DeliveryRepo
+	Node      NodeRepo
+	Courier   CourierAreaRepo
+	Vehicle   VehicleRepo
+	Driver    DriverRepo
+	ETA       ETARepo
+}
+
+// NewRepositories 基于同一数据库句柄（全局连接或事务）创建仓储集合
+func NewRepositories(db *gorm.DB) *Repositories {
+	return &Repositories{
+		Package:   NewPackageRepository(db),
+		Transport: NewTransportRepo(db),
+		Delivery:  NewDeliveryRepo(db),
+		Node:      NewNodeRepo(db),
+		Courier:   NewCourierAreaRepo(db),
+		Vehicle:   NewVehicleRepo(db),
+		Driver:    NewDriverRepo(db),
+		ETA:       NewETARepo(db),
+	}
+}
+
+// UnitOfWork 工作单元：一次业务操作内跨仓储的写入整体提交或整体回滚
+type UnitOfWork interface {
+	// Transaction 在事务中执行fn，fn内的仓储均绑定同一事务句柄；fn返回error或panic时整体回滚
+	Transaction(fn func(repos *Repositories) error) error
+}
+
+// unitOfWork 基于gorm事务的UnitOfWork实现
+type unitOfWork struct {
+	db *gorm.DB
+}
+
+// NewUnitOfWork 创建工作单元实例
+func NewUnitOfWork(db *gorm.DB) UnitOfWork {
+	return &unitOfWork{db: db}
+}
+
+// Transaction 在事务中执行fn
+func (u *unitOfWork) Transaction(fn func(repos *Repositories) error) error {
+	return u.db.Transaction(func(tx *gorm.DB) error {
+		return fn(NewRepositories(tx))
+	})
+}
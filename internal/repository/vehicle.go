@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+	"gorm.io/gorm"
+)
+
+// VehicleListQuery 车辆查询条件（分页从1开始）
+type VehicleListQuery struct {
+	Type      string
+	Status    string
+	ColdChain *bool
+	Keyword   string // 车牌模糊匹配
+	Page      int
+	PageSize  int
+}
+
+// apply 追加车型/状态/冷链/车牌筛选
+func (q VehicleListQuery) apply(db *gorm.DB) *gorm.DB {
+	if q.Type != "" {
+		db = db.Where("type = ?", q.Type)
+	}
+	if q.Status != "" {
+		db = db.Where("status = ?", q.Status)
+	}
+	if q.ColdChain != nil {
+		db = db.Where("cold_chain = ?", *q.ColdChain)
+	}
+	if q.Keyword != "" {
+		db = db.Where("plate LIKE ?", "%"+escapeLike(q.Keyword)+"%")
+	}
+	return db
+}
+
+// VehicleRepo 车辆数据访问接口
+type VehicleRepo interface {
+	// Create 创建车辆
+	Create(vehicle *model.Vehicle) error
+	// GetByID 根据ID查询车辆
+	GetByID(vehicleID string) (*model.Vehicle, error)
+	// ExistsByPlate 车牌号是否已被其他车辆占用（excludeID为空时不排除）
+	ExistsByPlate(plate, excludeID string) (bool, error)
+	// List 分页查询车辆（按车牌排序），返回当页车辆与总数
+	List(query VehicleListQuery) ([]*model.Vehicle, int64, error)
+	// Update 更新车辆
+	Update(vehicle *model.Vehicle) error
+	// Delete 删除车辆（软删除）
+	Delete(vehicleID string) error
+}
+
+// vehicleRepo 实现VehicleRepo接口
+type vehicleRepo struct {
+	db *gorm.DB
+}
+
+// NewVehicleRepo 创建仓储实例，db可为全局连接或事务句柄
+func NewVehicleRepo(db *gorm.DB) VehicleRepo {
+	return &vehicleRepo{db: db}
+}
+
+// Create 创建车辆
+func (r *vehicleRepo) Create(vehicle *model.Vehicle) error {
+	return r.db.Create(vehicle).Error
+}
+
+// GetByID 根据ID查询车辆
+func (r *vehicleRepo) GetByID(vehicleID string) (*model.Vehicle, error) {
+	var vehicle model.Vehicle
+	if err := r.db.Where("vehicle_id = ?", vehicleID).First(&vehicle).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errno.ErrVehicleNotFound
+		}
+		return nil, err
+	}
+	return &vehicle, nil
+}
+
+// ExistsByPlate 车牌号是否已被其他车辆占用（含已删除车辆，与唯一索引保持一致）
+func (r *vehicleRepo) ExistsByPlate(plate, excludeID string) (bool, error) {
+	db := r.db.Unscoped().Model(&model.Vehicle{}).Where("plate = ?", plate)
+	if excludeID != "" {
+		db = db.Where("vehicle_id <> ?", excludeID)
+	}
+	var count int64
+	if err := db.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// List 分页查询车辆（按车牌排序），返回当页车辆与总数
+func (r *vehicleRepo) List(query VehicleListQuery) ([]*model.Vehicle, int64, error) {
+	db := query.apply(r.db.Model(&model.Vehicle{}))
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var vehicles []*model.Vehicle
+	if err := paginate(db, query.Page, query.PageSize).Order("plate ASC").Find(&vehicles).Error; err != nil {
+		return nil, 0, err
+	}
+	return vehicles, total, nil
+}
+
+// Update 更新车辆（整体保存）
+func (r *vehicleRepo) Update(vehicle *model.Vehicle) error {
+	return r.db.Save(vehicle).Error
+}
+
+// Delete 删除车辆（软删除）
+func (r *vehicleRepo) Delete(vehicleID string) error {
+	return r.db.Where("vehicle_id = ?", vehicleID).Delete(&model.Vehicle{}).Error
+}
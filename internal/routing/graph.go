@@ -0,0 +1,147 @@
+package routing
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/LFrankl/fdu-lab3/config"
+)
+
+//go:embed data/network.json
+var embeddedNetwork []byte
+
+// Node 路网节点（分拣中心/网点）
+type Node struct {
+	Code      string   `json:"code"`
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`                // sorting_center/station
+	Parent    string   `json:"parent,omitempty"`    // 网点所属分拣中心名称
+	Districts []string `json:"districts,omitempty"` // 网点服务区县
+	Address   string   `json:"address"`
+	Longitude float64  `json:"longitude"`
+	Latitude  float64  `json:"latitude"`
+}
+
+// Edge 路网线路（双向），权重为距离、时长与成本
+type Edge struct {
+	From     string  `json:"from"`
+	To       string  `json:"to"`
+	Distance float64 `json:"distance"` // 公里
+	Duration float64 `json:"duration"` // 分钟
+	Cost     float64 `json:"cost"`     // 元
+}
+
+// network 路网文件结构
+type network struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// arc 邻接表中的有向弧
+type arc struct {
+	to   int
+	edge *Edge
+}
+
+// Graph 路网加权图
+type Graph struct {
+	nodes []Node
+	index map[string]int // 节点名称 → 下标
+	adj   [][]arc
+}
+
+var (
+	defaultGraph     *Graph
+	defaultGraphOnce sync.Once
+)
+
+// Init 按配置加载全局路网（服务启动时、创建服务实例前调用，路网文件错误时返回error）
+func Init(cfg config.RoutingConfig) error {
+	graph, err := LoadGraph(cfg.NetworkPath)
+	if err != nil {
+		return err
+	}
+	defaultGraphOnce.Do(func() {
+		defaultGraph = graph
+	})
+	return nil
+}
+
+// Default 获取全局路网；未初始化时按当前配置加载，配置错误时退回内置路网
+func Default() *Graph {
+	defaultGraphOnce.Do(func() {
+		graph, err := LoadGraph(config.Cfg.Routing.NetworkPath)
+		if err != nil {
+			log.Printf("路网加载失败，使用内置路网: %v", err)
+			graph, _ = LoadGraph("")
+		}
+		defaultGraph = graph
+	})
+	return defaultGraph
+}
+
+// LoadGraph 加载路网文件（JSON，含nodes与edges），path为空时使用内置路网
+func LoadGraph(path string) (*Graph, error) {
+	data := embeddedNetwork
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("读取路网文件失败: %w", err)
+		}
+	}
+	var nw network
+	if err := json.Unmarshal(data, &nw); err != nil {
+		return nil, fmt.Errorf("解析路网文件失败: %w", err)
+	}
+	return NewGraph(nw.Nodes, nw.Edges)
+}
+
+// NewGraph 由节点与线路构建路网，线路端点须为已声明的节点且权重非负
+func NewGraph(nodes []Node, edges []Edge) (*Graph, error) {
+	g := &Graph{
+		nodes: nodes,
+		index: make(map[string]int, len(nodes)),
+		adj:   make([][]arc, len(nodes)),
+	}
+	for i, n := range nodes {
+		if _, exists := g.index[n.Name]; exists {
+			return nil, fmt.Errorf("路网节点重复: %s", n.Name)
+		}
+		g.index[n.Name] = i
+	}
+	for i := range edges {
+		e := &edges[i]
+		from, ok := g.index[e.From]
+		if !ok {
+			return nil, fmt.Errorf("线路起点%s未声明", e.From)
+		}
+		to, ok := g.index[e.To]
+		if !ok {
+			return nil, fmt.Errorf("线路终点%s未声明", e.To)
+		}
+		if e.Distance < 0 || e.Duration < 0 || e.Cost < 0 {
+			return nil, fmt.Errorf("线路%s-%s权重不能为负", e.From, e.To)
+		}
+		g.adj[from] = append(g.adj[from], arc{to: to, edge: e})
+		g.adj[to] = append(g.adj[to], arc{to: from, edge: e})
+	}
+	return g, nil
+}
+
+// Node 按名称查询节点
+func (g *Graph) Node(name string) (Node, bool) {
+	i, ok := g.index[name]
+	if !ok {
+		return Node{}, false
+	}
+	return g.nodes[i], true
+}
+
+// Nodes 路网全部节点
+func (g *Graph) Nodes() []Node {
+	return g.nodes
+}
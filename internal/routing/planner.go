@@ -0,0 +1,148 @@
+package routing
+
+import (
+	"container/heap"
+	"encoding/json"
+	"math"
+
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+// 路径规划指标
+const (
+	MetricDistance = "distance" // 最短距离
+	MetricTime     = "time"     // 最短时长
+	MetricCost     = "cost"     // 最低成本
+)
+
+// Route 规划结果：途经节点（含起止）与累计距离/时长/成本
+type Route struct {
+	Nodes    []Node  `json:"nodes"`
+	Distance float64 `json:"distance"` // 公里
+	Duration float64 `json:"duration"` // 分钟
+	Cost     float64 `json:"cost"`     // 元
+}
+
+// routeJSONPoint RouteJSON元素（与TransportRoute.RouteJSON约定一致）
+type routeJSONPoint struct {
+	Name      string  `json:"name"`
+	Address   string  `json:"address"`
+	Longitude float64 `json:"longitude"`
+	Latitude  float64 `json:"latitude"`
+	Type      string  `json:"type"` // start/transit/end
+}
+
+// JSON 转换为运输任务RouteJSON：[{name,address,longitude,latitude,type}]
+func (r *Route) JSON() string {
+	points := make([]routeJSONPoint, len(r.Nodes))
+	for i, n := range r.Nodes {
+		pointType := "transit"
+		if i == 0 {
+			pointType = "start"
+		} else if i == len(r.Nodes)-1 {
+			pointType = "end"
+		}
+		points[i] = routeJSONPoint{
+			Name:      n.Name,
+			Address:   n.Address,
+			Longitude: n.Longitude,
+			Latitude:  n.Latitude,
+			Type:      pointType,
+		}
+	}
+	data, _ := json.Marshal(points)
+	return string(data)
+}
+
+// Plan 按指定指标（为空时按距离）计算起止节点间的最优多跳路线（Dijkstra）
+func (g *Graph) Plan(from, to, metric string) (*Route, error) {
+	weight, err := metricWeight(metric)
+	if err != nil {
+		return nil, err
+	}
+	src, ok := g.index[from]
+	if !ok {
+		return nil, errno.ErrRouteNodeNotFound
+	}
+	dst, ok := g.index[to]
+	if !ok {
+		return nil, errno.ErrRouteNodeNotFound
+	}
+
+	dist := make([]float64, len(g.nodes))
+	prev := make([]arc, len(g.nodes)) // prev[v]：到达v的弧（to为前驱节点）
+	for i := range dist {
+		dist[i] = math.Inf(1)
+		prev[i].to = -1
+	}
+	dist[src] = 0
+	pq := &priorityQueue{{node: src}}
+	for pq.Len() > 0 {
+		cur := heap.Pop(pq).(queueItem)
+		if cur.dist > dist[cur.node] {
+			continue
+		}
+		if cur.node == dst {
+			break
+		}
+		for _, a := range g.adj[cur.node] {
+			if d := cur.dist + weight(a.edge); d < dist[a.to] {
+				dist[a.to] = d
+				prev[a.to] = arc{to: cur.node, edge: a.edge}
+				heap.Push(pq, queueItem{node: a.to, dist: d})
+			}
+		}
+	}
+	if math.IsInf(dist[dst], 1) {
+		return nil, errno.ErrRouteUnreachable
+	}
+
+	// 回溯路径并累计各项指标
+	route := &Route{}
+	path := []int{dst}
+	for v := dst; v != src; v = prev[v].to {
+		e := prev[v].edge
+		route.Distance += e.Distance
+		route.Duration += e.Duration
+		route.Cost += e.Cost
+		path = append(path, prev[v].to)
+	}
+	route.Nodes = make([]Node, len(path))
+	for i, v := range path {
+		route.Nodes[len(path)-1-i] = g.nodes[v]
+	}
+	return route, nil
+}
+
+// metricWeight 指标对应的边权重函数
+func metricWeight(metric string) (func(e *Edge) float64, error) {
+	switch metric {
+	case "", MetricDistance:
+		return func(e *Edge) float64 { return e.Distance }, nil
+	case MetricTime:
+		return func(e *Edge) float64 { return e.Duration }, nil
+	case MetricCost:
+		return func(e *Edge) float64 { return e.Cost }, nil
+	}
+	return nil, errno.ErrRouteMetricInvalid
+}
+
+// queueItem 优先队列元素
+type queueItem struct {
+	node int
+	dist float64
+}
+
+// priorityQueue 按累计权重排序的小顶堆
+type priorityQueue []queueItem
+
+func (q priorityQueue) Len() int            { return len(q) }
+func (q priorityQueue) Less(i, j int) bool  { return q[i].dist < q[j].dist }
+func (q priorityQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *priorityQueue) Push(x interface{}) { *q = append(*q, x.(queueItem)) }
+func (q *priorityQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,144 @@
+package routing
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+// testGraph 测试路网：A→D按距离最短经C、按时长最短直达、按成本最低经B，E为孤立节点
+func testGraph(t *testing.T) *Graph {
+	t.Helper()
+	nodes := []Node{
+		{Name: "A", Type: "sorting_center"},
+		{Name: "B", Type: "sorting_center"},
+		{Name: "C", Type: "sorting_center"},
+		{Name: "D", Type: "station", Parent: "B"},
+		{Name: "E", Type: "station"},
+	}
+	edges := []Edge{
+		{From: "A", To: "B", Distance: 10, Duration: 60, Cost: 1},
+		{From: "B", To: "D", Distance: 10, Duration: 60, Cost: 1},
+		{From: "A", To: "C", Distance: 5, Duration: 200, Cost: 3},
+		{From: "C", To: "D", Distance: 5, Duration: 200, Cost: 3},
+		{From: "A", To: "D", Distance: 30, Duration: 30, Cost: 50},
+	}
+	g, err := NewGraph(nodes, edges)
+	if err != nil {
+		t.Fatalf("NewGraph() error = %v", err)
+	}
+	return g
+}
+
+func routeNames(r *Route) []string {
+	names := make([]string, len(r.Nodes))
+	for i, n := range r.Nodes {
+		names[i] = n.Name
+	}
+	return names
+}
+
+func TestGraphPlan(t *testing.T) {
+	g := testGraph(t)
+	tests := []struct {
+		name     string
+		from, to string
+		metric   string
+		want     []string
+		distance float64
+		duration float64
+		cost     float64
+	}{
+		{"默认按距离", "A", "D", "", []string{"A", "C", "D"}, 10, 400, 6},
+		{"最短距离", "A", "D", MetricDistance, []string{"A", "C", "D"}, 10, 400, 6},
+		{"最短时长", "A", "D", MetricTime, []string{"A", "D"}, 30, 30, 50},
+		{"最低成本", "A", "D", MetricCost, []string{"A", "B", "D"}, 20, 120, 2},
+		{"线路双向可达", "D", "A", MetricCost, []string{"D", "B", "A"}, 20, 120, 2},
+		{"起止相同", "B", "B", MetricDistance, []string{"B"}, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, err := g.Plan(tt.from, tt.to, tt.metric)
+			if err != nil {
+				t.Fatalf("Plan(%q, %q, %q) error = %v", tt.from, tt.to, tt.metric, err)
+			}
+			if got := routeNames(route); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Plan(%q, %q, %q) nodes = %v, want %v", tt.from, tt.to, tt.metric, got, tt.want)
+			}
+			if route.Distance != tt.distance || route.Duration != tt.duration || route.Cost != tt.cost {
+				t.Fatalf("Plan(%q, %q, %q) = %v公里/%v分钟/%v元, want %v/%v/%v", tt.from, tt.to, tt.metric,
+					route.Distance, route.Duration, route.Cost, tt.distance, tt.duration, tt.cost)
+			}
+		})
+	}
+}
+
+func TestGraphPlanErrors(t *testing.T) {
+	g := testGraph(t)
+	tests := []struct {
+		name     string
+		from, to string
+		metric   string
+		wantErr  error
+	}{
+		{"起点未登记", "X", "D", "", errno.ErrRouteNodeNotFound},
+		{"终点未登记", "A", "X", "", errno.ErrRouteNodeNotFound},
+		{"终点不可达", "A", "E", "", errno.ErrRouteUnreachable},
+		{"指标不合法", "A", "D", "speed", errno.ErrRouteMetricInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := g.Plan(tt.from, tt.to, tt.metric); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Plan(%q, %q, %q) error = %v, want %v", tt.from, tt.to, tt.metric, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewGraphInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []Node
+		edges []Edge
+	}{
+		{"节点重复", []Node{{Name: "A"}, {Name: "A"}}, nil},
+		{"线路起点未声明", []Node{{Name: "A"}}, []Edge{{From: "X", To: "A"}}},
+		{"线路终点未声明", []Node{{Name: "A"}}, []Edge{{From: "A", To: "X"}}},
+		{"权重为负", []Node{{Name: "A"}, {Name: "B"}}, []Edge{{From: "A", To: "B", Distance: -1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewGraph(tt.nodes, tt.edges); err == nil {
+				t.Fatalf("NewGraph() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestRouteJSON(t *testing.T) {
+	route, err := testGraph(t).Plan("A", "D", MetricCost)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	var points []routeJSONPoint
+	if err := json.Unmarshal([]byte(route.JSON()), &points); err != nil {
+		t.Fatalf("JSON() 无法解析: %v", err)
+	}
+	want := []routeJSONPoint{{Name: "A", Type: "start"}, {Name: "B", Type: "transit"}, {Name: "D", Type: "end"}}
+	if !reflect.DeepEqual(points, want) {
+		t.Fatalf("JSON() = %+v, want %+v", points, want)
+	}
+}
+
+func TestLoadGraphEmbedded(t *testing.T) {
+	g, err := LoadGraph("")
+	if err != nil {
+		t.Fatalf("LoadGraph(\"\") error = %v", err)
+	}
+	if len(g.Nodes()) == 0 {
+		t.Fatalf("内置路网没有节点")
+	}
+}
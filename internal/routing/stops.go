@@ -0,0 +1,99 @@
+package routing
+
+import "math"
+
+// earthRadiusKm 地球平均半径（公里）
+const earthRadiusKm = 6371.0
+
+// maxTwoOptRounds 2-opt改进的最大轮数，避免大任务耗时过长
+const maxTwoOptRounds = 50
+
+// Stop 派送停靠点（同一地址的包裹合并为一个停靠点）
+type Stop struct {
+	Key       string
+	Longitude float64
+	Latitude  float64
+}
+
+// Distance 两点间球面直线距离（公里，Haversine公式）
+func Distance(lng1, lat1, lng2, lat2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// SequenceStops 计算从起点出发依次访问全部停靠点的顺序（返回stops下标，不回到起点）：
+// 最近邻构造初始路线，再以2-opt反转区间消除交叉，仅依赖已有坐标，无需网络
+func SequenceStops(start Stop, stops []Stop) []int {
+	n := len(stops)
+	if n == 0 {
+		return []int{}
+	}
+	// 距离矩阵：下标0为起点，1..n为停靠点
+	points := append([]Stop{start}, stops...)
+	dist := make([][]float64, n+1)
+	for i := range points {
+		dist[i] = make([]float64, n+1)
+		for j := range points {
+			dist[i][j] = Distance(points[i].Longitude, points[i].Latitude, points[j].Longitude, points[j].Latitude)
+		}
+	}
+
+	// 1. 最近邻：每次前往最近的未访问停靠点
+	path := make([]int, 0, n+1)
+	path = append(path, 0)
+	visited := make([]bool, n+1)
+	for cur := 0; len(path) <= n; {
+		next, best := -1, math.Inf(1)
+		for j := 1; j <= n; j++ {
+			if !visited[j] && dist[cur][j] < best {
+				next, best = j, dist[cur][j]
+			}
+		}
+		visited[next] = true
+		path = append(path, next)
+		cur = next
+	}
+
+	// 2. 2-opt：反转path[i..k]可缩短路线时执行反转（开放路线，终点后无回程边）
+	for round := 0; round < maxTwoOptRounds; round++ {
+		improved := false
+		for i := 1; i < n; i++ {
+			for k := i + 1; k <= n; k++ {
+				delta := dist[path[i-1]][path[k]] - dist[path[i-1]][path[i]]
+				if k < n {
+					delta += dist[path[i]][path[k+1]] - dist[path[k]][path[k+1]]
+				}
+				if delta < -1e-9 {
+					for l, r := i, k; l < r; l, r = l+1, r-1 {
+						path[l], path[r] = path[r], path[l]
+					}
+					improved = true
+				}
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+
+	order := make([]int, n)
+	for i, p := range path[1:] {
+		order[i] = p - 1
+	}
+	return order
+}
+
+// PathLength 按给定顺序从起点依次访问停靠点的总里程（公里）
+func PathLength(start Stop, stops []Stop, order []int) float64 {
+	total := 0.0
+	prev := start
+	for _, idx := range order {
+		total += Distance(prev.Longitude, prev.Latitude, stops[idx].Longitude, stops[idx].Latitude)
+		prev = stops[idx]
+	}
+	return total
+}
@@ -0,0 +1,103 @@
+package routing
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// nearestNeighbor 仅按最近邻构造的访问顺序，作为2-opt改进效果的对照
+func nearestNeighbor(start Stop, stops []Stop) []int {
+	order := make([]int, 0, len(stops))
+	visited := make([]bool, len(stops))
+	cur := start
+	for len(order) < len(stops) {
+		next, best := -1, math.Inf(1)
+		for j, s := range stops {
+			if d := Distance(cur.Longitude, cur.Latitude, s.Longitude, s.Latitude); !visited[j] && d < best {
+				next, best = j, d
+			}
+		}
+		visited[next] = true
+		order = append(order, next)
+		cur = stops[next]
+	}
+	return order
+}
+
+func TestSequenceStops(t *testing.T) {
+	start := Stop{Key: "网点", Longitude: 121.40, Latitude: 31.20}
+	tests := []struct {
+		name  string
+		stops []Stop
+		want  []int
+	}{
+		{"无停靠点", nil, []int{}},
+		{"单个停靠点", []Stop{{Key: "a", Longitude: 121.45, Latitude: 31.25}}, []int{0}},
+		{"同一直线由近及远", []Stop{
+			{Key: "a", Longitude: 121.43, Latitude: 31.20},
+			{Key: "b", Longitude: 121.41, Latitude: 31.20},
+			{Key: "c", Longitude: 121.42, Latitude: 31.20},
+		}, []int{1, 2, 0}},
+		{"2-opt消除最近邻路线的交叉", []Stop{
+			{Key: "a", Longitude: 121.41, Latitude: 31.28},
+			{Key: "b", Longitude: 121.47, Latitude: 31.21},
+			{Key: "c", Longitude: 121.49, Latitude: 31.26},
+			{Key: "d", Longitude: 121.47, Latitude: 31.21},
+			{Key: "e", Longitude: 121.45, Latitude: 31.26},
+		}, []int{1, 3, 2, 4, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SequenceStops(start, tt.stops); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("SequenceStops() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSequenceStopsImprovesNearestNeighbor(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	start := Stop{Longitude: 121.40, Latitude: 31.20}
+	for _, n := range []int{2, 5, 10, 30} {
+		stops := make([]Stop, n)
+		for i := range stops {
+			stops[i] = Stop{Longitude: 121.40 + r.Float64()/10, Latitude: 31.20 + r.Float64()/10}
+		}
+		order := SequenceStops(start, stops)
+		sorted := append([]int(nil), order...)
+		sort.Ints(sorted)
+		for i, idx := range sorted {
+			if idx != i {
+				t.Fatalf("n=%d: SequenceStops() = %v，不是停靠点的排列", n, order)
+			}
+		}
+		if got, nn := PathLength(start, stops, order), PathLength(start, stops, nearestNeighbor(start, stops)); got > nn+1e-9 {
+			t.Fatalf("n=%d: 优化后里程%.3f公里长于最近邻%.3f公里", n, got, nn)
+		}
+	}
+}
+
+func TestPathLength(t *testing.T) {
+	start := Stop{Longitude: 121.40, Latitude: 31.20}
+	stops := []Stop{{Longitude: 121.40, Latitude: 31.30}, {Longitude: 121.40, Latitude: 31.25}}
+	// 纬度相差0.1度约11.12公里
+	tests := []struct {
+		name  string
+		order []int
+		want  float64
+	}{
+		{"无停靠点", nil, 0},
+		{"顺路", []int{1, 0}, 11.12},
+		{"折返", []int{0, 1}, 16.68},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PathLength(start, stops, tt.order); math.Abs(got-tt.want) > 0.01 {
+				t.Fatalf("PathLength(%v) = %.3f, want %.2f", tt.order, got, tt.want)
+			}
+		})
+	}
+}
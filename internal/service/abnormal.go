@@ -0,0 +1,226 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/LFrankl/fdu-lab3/internal/auth"
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/internal/repository"
+	"github.com/LFrankl/fdu-lab3/pkg/db"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+// AbnormalSvc 异常处理台业务服务（异常记录的查询、指派、处理、关闭与重新打开）
+type AbnormalSvc struct {
+	uow         repository.UnitOfWork // 跨仓储写操作的事务边界
+	packageRepo repository.PackageRepository
+	accountRepo repository.AccountRepo // 校验指派的处理人
+}
+
+func NewAbnormalSvc() *AbnormalSvc {
+	return &AbnormalSvc{
+		uow:         repository.NewUnitOfWork(db.DB),
+		packageRepo: repository.NewPackageRepository(db.DB),
+		accountRepo: repository.NewAccountRepo(db.DB),
+	}
+}
+
+// AbnormalRecordListReq 异常记录查询参数
+// Status为空时仅查询未结记录（pending/processing），为all时不限状态
+type AbnormalRecordListReq struct {
+	AbnormalType   string `form:"type"`
+	NodeName       string `form:"node_name"`
+	Status         string `form:"status"`
+	AssigneeID     string `form:"assignee_id"`
+	OlderThanHours int    `form:"older_than_hours"`
+	Page           int    `form:"page"`
+	PageSize       int    `form:"page_size"`
+}
+
+// ListRecords 按类型/节点/状态/积压时长分页查询异常记录
+func (s *AbnormalSvc) ListRecords(req *AbnormalRecordListReq) ([]*model.AbnormalRecord, int64, error) {
+	if req.OlderThanHours < 0 {
+		return nil, 0, errno.ErrParamInvalid
+	}
+	normalizePage(&req.Page, &req.PageSize)
+	query := repository.AbnormalRecordQuery{
+		AbnormalType: req.AbnormalType,
+		NodeName:     req.NodeName,
+		AssigneeID:   req.AssigneeID,
+		Page:         req.Page,
+		PageSize:     req.PageSize,
+	}
+	switch req.Status {
+	case "":
+		query.Statuses = []string{"pending", "processing"}
+	case "all":
+	default:
+		query.Statuses = []string{req.Status}
+	}
+	if req.OlderThanHours > 0 {
+		query.CreatedBefore = time.Now().Add(-time.Duration(req.OlderThanHours) * time.Hour)
+	}
+	return s.packageRepo.ListAbnormalRecords(query)
+}
+
+// GetRecord 查询异常记录详情
+func (s *AbnormalSvc) GetRecord(recordID string) (*model.AbnormalRecord, error) {
+	return s.packageRepo.GetAbnormalRecordByID(recordID)
+}
+
+// AssignRecord 指派（或改派）异常记录处理人
+func (s *AbnormalSvc) AssignRecord(recordID, assigneeID string) (*model.AbnormalRecord, error) {
+	account, err := s.accountRepo.GetByID(assigneeID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || account.Status != "active" {
+		return nil, errno.ErrAbnormalAssigneeInvalid
+	}
+	record, err := s.packageRepo.GetAbnormalRecordByID(recordID)
+	if err != nil {
+		return nil, err
+	}
+	fromStatus := record.Status
+	if err := record.Assign(account.UserID, account.Name); err != nil {
+		return nil, err
+	}
+	if err := s.packageRepo.UpdateAbnormalRecord(record, fromStatus); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// ResolveRecord 按处理方式处理异常：记录处理结果，将仍处于异常状态的包裹恢复到正常流转并记录轨迹（整体事务提交）
+func (s *AbnormalSvc) ResolveRecord(caller *auth.Identity, recordID, method, remark string) (*model.AbnormalRecord, error) {
+	operator := caller.OperatorName()
+	var record *model.AbnormalRecord
+	err := s.uow.Transaction(func(repos *repository.Repositories) error {
+		var err error
+		// 1. 查询异常记录并校验处理人（管理员不受限）
+		record, err = repos.Package.GetAbnormalRecordByID(recordID)
+		if err != nil {
+			return err
+		}
+		if caller.Role != auth.RoleAdmin && record.AssigneeID != caller.UserID {
+			return errno.ErrAbnormalNotAssignee
+		}
+		// 2. 执行领域行为：处理异常记录
+		fromStatus := record.Status
+		if err := record.Resolve(method, remark, operator); err != nil {
+			return err
+		}
+		if err := repos.Package.UpdateAbnormalRecord(record, fromStatus); err != nil {
+			return err
+		}
+		// 3. 包裹回到正常流转（已随任务异常处理恢复的包裹不再变更），并脱离原任务单独调度，
+		// 避免原任务后续状态同步时对该包裹执行非法流转
+		pkg, err := repos.Package.GetByID(record.PackageID)
+		if err != nil {
+			return err
+		}
+		oldStatus := pkg.Status
+		var detachedTaskID string
+		if isAbnormalStatus(pkg.Status) {
+			pkgStatus, err := model.AbnormalResolveStatus(pkg.Status, method)
+			if err != nil {
+				return err
+			}
+			if detachedTaskID, err = detachAbnormalPackage(repos, pkg); err != nil {
+				return err
+			}
+			if err := savePackageStatus(repos, pkg, pkgStatus); err != nil {
+				return err
+			}
+		}
+		// 4. 记录异常处理轨迹
+		nodeName := record.NodeName
+		if nodeName == "" {
+			nodeName = "异常处理台"
+		}
+		traceRemark := fmt.Sprintf("异常已处理（%s）：%s → %s，%s", method, oldStatus, pkg.Status, remark)
+		if detachedTaskID != "" {
+			traceRemark += fmt.Sprintf("，已从任务%s解绑", detachedTaskID)
+		}
+		trace := &model.PackageTrace{
+			PackageID:     record.PackageID,
+			NodeType:      "abnormal_resolved",
+			NodeName:      nodeName,
+			OperationTime: time.Now(),
+			Operator:      operator,
+			Remark:        traceRemark,
+		}
+		return repos.Package.CreateTrace(trace)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// detachAbnormalPackage 异常包裹自所在的未完成任务解绑：运输异常解绑运输任务（重新统计装载），派送异常解绑派送任务，
+// 返回解绑的任务ID（未绑定任务时为空）
+func detachAbnormalPackage(repos *repository.Repositories, pkg *model.Package) (string, error) {
+	switch pkg.Status {
+	case "transport_abnormal":
+		task, err := repos.Transport.FindOpenTaskByPackageID(pkg.PackageID)
+		if err != nil || task == nil {
+			return "", err
+		}
+		if _, err := detachTransportPackages(repos, task, []string{pkg.PackageID}); err != nil {
+			return "", err
+		}
+		return task.TaskID, nil
+	case "delivery_abnormal":
+		taskIDs, err := repos.Delivery.FindOpenTaskIDsByPackageIDs([]string{pkg.PackageID}, "")
+		if err != nil {
+			return "", err
+		}
+		taskID, ok := taskIDs[pkg.PackageID]
+		if !ok {
+			return "", nil
+		}
+		task, err := repos.Delivery.GetTaskByID(taskID)
+		if err != nil {
+			return "", err
+		}
+		if err := task.UnbindPackage([]string{pkg.PackageID}); err != nil {
+			return "", err
+		}
+		return taskID, saveDeliveryUnbind(repos, task, []string{pkg.PackageID})
+	}
+	return "", nil
+}
+
+// CloseRecord 关闭已处理的异常记录
+func (s *AbnormalSvc) CloseRecord(recordID, remark string) (*model.AbnormalRecord, error) {
+	record, err := s.packageRepo.GetAbnormalRecordByID(recordID)
+	if err != nil {
+		return nil, err
+	}
+	fromStatus := record.Status
+	if err := record.Close(remark); err != nil {
+		return nil, err
+	}
+	if err := s.packageRepo.UpdateAbnormalRecord(record, fromStatus); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// ReopenRecord 重新打开已处理/已关闭的异常记录，交回原处理人继续处理
+func (s *AbnormalSvc) ReopenRecord(recordID, reason string) (*model.AbnormalRecord, error) {
+	record, err := s.packageRepo.GetAbnormalRecordByID(recordID)
+	if err != nil {
+		return nil, err
+	}
+	fromStatus := record.Status
+	if err := record.Reopen(reason); err != nil {
+		return nil, err
+	}
+	if err := s.packageRepo.UpdateAbnormalRecord(record, fromStatus); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
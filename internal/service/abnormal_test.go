@@ -0,0 +1,107 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveTransportAbnormalThenArrive(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		wantStatus string
+	}{
+		{"重新贴标回到已分拣", "relabel", "sorted"},
+		{"重新分拣回到已分拣", "resort", "sorted"},
+		{"退回寄件人", "return_to_sender", "returning"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemStore()
+			s.addPackages("transporting", "P1", "P2")
+			s.addTransportTask("T1", "transporting", "P1", "P2")
+			uow := &memUnitOfWork{s: s}
+			transportSvc := &TransportSvc{uow: uow}
+			abnormalSvc := &AbnormalSvc{uow: uow}
+
+			if err := transportSvc.ReportTransportAbnormal(testAdmin, "T1", "vehicle_fault", "车辆故障"); err != nil {
+				t.Fatalf("ReportTransportAbnormal() error = %v", err)
+			}
+			assertPackageStatus(t, s, "transport_abnormal", "P1", "P2")
+			recordID := assignedRecordOf(t, s, "P1")
+			if _, err := abnormalSvc.ResolveRecord(testAdmin, recordID, tt.method, "已处理"); err != nil {
+				t.Fatalf("ResolveRecord() error = %v", err)
+			}
+			// 处理后的包裹脱离原任务，任务包裹数与装载随之重新统计
+			assertPackageStatus(t, s, tt.wantStatus, "P1")
+			if got := s.transportPkgs["T1"]; !reflect.DeepEqual(got, []string{"P2"}) {
+				t.Fatalf("任务T1绑定包裹 = %v, want [P2]", got)
+			}
+			if task := s.transports["T1"]; task.PackageCount != 1 || task.LoadWeight != 1 {
+				t.Fatalf("任务T1包裹数/装载 = %d/%v, want 1/1", task.PackageCount, task.LoadWeight)
+			}
+			// 原任务恢复运输并到站，仅同步仍绑定的包裹
+			if err := transportSvc.HandleTransportAbnormal(testAdmin, "T1", "已更换车辆", "transporting"); err != nil {
+				t.Fatalf("HandleTransportAbnormal() error = %v", err)
+			}
+			if err := transportSvc.ChangeTaskStatus(testAdmin, "T1", "arrived"); err != nil {
+				t.Fatalf("ChangeTaskStatus(arrived) error = %v", err)
+			}
+			assertPackageStatus(t, s, "arrived", "P2")
+			assertPackageStatus(t, s, tt.wantStatus, "P1")
+		})
+	}
+}
+
+func TestResolveDeliveryAbnormalThenDeliver(t *testing.T) {
+	s := newMemStore()
+	s.addPackages("delivering", "P1", "P2")
+	s.addDeliveryTask("D1", "delivering", "P1", "P2")
+	uow := &memUnitOfWork{s: s}
+	deliverySvc := &DeliverySvc{uow: uow}
+	abnormalSvc := &AbnormalSvc{uow: uow}
+
+	if err := deliverySvc.ReportDeliveryAbnormal(testAdmin, "D1", "receiver_absent", "收件人不在"); err != nil {
+		t.Fatalf("ReportDeliveryAbnormal() error = %v", err)
+	}
+	assertPackageStatus(t, s, "delivery_abnormal", "P1", "P2")
+	recordID := assignedRecordOf(t, s, "P1")
+	if _, err := abnormalSvc.ResolveRecord(testAdmin, recordID, "relabel", "更正地址"); err != nil {
+		t.Fatalf("ResolveRecord() error = %v", err)
+	}
+	assertPackageStatus(t, s, "arrived", "P1")
+	if ids, _ := s.repos().Delivery.GetPackageIDsByTaskID("D1"); !reflect.DeepEqual(ids, []string{"P2"}) {
+		t.Fatalf("任务D1绑定包裹 = %v, want [P2]", ids)
+	}
+	if got := s.deliveries["D1"].PackageCount; got != 1 {
+		t.Fatalf("任务D1包裹数 = %d, want 1", got)
+	}
+	// 原任务继续派送，已解绑的包裹留在网点待重新派送
+	if err := deliverySvc.HandleDeliveryAbnormal(testAdmin, "D1", "联系收件人改约", "delivering"); err != nil {
+		t.Fatalf("HandleDeliveryAbnormal() error = %v", err)
+	}
+	assertPackageStatus(t, s, "delivering", "P2")
+	assertPackageStatus(t, s, "arrived", "P1")
+}
+
+func TestResolveRecordRollback(t *testing.T) {
+	s := newMemStore()
+	s.addPackages("transporting", "P1")
+	s.addTransportTask("T1", "transporting", "P1")
+	uow := &memUnitOfWork{s: s}
+	if err := (&TransportSvc{uow: uow}).ReportTransportAbnormal(testAdmin, "T1", "delay", "拥堵"); err != nil {
+		t.Fatalf("ReportTransportAbnormal() error = %v", err)
+	}
+	recordID := assignedRecordOf(t, s, "P1")
+	// 处理方式不合法时整体回滚：记录、包裹与任务绑定均不变
+	if _, err := (&AbnormalSvc{uow: uow}).ResolveRecord(testAdmin, recordID, "discard", ""); err == nil {
+		t.Fatalf("ResolveRecord(discard) error = nil, want error")
+	}
+	if got := s.records[recordID].Status; got != "processing" {
+		t.Fatalf("异常记录状态 = %s, want processing", got)
+	}
+	assertPackageStatus(t, s, "transport_abnormal", "P1")
+	if got := s.transportPkgs["T1"]; !reflect.DeepEqual(got, []string{"P1"}) {
+		t.Fatalf("任务T1绑定包裹 = %v, want [P1]", got)
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/internal/repository"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// memAccountRepo 账号仓储（不存在时返回nil）
+type memAccountRepo struct {
+	repository.AccountRepo
+	accounts map[string]*model.Account
+}
+
+func (r *memAccountRepo) GetByID(userID string) (*model.Account, error) {
+	return r.accounts[userID], nil
+}
+
+func TestLoginFailed(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() error = %v", err)
+	}
+	svc := &AuthSvc{accountRepo: &memAccountRepo{accounts: map[string]*model.Account{
+		"U0001": {UserID: "U0001", PasswordHash: string(hash), Status: "active"},
+		"U0002": {UserID: "U0002", PasswordHash: string(hash), Status: "disabled"},
+	}}}
+	tests := []struct {
+		name     string
+		userID   string
+		password string
+	}{
+		{"账号不存在", "U9999", "secret"},
+		{"账号已停用", "U0002", "secret"},
+		{"密码错误", "U0001", "wrong"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, err := svc.Login(tt.userID, tt.password); !errors.Is(err, errno.ErrLoginFailed) {
+				t.Fatalf("Login() error = %v, want %v", err, errno.ErrLoginFailed)
+			}
+		})
+	}
+}
+
+func TestDummyPasswordHashCost(t *testing.T) {
+	// 固定哈希的代价须与账号密码一致，账号不存在时的比对耗时才与密码错误相当
+	cost, err := bcrypt.Cost([]byte(dummyPasswordHash))
+	if err != nil || cost != bcrypt.DefaultCost {
+		t.Fatalf("bcrypt.Cost(dummyPasswordHash) = %d, %v, want %d", cost, err, bcrypt.DefaultCost)
+	}
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/LFrankl/fdu-lab3/internal/auth"
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/internal/repository"
+	"github.com/LFrankl/fdu-lab3/internal/util"
+	"github.com/LFrankl/fdu-lab3/pkg/db"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+// CourierSvc 派送员服务范围登记与派送员推荐
+type CourierSvc struct {
+	courierRepo  repository.CourierAreaRepo
+	accountRepo  repository.AccountRepo
+	nodeRepo     repository.NodeRepo
+	deliveryRepo repository.DeliveryRepo // 统计派送员当前负载
+	geoUtils     *util.GeoUtils
+}
+
+func NewCourierSvc() *CourierSvc {
+	return &CourierSvc{
+		courierRepo:  repository.NewCourierAreaRepo(db.DB),
+		accountRepo:  repository.NewAccountRepo(db.DB),
+		nodeRepo:     repository.NewNodeRepo(db.DB),
+		deliveryRepo: repository.NewDeliveryRepo(db.DB),
+		geoUtils:     util.NewGeoUtils(),
+	}
+}
+
+// CourierAreaReq 登记/更新派送员服务范围请求参数
+type CourierAreaReq struct {
+	StationID      string           `json:"station_id"` // 所属网点ID，为空不限网点
+	Districts      []string         `json:"districts"`
+	Polygon        []model.GeoPoint `json:"polygon"`
+	MaxActiveTasks int              `json:"max_active_tasks"` // 0不限
+	MaxPackages    int              `json:"max_packages"`     // 0不限
+	Status         string           `json:"status"`           // active/disabled，默认active
+}
+
+// CourierAreaListReq 服务范围查询参数
+type CourierAreaListReq struct {
+	StationID string `form:"station_id"`
+	District  string `form:"district"`
+	Status    string `form:"status"`
+	Page      int    `form:"page"`
+	PageSize  int    `form:"page_size"`
+}
+
+// EligibleCourierReq 派送员推荐查询参数
+type EligibleCourierReq struct {
+	DeliveryArea string `form:"delivery_area" binding:"required"`
+	StationID    string `form:"station_id"`
+}
+
+// EligibleCourier 可承接派送区域的派送员及其当前负载
+type EligibleCourier struct {
+	CourierID      string `json:"courier_id"`
+	CourierName    string `json:"courier_name"`
+	StationID      string `json:"station_id"`
+	ActiveTasks    int    `json:"active_tasks"`
+	OpenPackages   int    `json:"open_packages"`
+	MaxActiveTasks int    `json:"max_active_tasks"`
+	MaxPackages    int    `json:"max_packages"`
+}
+
+// SetCourierArea 登记或整体更新派送员服务范围（仅启用中的派送员账号）
+func (s *CourierSvc) SetCourierArea(courierID string, req *CourierAreaReq) (*model.CourierArea, error) {
+	account, err := s.accountRepo.GetByID(courierID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || account.Role != auth.RoleCourier || account.Status != "active" {
+		return nil, errno.ErrCourierInvalid
+	}
+	if req.StationID != "" {
+		node, err := s.nodeRepo.GetByID(req.StationID)
+		if err != nil {
+			return nil, err
+		}
+		if node.Type != "station" {
+			return nil, errno.ErrNodeTypeInvalid
+		}
+	}
+	area := &model.CourierArea{
+		CourierID:      account.UserID,
+		CourierName:    account.Name,
+		StationID:      req.StationID,
+		Districts:      req.Districts,
+		Polygon:        req.Polygon,
+		MaxActiveTasks: req.MaxActiveTasks,
+		MaxPackages:    req.MaxPackages,
+		Status:         req.Status,
+	}
+	if area.Status == "" {
+		area.Status = "active"
+	}
+	area.Normalize()
+	if err := area.Validate(); err != nil {
+		return nil, err
+	}
+	// 保留首次登记时间
+	if existing, err := s.courierRepo.GetByCourierID(courierID); err == nil {
+		area.CreatedAt = existing.CreatedAt
+	}
+	if err := s.courierRepo.Save(area); err != nil {
+		return nil, err
+	}
+	return area, nil
+}
+
+// GetCourierArea 查询派送员服务范围
+func (s *CourierSvc) GetCourierArea(courierID string) (*model.CourierArea, error) {
+	return s.courierRepo.GetByCourierID(courierID)
+}
+
+// ListCourierAreas 按网点/区县/状态分页查询服务范围
+func (s *CourierSvc) ListCourierAreas(req *CourierAreaListReq) ([]*model.CourierArea, int64, error) {
+	normalizePage(&req.Page, &req.PageSize)
+	return s.courierRepo.List(repository.CourierAreaQuery{
+		StationID: req.StationID,
+		District:  strings.TrimSpace(req.District),
+		Status:    req.Status,
+		Page:      req.Page,
+		PageSize:  req.PageSize,
+	})
+}
+
+// DeleteCourierArea 删除派送员服务范围（删除后该派送员不可再被指派新任务）
+func (s *CourierSvc) DeleteCourierArea(courierID string) error {
+	if _, err := s.courierRepo.GetByCourierID(courierID); err != nil {
+		return err
+	}
+	return s.courierRepo.Delete(courierID)
+}
+
+// EligibleCouriers 推荐可承接派送区域的派送员：服务范围覆盖该区域且运力未满，按当前负载由低到高排序
+func (s *CourierSvc) EligibleCouriers(req *EligibleCourierReq) ([]*EligibleCourier, error) {
+	area := strings.TrimSpace(req.DeliveryArea)
+	lng, lat := areaCoordinates(s.geoUtils, area)
+	candidates, err := s.courierRepo.ListActive(req.StationID)
+	if err != nil {
+		return nil, err
+	}
+	covered := make([]*model.CourierArea, 0, len(candidates))
+	courierIDs := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		if c.Covers(area, lng, lat) {
+			covered = append(covered, c)
+			courierIDs = append(courierIDs, c.CourierID)
+		}
+	}
+	loads, err := s.deliveryRepo.CountOpenLoadByCourierIDs(courierIDs)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*EligibleCourier, 0, len(covered))
+	for _, c := range covered {
+		load := loads[c.CourierID]
+		if !c.HasCapacity(load.Tasks, load.Packages) {
+			continue
+		}
+		result = append(result, &EligibleCourier{
+			CourierID:      c.CourierID,
+			CourierName:    c.CourierName,
+			StationID:      c.StationID,
+			ActiveTasks:    load.Tasks,
+			OpenPackages:   load.Packages,
+			MaxActiveTasks: c.MaxActiveTasks,
+			MaxPackages:    c.MaxPackages,
+		})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].ActiveTasks != result[j].ActiveTasks {
+			return result[i].ActiveTasks < result[j].ActiveTasks
+		}
+		return result[i].OpenPackages < result[j].OpenPackages
+	})
+	return result, nil
+}
+
+// checkCourierArea 校验派送员服务范围：已登记且启用、所属网点与派送网点一致、覆盖派送区域
+// （须在事务中调用，锁定服务范围，使后续运力校验与任务创建对同一派送员串行执行）
+func checkCourierArea(repo repository.CourierAreaRepo, courierID, stationID, area string, lng, lat float64) (*model.CourierArea, error) {
+	courier, err := repo.LockByID(courierID)
+	if err != nil {
+		return nil, err
+	}
+	if courier.Status != "active" {
+		return nil, errno.ErrCourierInvalid
+	}
+	if courier.StationID != "" && courier.StationID != stationID {
+		return nil, errno.ErrCourierAreaMismatch
+	}
+	if !courier.Covers(area, lng, lat) {
+		return nil, errno.ErrCourierAreaMismatch
+	}
+	return courier, nil
+}
+
+// areaCoordinates 解析派送区域坐标（用于多边形范围判断），解析失败时返回0
+func areaCoordinates(geo *util.GeoUtils, area string) (lng, lat float64) {
+	lng, lat, err := geo.GetCoordinates(area)
+	if err != nil {
+		log.Printf("派送区域%s解析失败，仅按区县匹配服务范围: %v", area, err)
+		return 0, 0
+	}
+	return lng, lat
+}
@@ -1,177 +1,356 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/LFrankl/fdu-lab3/internal/auth"
 	"github.com/LFrankl/fdu-lab3/internal/model"
 	"github.com/LFrankl/fdu-lab3/internal/repository"
+	"github.com/LFrankl/fdu-lab3/internal/util"
+	"github.com/LFrankl/fdu-lab3/pkg/db"
 	"github.com/LFrankl/fdu-lab3/pkg/errno"
 )
 
 // DeliverySvc 派送领域核心业务服务
 type DeliverySvc struct {
+	uow          repository.UnitOfWork // 跨仓储写操作的事务边界
 	deliveryRepo repository.DeliveryRepo
 	packageRepo  repository.PackageRepository // 依赖包裹领域Repo
-	//networkRepo  repository.NetworkRepo // 依赖网点领域Repo（校验派送区域）
+	nodeRepo     repository.NodeRepo          // 依赖网络节点Repo（解析派送网点）
+	courierRepo  repository.CourierAreaRepo   // 派送员服务范围（自动生成任务时选取派送员）
+	geoUtils     *util.GeoUtils               // 解析派送区域坐标（匹配派送员多边形服务范围）
 }
 
 func NewDeliverySvc() *DeliverySvc {
 	return &DeliverySvc{
-		deliveryRepo: repository.NewDeliveryRepo(),
-		packageRepo:  repository.NewPackageRepository(),
-		//networkRepo:  networkRepo,
+		uow:          repository.NewUnitOfWork(db.DB),
+		deliveryRepo: repository.NewDeliveryRepo(db.DB),
+		packageRepo:  repository.NewPackageRepository(db.DB),
+		nodeRepo:     repository.NewNodeRepo(db.DB),
+		courierRepo:  repository.NewCourierAreaRepo(db.DB),
+		geoUtils:     util.NewGeoUtils(),
 	}
 }
 
-// CreateDeliveryTask 创建派送任务（含基础校验）
+// CreateDeliveryTask 创建派送任务（含基础校验、派送员服务范围与运力校验）
 func (s *DeliverySvc) CreateDeliveryTask(req *CreateDeliveryTaskReq) (*model.DeliveryTask, error) {
 	// 1. 校验必填参数
-	if req.DeliveryArea == "" || req.CourierID == "" || req.StartNode == "" {
+	if req.DeliveryArea == "" || req.CourierID == "" {
 		return nil, errno.ErrParamInvalid
 	}
-	// 2. 校验派送员与派送区域匹配（联动网点领域）
-	//areaCourier, err := s.networkRepo.CheckCourierArea(req.CourierID, req.DeliveryArea)
-	//if err != nil || !areaCourier {
-	//	return nil, fmt.Errorf("派送员%s不负责%s区域派送", req.CourierID, req.DeliveryArea)
-	//}
-	// 3. 构建派送任务模型
+	// 派送网点按ID或名称解析为登记节点
+	startNode, err := resolveNode(s.nodeRepo, req.StartNodeID, req.StartNode)
+	if err != nil {
+		return nil, err
+	}
+	lng, lat := areaCoordinates(s.geoUtils, req.DeliveryArea)
+	// 2. 构建派送任务模型
 	task := &model.DeliveryTask{
 		TaskID:       genDeliveryTaskID(), // 生成唯一任务ID
 		DeliveryArea: req.DeliveryArea,
 		CourierID:    req.CourierID,
 		CourierName:  req.CourierName,
 		Status:       "pending",
-		StartNode:    req.StartNode,
+		StartNodeID:  startNode.NodeID,
+		StartNode:    startNode.Name,
 	}
-	// 4. 入库
-	if err := s.deliveryRepo.CreateTask(task); err != nil {
+	err = s.uow.Transaction(func(repos *repository.Repositories) error {
+		// 3. 锁定派送员服务范围，校验其负责该区域且未完成任务数未达上限（并发创建时串行统计）
+		courier, err := checkCourierArea(repos.Courier, req.CourierID, startNode.NodeID, req.DeliveryArea, lng, lat)
+		if err != nil {
+			return err
+		}
+		loads, err := repos.Delivery.CountOpenLoadByCourierIDs([]string{req.CourierID})
+		if err != nil {
+			return err
+		}
+		if err := courier.CheckTaskCapacity(loads[req.CourierID].Tasks + 1); err != nil {
+			return err
+		}
+		if task.CourierName == "" {
+			task.CourierName = courier.CourierName
+		}
+		// 4. 入库
+		return repos.Delivery.CreateTask(task)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return task, nil
 }
 
-// ChangeTaskStatus 变更派送任务状态（含包裹状态同步）
-func (s *DeliverySvc) ChangeTaskStatus(taskID, newStatus string) error {
-	// 1. 查询任务
-	task, err := s.deliveryRepo.GetTaskByID(taskID)
-	if err != nil {
-		return err
-	}
-	// 2. 执行领域行为：状态变更
-	if err := task.ChangeStatus(newStatus); err != nil {
-		return err
-	}
-	// 3. 同步包裹状态（核心交互逻辑）
-	if newStatus == "delivering" {
-		// 派送中：同步包裹状态为delivering
-		pkgIDs, err := s.deliveryRepo.GetPackageIDsByTaskID(taskID)
+// ChangeTaskStatus 变更派送任务状态（含包裹状态同步，整体事务提交）
+func (s *DeliverySvc) ChangeTaskStatus(caller *auth.Identity, taskID, newStatus string) error {
+	return s.uow.Transaction(func(repos *repository.Repositories) error {
+		// 1. 查询任务并校验归属
+		task, err := repos.Delivery.GetTaskByID(taskID)
 		if err != nil {
 			return err
 		}
-		for _, pkgID := range pkgIDs {
-			_ = s.packageRepo.UpdateStatus(pkgID, "delivering", "", "")
+		if err := checkCourierAccess(caller, task); err != nil {
+			return err
 		}
-	} else if newStatus == "completed" {
-		// 已完成：同步所有包裹状态为delivered（需先确认全部签收）
-		pkgIDs, err := s.deliveryRepo.GetPackageIDsByTaskID(taskID)
+		// 异常任务须经异常处理恢复，以记录处理结果
+		if task.Status == "abnormal" {
+			return errno.ErrDeliveryTaskIsAbnormal
+		}
+		// 2. 执行领域行为：状态变更
+		if err := task.ChangeStatus(newStatus); err != nil {
+			return err
+		}
+		// 3. 同步包裹状态（核心交互逻辑，经由包裹领域状态机校验）并写入轨迹
+		if err := syncDeliveryPackages(repos, taskID, newStatus); err != nil {
+			return err
+		}
+		if err := writeDeliveryTraces(repos, task, caller.OperatorName(), ""); err != nil {
+			return err
+		}
+		// 4. 更新任务
+		return repos.Delivery.UpdateTask(task)
+	})
+}
+
+// HandleDeliveryAbnormal 处理派送异常：记录处理结果并恢复任务状态，绑定包裹状态随之恢复（整体事务提交）
+func (s *DeliverySvc) HandleDeliveryAbnormal(caller *auth.Identity, taskID, result, newStatus string) error {
+	return s.uow.Transaction(func(repos *repository.Repositories) error {
+		// 1. 查询任务
+		task, err := repos.Delivery.GetTaskByID(taskID)
 		if err != nil {
 			return err
 		}
-		for _, pkgID := range pkgIDs {
-			dtp, err := s.deliveryRepo.GetDeliveryTaskPackage(taskID, pkgID)
+		// 2. 执行领域行为：处理异常（处理人取自调用方身份）
+		if err := task.HandleAbnormal(result, newStatus, caller.OperatorName()); err != nil {
+			return err
+		}
+		// 3. 恢复包裹状态：继续派送时异常包裹恢复为delivering，完成时需全部签收
+		if err := syncDeliveryPackages(repos, taskID, newStatus); err != nil {
+			return err
+		}
+		if err := writeDeliveryTraces(repos, task, caller.OperatorName(), "异常已处理："+result); err != nil {
+			return err
+		}
+		// 4. 更新任务
+		return repos.Delivery.UpdateTask(task)
+	})
+}
+
+// BindPackagesToTask 增量绑定包裹到派送任务：已绑定包裹去重，原有包裹的签收信息与派送顺序不变，
+// 包裹数量以关联表为准，返回本次新绑定的包裹数与绑定后的包裹数量（含包裹状态校验，整体事务提交）
+func (s *DeliverySvc) BindPackagesToTask(taskID string, packageIDs []string) (bound, count int, err error) {
+	err = s.uow.Transaction(func(repos *repository.Repositories) error {
+		// 1. 查询任务及已绑定包裹，过滤出新增包裹
+		task, err := repos.Delivery.GetTaskByID(taskID)
+		if err != nil {
+			return err
+		}
+		boundIDs, err := repos.Delivery.GetPackageIDsByTaskID(taskID)
+		if err != nil {
+			return err
+		}
+		newIDs := excludeIDs(packageIDs, boundIDs)
+		// 2. 校验包裹状态：仅已到站（arrived）且未绑定其他未完成任务的包裹可绑定
+		for _, pkgID := range newIDs {
+			pkg, err := repos.Package.GetByID(pkgID)
 			if err != nil {
 				return err
 			}
-			if dtp.SignInfo.SignTime.IsZero() {
-				return fmt.Errorf("包裹%s未签收，无法完成派送任务", pkgID)
+			if pkg.Status != "arrived" {
+				return fmt.Errorf("包裹%s状态为%s，仅已到站包裹可绑定派送任务：%w", pkgID, pkg.Status, errno.ErrPackageNotBindable)
 			}
-			_ = s.packageRepo.UpdateStatus(pkgID, "delivered", "", "")
 		}
+		others, err := repos.Delivery.FindOpenTaskIDsByPackageIDs(newIDs, taskID)
+		if err != nil {
+			return err
+		}
+		for _, pkgID := range newIDs {
+			if otherID, ok := others[pkgID]; ok {
+				return fmt.Errorf("包裹%s（任务%s）：%w", pkgID, otherID, errno.ErrPackageBoundToDeliveryTask)
+			}
+		}
+		// 3. 执行领域行为：绑定包裹
+		if err := task.BindPackage(newIDs); err != nil {
+			return err
+		}
+		count = len(boundIDs)
+		if len(newIDs) == 0 {
+			return nil
+		}
+		// 4. 校验派送员包裹运力
+		if err := checkCourierPackageCapacity(repos, task, len(newIDs)); err != nil {
+			return err
+		}
+		// 5. 保存关联关系（新包裹派送顺序接在末尾）
+		if err := repos.Delivery.BindPackages(taskID, newIDs); err != nil {
+			return err
+		}
+		// 6. 以关联表为准重新统计包裹数量，乐观锁保存任务
+		if task.PackageCount, err = repos.Delivery.CountPackagesByTaskID(taskID); err != nil {
+			return err
+		}
+		if err := repos.Delivery.UpdateTask(task); err != nil {
+			return err
+		}
+		bound, count = len(newIDs), task.PackageCount
+		// 7. 首次绑定时整体优化派送顺序，追加绑定时仅为新包裹安排顺序
+		if len(boundIDs) == 0 {
+			_, err = sequenceDeliveryTask(repos, task, nil)
+			return err
+		}
+		return appendDeliveryStops(repos, task, newIDs)
+	})
+	if err != nil {
+		return 0, 0, err
 	}
-	// 4. 更新任务
-	return s.deliveryRepo.UpdateTask(task)
+	return bound, count, nil
 }
 
-// BindPackagesToTask 绑定包裹到派送任务（含包裹状态校验）
-func (s *DeliverySvc) BindPackagesToTask(taskID string, packageIDs []string) error {
-	// 1. 查询任务
-	task, err := s.deliveryRepo.GetTaskByID(taskID)
-	if err != nil {
-		return err
+// UnbindPackagesFromTask 从派送任务解绑包裹：已签收包裹不可解绑，解绑包裹恢复为已到站（arrived），
+// 其余包裹的签收信息与派送顺序不变，包裹数量以关联表为准，返回解绑后的包裹数量（整体事务提交）
+func (s *DeliverySvc) UnbindPackagesFromTask(caller *auth.Identity, taskID string, packageIDs []string) (int, error) {
+	packageIDs = excludeIDs(packageIDs, nil)
+	if len(packageIDs) == 0 {
+		return 0, errno.ErrParamInvalid
 	}
-	// 2. 校验包裹状态：仅已到站（arrived）的包裹可绑定
-	for _, pkgID := range packageIDs {
-		pkg, err := s.packageRepo.GetByID(pkgID)
+	var count int
+	err := s.uow.Transaction(func(repos *repository.Repositories) error {
+		// 1. 查询任务并执行领域行为：解绑包裹
+		task, err := repos.Delivery.GetTaskByID(taskID)
 		if err != nil {
 			return err
 		}
-		if pkg.Status != "arrived" {
-			return fmt.Errorf("包裹%s状态为%s，仅已到站包裹可绑定派送任务", pkgID, pkg.Status)
+		if err := task.UnbindPackage(packageIDs); err != nil {
+			return err
 		}
+		// 2. 校验包裹已绑定且未签收，派送中/派送异常的包裹退回已到站
+		for _, pkgID := range packageIDs {
+			dtp, err := repos.Delivery.GetDeliveryTaskPackage(taskID, pkgID)
+			if err != nil {
+				return err
+			}
+			if !dtp.SignInfo.SignTime.IsZero() {
+				return fmt.Errorf("包裹%s：%w", pkgID, errno.ErrPackageAlreadySigned)
+			}
+			pkg, err := repos.Package.GetByID(pkgID)
+			if err != nil {
+				return err
+			}
+			if pkg.Status == "delivering" || pkg.Status == "delivery_abnormal" {
+				if err := savePackageStatus(repos, pkg, "arrived"); err != nil {
+					return err
+				}
+			}
+		}
+		// 3. 删除关联关系，以关联表为准重新统计包裹数量，乐观锁保存任务
+		if err := saveDeliveryUnbind(repos, task, packageIDs); err != nil {
+			return err
+		}
+		count = task.PackageCount
+		// 4. 写入解绑轨迹（节点为派送网点）
+		remark := fmt.Sprintf("包裹已从派送任务%s解绑，退回网点待派送", taskID)
+		return writePackageTraces(repos.Package, packageIDs, "delivery_unbound", deliveryTraceNode(repos, task), caller.OperatorName(), remark)
+	})
+	if err != nil {
+		return 0, err
 	}
-	// 3. 执行领域行为：绑定包裹
-	if err := task.BindPackage(packageIDs); err != nil {
-		return err
-	}
-	// 4. 保存任务（更新包裹数量）
-	if err := s.deliveryRepo.UpdateTask(task); err != nil {
-		return err
-	}
-	// 5. 保存关联关系
-	return s.deliveryRepo.BindPackages(taskID, packageIDs)
+	return count, nil
 }
 
-// ReportDeliveryAbnormal 上报派送异常
-func (s *DeliverySvc) ReportDeliveryAbnormal(taskID, abnormalType, reason, handler string) error {
-	// 1. 查询任务
-	task, err := s.deliveryRepo.GetTaskByID(taskID)
-	if err != nil {
-		return err
-	}
-	// 2. 执行领域行为：上报异常
-	task.ReportAbnormal(abnormalType, reason, handler)
-	// 3. 同步包裹状态为delivery_abnormal
-	pkgIDs, err := s.deliveryRepo.GetPackageIDsByTaskID(taskID)
-	if err != nil {
-		return err
-	}
-	for _, pkgID := range pkgIDs {
-		_ = s.packageRepo.UpdateStatus(pkgID, "delivery_abnormal", reason, handler)
-	}
-	// 4. 更新任务
-	return s.deliveryRepo.UpdateTask(task)
+// ReportDeliveryAbnormal 上报派送异常（整体事务提交，上报人取自调用方身份）
+func (s *DeliverySvc) ReportDeliveryAbnormal(caller *auth.Identity, taskID, abnormalType, reason string) error {
+	handler := caller.OperatorName()
+	return s.uow.Transaction(func(repos *repository.Repositories) error {
+		// 1. 查询任务并校验归属
+		task, err := repos.Delivery.GetTaskByID(taskID)
+		if err != nil {
+			return err
+		}
+		if err := checkCourierAccess(caller, task); err != nil {
+			return err
+		}
+		// 2. 执行领域行为：上报异常
+		if err := task.ReportAbnormal(abnormalType, reason, handler); err != nil {
+			return err
+		}
+		// 3. 同步包裹状态为delivery_abnormal，并逐件记录异常与轨迹
+		pkgIDs, err := repos.Delivery.GetPackageIDsByTaskID(taskID)
+		if err != nil {
+			return err
+		}
+		for _, pkgID := range pkgIDs {
+			pkg, err := repos.Package.GetByID(pkgID)
+			if err != nil {
+				return err
+			}
+			if pkg.Status == "delivered" { // 已签收包裹不受派送异常影响
+				continue
+			}
+			if pkg.Status != "delivery_abnormal" {
+				if err := markPackageAbnormal(repos, pkg, "delivery_abnormal", reason, handler); err != nil {
+					return err
+				}
+			}
+			record := &model.AbnormalRecord{
+				PackageID:      pkgID,
+				AbnormalType:   "delivery",
+				SubType:        abnormalType,
+				AbnormalReason: reason,
+				TaskID:         taskID,
+				NodeName:       task.StartNode,
+				Reporter:       handler,
+			}
+			remark := fmt.Sprintf("派送异常（%s）：%s，派送任务%s", abnormalType, reason, taskID)
+			if err := recordPackageAbnormal(repos.Package, record, remark); err != nil {
+				return err
+			}
+		}
+		// 4. 更新任务
+		return repos.Delivery.UpdateTask(task)
+	})
 }
 
-// SignPackage 包裹签收（核心场景）
-func (s *DeliverySvc) SignPackage(taskID, packageID, courierID, signerName, signerPhone, signType, remark string) error {
-	// 1. 校验任务归属：确保任务属于该派送员
-	task, err := s.deliveryRepo.GetTaskByID(taskID)
-	if err != nil {
-		return err
-	}
-	if task.CourierID != courierID {
-		return errno.ErrDeliveryTaskNotBelongToCourier
-	}
-	// 2. 脱敏处理手机号（仅保留后4位）
-	desensitizedPhone := desensitizePhone(signerPhone)
-	// 3. 执行签收行为
-	if err := s.deliveryRepo.SignPackage(taskID, packageID, signerName, desensitizedPhone, signType, remark); err != nil {
-		return err
-	}
-	// 4. 同步包裹状态为delivered
-	return s.packageRepo.UpdateStatus(packageID, "delivered", "", "")
+// SignPackage 包裹签收（核心场景，签收记录与包裹状态整体事务提交）
+func (s *DeliverySvc) SignPackage(caller *auth.Identity, taskID, packageID, signerName, signerPhone, signType, remark string) error {
+	return s.uow.Transaction(func(repos *repository.Repositories) error {
+		// 1. 校验任务归属：确保任务属于该派送员
+		task, err := repos.Delivery.GetTaskByID(taskID)
+		if err != nil {
+			return err
+		}
+		if err := checkCourierAccess(caller, task); err != nil {
+			return err
+		}
+		// 2. 脱敏处理手机号（仅保留后4位）
+		desensitizedPhone := desensitizePhone(signerPhone)
+		// 3. 执行签收行为
+		if err := repos.Delivery.SignPackage(taskID, packageID, signerName, desensitizedPhone, signType, remark); err != nil {
+			return err
+		}
+		// 4. 同步包裹状态为delivered
+		pkg, err := repos.Package.GetByID(packageID)
+		if err != nil {
+			return err
+		}
+		if err := savePackageStatus(repos, pkg, "delivered"); err != nil {
+			return err
+		}
+		// 5. 写入签收轨迹（节点为收件地址）
+		node := traceNode{Name: task.DeliveryArea, Address: pkg.ReceiverAddress}
+		remark := fmt.Sprintf("包裹已签收，签收人：%s（%s），派送任务%s", signerName, signType, taskID)
+		return writePackageTraces(repos.Package, []string{packageID}, "delivered", node, caller.OperatorName(), remark)
+	})
 }
 
-// GetCourierTaskPackages 派送员查询本人任务的包裹列表
-func (s *DeliverySvc) GetCourierTaskPackages(courierID, taskID string) ([]*model.Package, error) {
+// GetCourierTaskPackages 派送员查询本人任务的包裹列表（调度员可查询任意任务）
+func (s *DeliverySvc) GetCourierTaskPackages(caller *auth.Identity, taskID string) ([]*model.Package, error) {
 	// 1. 校验任务归属
 	task, err := s.deliveryRepo.GetTaskByID(taskID)
 	if err != nil {
 		return nil, err
 	}
-	if task.CourierID != courierID {
-		return nil, errno.ErrDeliveryTaskNotBelongToCourier
+	if err := checkCourierAccess(caller, task); err != nil {
+		return nil, err
 	}
 	// 2. 查询任务绑定的包裹ID
 	pkgIDs, err := s.deliveryRepo.GetPackageIDsByTaskID(taskID)
@@ -190,12 +369,136 @@ func (s *DeliverySvc) GetCourierTaskPackages(courierID, taskID string) ([]*model
 	return packages, nil
 }
 
+// ListCourierTasks 派送员任务收件箱：按状态/日期筛选并分页，包裹数量以关联表实时统计为准
+func (s *DeliverySvc) ListCourierTasks(caller *auth.Identity, courierID string, req *TaskListReq) ([]*model.DeliveryTask, int64, error) {
+	// 派送员仅可查询本人任务
+	if caller.Role == auth.RoleCourier && caller.UserID != courierID {
+		return nil, 0, errno.ErrForbidden
+	}
+	query, err := req.toQuery()
+	if err != nil {
+		return nil, 0, err
+	}
+	tasks, total, err := s.deliveryRepo.ListTasksByCourierID(courierID, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	taskIDs := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		taskIDs = append(taskIDs, task.TaskID)
+	}
+	counts, err := s.deliveryRepo.CountPackagesByTaskIDs(taskIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, task := range tasks {
+		task.PackageCount = counts[task.TaskID]
+	}
+	return tasks, total, nil
+}
+
+// syncDeliveryPackages 按派送任务新状态同步绑定包裹状态
+func syncDeliveryPackages(repos *repository.Repositories, taskID, newStatus string) error {
+	switch newStatus {
+	case "delivering":
+		// 派送中：同步包裹状态为delivering（已签收包裹跳过）
+		pkgIDs, err := repos.Delivery.GetPackageIDsByTaskID(taskID)
+		if err != nil {
+			return err
+		}
+		for _, pkgID := range pkgIDs {
+			pkg, err := repos.Package.GetByID(pkgID)
+			if err != nil {
+				return err
+			}
+			if pkg.Status == "delivered" || pkg.Status == "delivering" {
+				continue
+			}
+			if err := savePackageStatus(repos, pkg, "delivering"); err != nil {
+				return err
+			}
+		}
+	case "completed":
+		// 已完成：同步所有包裹状态为delivered（需先确认全部签收）
+		pkgIDs, err := repos.Delivery.GetPackageIDsByTaskID(taskID)
+		if err != nil {
+			return err
+		}
+		for _, pkgID := range pkgIDs {
+			dtp, err := repos.Delivery.GetDeliveryTaskPackage(taskID, pkgID)
+			if err != nil {
+				return err
+			}
+			if dtp.SignInfo.SignTime.IsZero() {
+				return fmt.Errorf("包裹%s未签收，无法完成派送任务：%w", pkgID, errno.ErrPackageNotSigned)
+			}
+		}
+		return syncPackagesStatus(repos, pkgIDs, "delivered")
+	}
+	return nil
+}
+
+// saveDeliveryUnbind 删除派送任务与包裹的关联，以关联表为准重新统计包裹数量并乐观锁保存任务（调用方已执行领域行为UnbindPackage）
+func saveDeliveryUnbind(repos *repository.Repositories, task *model.DeliveryTask, packageIDs []string) error {
+	if err := repos.Delivery.UnbindPackages(task.TaskID, packageIDs); err != nil {
+		return err
+	}
+	var err error
+	if task.PackageCount, err = repos.Delivery.CountPackagesByTaskID(task.TaskID); err != nil {
+		return err
+	}
+	return repos.Delivery.UpdateTask(task)
+}
+
+// checkCourierAccess 派送员仅可访问本人承接的任务，调度员/管理员不受限
+func checkCourierAccess(caller *auth.Identity, task *model.DeliveryTask) error {
+	if caller.Role == auth.RoleCourier && task.CourierID != caller.UserID {
+		return errno.ErrDeliveryTaskNotBelongToCourier
+	}
+	return nil
+}
+
+// checkCourierPackageCapacity 校验派送员未完成任务的包裹总数在任务新增added个包裹后不超过上限（未登记服务范围的存量任务不限；
+// 锁定服务范围，避免并发绑定同时通过校验）
+func checkCourierPackageCapacity(repos *repository.Repositories, task *model.DeliveryTask, added int) error {
+	courier, err := repos.Courier.LockByID(task.CourierID)
+	if errors.Is(err, errno.ErrCourierAreaNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	loads, err := repos.Delivery.CountOpenLoadByCourierIDs([]string{task.CourierID})
+	if err != nil {
+		return err
+	}
+	return courier.CheckPackageCapacity(loads[task.CourierID].Packages + added)
+}
+
+// excludeIDs 去除空ID、重复ID及exclude中的ID，保持原顺序
+func excludeIDs(ids, exclude []string) []string {
+	seen := make(map[string]struct{}, len(ids)+len(exclude))
+	for _, id := range exclude {
+		seen[id] = struct{}{}
+	}
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok || id == "" {
+			continue
+		}
+		seen[id] = struct{}{}
+		result = append(result, id)
+	}
+	return result
+}
+
 // CreateDeliveryTaskReq ========== 辅助结构体/函数 ==========
 // CreateDeliveryTaskReq 创建派送任务请求参数
 type CreateDeliveryTaskReq struct {
 	DeliveryArea string `json:"delivery_area"`
 	CourierID    string `json:"courier_id"`
-	CourierName  string `json:"courier_name"`
+	CourierName  string `json:"courier_name"`  // 为空时取服务范围登记的派送员姓名
+	StartNodeID  string `json:"start_node_id"` // 派送网点可传ID或名称，ID优先
 	StartNode    string `json:"start_node"`
 }
 
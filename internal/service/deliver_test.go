@@ -0,0 +1,54 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+func TestReportDeliveryAbnormalConflict(t *testing.T) {
+	s := newMemStore()
+	s.addPackages("delivering", "P1", "P2")
+	s.addDeliveryTask("D1", "delivering", "P1", "P2")
+	svc := &DeliverySvc{uow: &racingUnitOfWork{s: s}}
+
+	// 任务在读取后被其他操作修改：异常记录、包裹异常状态与轨迹均不落库
+	err := svc.ReportDeliveryAbnormal(testAdmin, "D1", "receiver_absent", "收件人不在")
+	if !errors.Is(err, errno.ErrDeliveryTaskConflict) {
+		t.Fatalf("ReportDeliveryAbnormal() error = %v, want %v", err, errno.ErrDeliveryTaskConflict)
+	}
+	assertPackageStatus(t, s, "delivering", "P1", "P2")
+	if task := s.deliveries["D1"]; task.Status != "delivering" || task.Version != 0 {
+		t.Fatalf("任务D1 状态/版本 = %s/%d, want delivering/0", task.Status, task.Version)
+	}
+	if len(s.records) != 0 || len(s.traces) != 0 {
+		t.Fatalf("异常记录/轨迹数 = %d/%d, want 0/0", len(s.records), len(s.traces))
+	}
+}
+
+func TestHandleDeliveryAbnormalSyncsPackages(t *testing.T) {
+	s := newMemStore()
+	s.addPackages("delivering", "P1", "P2")
+	s.addDeliveryTask("D1", "delivering", "P1", "P2")
+	svc := &DeliverySvc{uow: &memUnitOfWork{s: s}}
+
+	if err := svc.ReportDeliveryAbnormal(testAdmin, "D1", "receiver_absent", "收件人不在"); err != nil {
+		t.Fatalf("ReportDeliveryAbnormal() error = %v", err)
+	}
+	assertPackageStatus(t, s, "delivery_abnormal", "P1", "P2")
+	// 未签收时不可直接完成，任务保持异常
+	if err := svc.HandleDeliveryAbnormal(testAdmin, "D1", "收件人拒收", "completed"); !errors.Is(err, errno.ErrPackageNotSigned) {
+		t.Fatalf("HandleDeliveryAbnormal(completed) error = %v, want %v", err, errno.ErrPackageNotSigned)
+	}
+	if got := s.deliveries["D1"].Status; got != "abnormal" {
+		t.Fatalf("任务D1状态 = %s, want abnormal", got)
+	}
+	if err := svc.HandleDeliveryAbnormal(testAdmin, "D1", "联系收件人改约", "delivering"); err != nil {
+		t.Fatalf("HandleDeliveryAbnormal(delivering) error = %v", err)
+	}
+	assertPackageStatus(t, s, "delivering", "P1", "P2")
+	if task := s.deliveries["D1"]; task.Status != "delivering" || task.Abnormal.HandleResult != "联系收件人改约" {
+		t.Fatalf("任务D1 状态/处理结果 = %s/%s, want delivering/联系收件人改约", task.Status, task.Abnormal.HandleResult)
+	}
+}
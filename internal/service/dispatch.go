@@ -0,0 +1,259 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/internal/repository"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+// GenerateDeliveryTasksReq 按网点自动生成派送任务请求参数
+type GenerateDeliveryTasksReq struct {
+	StationID          string `json:"station_id"` // 派送网点可传ID或名称，ID优先
+	Station            string `json:"station"`
+	MaxPackagesPerTask int    `json:"max_packages_per_task"` // 单个任务包裹数上限，0不限（仍受派送员运力约束）
+	DryRun             bool   `json:"dry_run"`               // 仅返回方案，不创建任务
+}
+
+// DeliveryPlan 自动派送方案
+type DeliveryPlan struct {
+	StationID   string                 `json:"station_id"`
+	StationName string                 `json:"station_name"`
+	DryRun      bool                   `json:"dry_run"`
+	Tasks       []*PlannedDeliveryTask `json:"tasks"`
+	Unassigned  []*UnassignedPackages  `json:"unassigned"` // 无可用派送员的包裹
+}
+
+// PlannedDeliveryTask 方案中的派送任务（试运行时TaskID为空）
+type PlannedDeliveryTask struct {
+	TaskID       string   `json:"task_id"`
+	DeliveryArea string   `json:"delivery_area"`
+	CourierID    string   `json:"courier_id"`
+	CourierName  string   `json:"courier_name"`
+	PackageIDs   []string `json:"package_ids"`
+}
+
+// UnassignedPackages 未能分配派送员的包裹分组
+type UnassignedPackages struct {
+	DeliveryArea string   `json:"delivery_area"`
+	PackageIDs   []string `json:"package_ids"`
+	Reason       string   `json:"reason"`
+}
+
+// areaGroup 同一派送区域的待派送包裹
+type areaGroup struct {
+	area       string
+	packageIDs []string
+}
+
+// GenerateDeliveryTasks 收集网点已到站且未绑定派送任务的包裹，按收件区县分组、按派送员运力拆分，
+// 生成待派送任务并绑定包裹（整体事务提交）；DryRun时仅返回方案
+func (s *DeliverySvc) GenerateDeliveryTasks(req *GenerateDeliveryTasksReq) (*DeliveryPlan, error) {
+	if req.MaxPackagesPerTask < 0 {
+		return nil, errno.ErrParamInvalid
+	}
+	station, err := resolveNode(s.nodeRepo, req.StationID, req.Station)
+	if err != nil {
+		return nil, err
+	}
+	if station.Type != "station" {
+		return nil, errno.ErrNodeTypeInvalid
+	}
+	plan, err := s.planDeliveryTasks(station, req.MaxPackagesPerTask)
+	if err != nil || req.DryRun || len(plan.Tasks) == 0 {
+		return plan, err
+	}
+	err = s.uow.Transaction(func(repos *repository.Repositories) error {
+		for _, planned := range plan.Tasks {
+			// 方案生成后包裹可能已被其他任务绑定或状态已变化、派送员负载可能已增加，入库前复核
+			if err := recheckPlannedTask(repos, planned); err != nil {
+				return err
+			}
+			task := &model.DeliveryTask{
+				TaskID:       genDeliveryTaskID(),
+				DeliveryArea: planned.DeliveryArea,
+				CourierID:    planned.CourierID,
+				CourierName:  planned.CourierName,
+				Status:       "pending",
+				StartNodeID:  station.NodeID,
+				StartNode:    station.Name,
+			}
+			if err := task.BindPackage(planned.PackageIDs); err != nil {
+				return err
+			}
+			if err := repos.Delivery.CreateTask(task); err != nil {
+				return err
+			}
+			if err := repos.Delivery.BindPackages(task.TaskID, planned.PackageIDs); err != nil {
+				return err
+			}
+			if _, err := sequenceDeliveryTask(repos, task, nil); err != nil {
+				return err
+			}
+			planned.TaskID = task.TaskID
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// recheckPlannedTask 事务内复核方案中的任务：包裹仍为已到站且未绑定未完成的派送任务，
+// 锁定派送员服务范围后统计其未完成任务数与包裹数（含本事务已创建的任务），加上该任务后未超运力
+func recheckPlannedTask(repos *repository.Repositories, planned *PlannedDeliveryTask) error {
+	for _, pkgID := range planned.PackageIDs {
+		pkg, err := repos.Package.GetByID(pkgID)
+		if err != nil {
+			return err
+		}
+		if pkg.Status != "arrived" {
+			return fmt.Errorf("包裹%s状态为%s，仅已到站包裹可绑定派送任务：%w", pkgID, pkg.Status, errno.ErrPackageNotBindable)
+		}
+	}
+	others, err := repos.Delivery.FindOpenTaskIDsByPackageIDs(planned.PackageIDs, "")
+	if err != nil {
+		return err
+	}
+	for _, pkgID := range planned.PackageIDs {
+		if otherID, ok := others[pkgID]; ok {
+			return fmt.Errorf("包裹%s（任务%s）：%w", pkgID, otherID, errno.ErrPackageBoundToDeliveryTask)
+		}
+	}
+	courier, err := repos.Courier.LockByID(planned.CourierID)
+	if err != nil {
+		return err
+	}
+	loads, err := repos.Delivery.CountOpenLoadByCourierIDs([]string{planned.CourierID})
+	if err != nil {
+		return err
+	}
+	load := loads[planned.CourierID]
+	if err := courier.CheckTaskCapacity(load.Tasks + 1); err != nil {
+		return err
+	}
+	return courier.CheckPackageCapacity(load.Packages + len(planned.PackageIDs))
+}
+
+// planDeliveryTasks 生成派送方案：包裹多的区域优先，每次选取覆盖该区域且负载最低的派送员，
+// 按其剩余包裹运力与单任务上限切分；方案内已分配的任务/包裹计入负载
+func (s *DeliverySvc) planDeliveryTasks(station *model.Node, maxPerTask int) (*DeliveryPlan, error) {
+	plan := &DeliveryPlan{
+		StationID:   station.NodeID,
+		StationName: station.Name,
+		Tasks:       []*PlannedDeliveryTask{},
+		Unassigned:  []*UnassignedPackages{},
+	}
+	pkgs, err := s.packageRepo.ListPendingDeliveryAtNode(station.NodeID, station.ServiceDistricts)
+	if err != nil {
+		return nil, err
+	}
+	groups := groupPackagesByArea(pkgs)
+	if len(groups) == 0 {
+		return plan, nil
+	}
+	couriers, err := s.courierRepo.ListActive(station.NodeID)
+	if err != nil {
+		return nil, err
+	}
+	courierIDs := make([]string, 0, len(couriers))
+	for _, c := range couriers {
+		courierIDs = append(courierIDs, c.CourierID)
+	}
+	loads, err := s.deliveryRepo.CountOpenLoadByCourierIDs(courierIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, g := range groups {
+		lng, lat := areaCoordinates(s.geoUtils, g.area)
+		remaining := g.packageIDs
+		for len(remaining) > 0 {
+			courier := pickCourier(couriers, loads, g.area, lng, lat)
+			if courier == nil {
+				reason := "无覆盖该区域的派送员"
+				if hasCoveringCourier(couriers, g.area, lng, lat) {
+					reason = "覆盖该区域的派送员运力已满"
+				}
+				plan.Unassigned = append(plan.Unassigned, &UnassignedPackages{DeliveryArea: g.area, PackageIDs: remaining, Reason: reason})
+				break
+			}
+			load := loads[courier.CourierID]
+			size := len(remaining)
+			if courier.MaxPackages > 0 && courier.MaxPackages-load.Packages < size {
+				size = courier.MaxPackages - load.Packages
+			}
+			if maxPerTask > 0 && maxPerTask < size {
+				size = maxPerTask
+			}
+			plan.Tasks = append(plan.Tasks, &PlannedDeliveryTask{
+				DeliveryArea: g.area,
+				CourierID:    courier.CourierID,
+				CourierName:  courier.CourierName,
+				PackageIDs:   remaining[:size],
+			})
+			load.CourierID = courier.CourierID
+			load.Tasks++
+			load.Packages += size
+			loads[courier.CourierID] = load
+			remaining = remaining[size:]
+		}
+	}
+	return plan, nil
+}
+
+// groupPackagesByArea 按收件城市+区县分组，包裹多的区域在前
+func groupPackagesByArea(pkgs []*model.Package) []*areaGroup {
+	index := make(map[string]*areaGroup)
+	var groups []*areaGroup
+	for _, pkg := range pkgs {
+		area := pkg.ReceiverCity + pkg.ReceiverDistrict
+		if area == "" {
+			area = pkg.ReceiverAddress
+		}
+		g, ok := index[area]
+		if !ok {
+			g = &areaGroup{area: area}
+			index[area] = g
+			groups = append(groups, g)
+		}
+		g.packageIDs = append(g.packageIDs, pkg.PackageID)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].packageIDs) > len(groups[j].packageIDs)
+	})
+	return groups
+}
+
+// pickCourier 选取覆盖区域、仍有运力且当前负载最低的派送员
+func pickCourier(couriers []*model.CourierArea, loads map[string]repository.CourierLoad, area string, lng, lat float64) *model.CourierArea {
+	var best *model.CourierArea
+	for _, c := range couriers {
+		load := loads[c.CourierID]
+		if !c.Covers(area, lng, lat) || !c.HasCapacity(load.Tasks, load.Packages) {
+			continue
+		}
+		if best == nil {
+			best = c
+			continue
+		}
+		bestLoad := loads[best.CourierID]
+		if load.Tasks < bestLoad.Tasks || (load.Tasks == bestLoad.Tasks && load.Packages < bestLoad.Packages) {
+			best = c
+		}
+	}
+	return best
+}
+
+// hasCoveringCourier 是否存在覆盖区域的派送员（不考虑运力）
+func hasCoveringCourier(couriers []*model.CourierArea, area string, lng, lat float64) bool {
+	for _, c := range couriers {
+		if c.Covers(area, lng, lat) {
+			return true
+		}
+	}
+	return false
+}
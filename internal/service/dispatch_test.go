@@ -0,0 +1,54 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/internal/repository"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+func TestRecheckPlannedTask(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(s *memStore)
+		wantErr error
+	}{
+		{"复核通过", func(s *memStore) {}, nil},
+		{"包裹已非到站状态", func(s *memStore) {
+			s.addPackages("delivering", "P2")
+		}, errno.ErrPackageNotBindable},
+		{"包裹已被其他任务绑定", func(s *memStore) {
+			s.addDeliveryTask("D0", "pending", "P1")
+		}, errno.ErrPackageBoundToDeliveryTask},
+		{"派送员任务数已满", func(s *memStore) {
+			s.addDeliveryTask("D0", "delivering")
+			s.addDeliveryTask("D00", "pending")
+		}, errno.ErrCourierCapacityExceeded},
+		{"派送员包裹运力不足", func(s *memStore) {
+			s.addPackages("delivering", "P8", "P9")
+			s.addDeliveryTask("D0", "delivering", "P8", "P9")
+		}, errno.ErrCourierCapacityExceeded},
+		{"已完成任务不计入负载", func(s *memStore) {
+			s.addPackages("delivered", "P8", "P9")
+			s.addDeliveryTask("D0", "completed", "P8", "P9")
+			s.addDeliveryTask("D00", "completed")
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemStore()
+			s.addPackages("arrived", "P1", "P2")
+			s.couriers["C001"] = model.CourierArea{CourierID: "C001", MaxActiveTasks: 2, MaxPackages: 3, Status: "active"}
+			tt.setup(s)
+			planned := &PlannedDeliveryTask{CourierID: "C001", PackageIDs: []string{"P1", "P2"}}
+			err := (&memUnitOfWork{s: s}).Transaction(func(repos *repository.Repositories) error {
+				return recheckPlannedTask(repos, planned)
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("recheckPlannedTask() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
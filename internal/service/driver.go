@@ -0,0 +1,274 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/LFrankl/fdu-lab3/internal/auth"
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/internal/repository"
+	"github.com/LFrankl/fdu-lab3/pkg/db"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+// DriverSvc 司机档案登记与排班查询
+type DriverSvc struct {
+	driverRepo    repository.DriverRepo
+	accountRepo   repository.AccountRepo
+	nodeRepo      repository.NodeRepo
+	vehicleRepo   repository.VehicleRepo
+	transportRepo repository.TransportRepo // 统计司机进行中任务与当日驾驶时长
+}
+
+func NewDriverSvc() *DriverSvc {
+	return &DriverSvc{
+		driverRepo:    repository.NewDriverRepo(db.DB),
+		accountRepo:   repository.NewAccountRepo(db.DB),
+		nodeRepo:      repository.NewNodeRepo(db.DB),
+		vehicleRepo:   repository.NewVehicleRepo(db.DB),
+		transportRepo: repository.NewTransportRepo(db.DB),
+	}
+}
+
+// DriverReq 登记/更新司机档案请求参数
+type DriverReq struct {
+	Phone         string  `json:"phone"`
+	LicenseClass  string  `json:"license_class" binding:"required"` // A1/A2/B1/B2/C1
+	HomeNodeID    string  `json:"home_node_id"`                     // 常驻节点ID，为空不限
+	MaxDailyHours float64 `json:"max_daily_hours"`                  // 默认8小时
+	Status        string  `json:"status"`                           // active/on_leave/disabled，默认active
+}
+
+// DriverListReq 司机档案查询参数
+type DriverListReq struct {
+	HomeNodeID   string `form:"home_node_id"`
+	LicenseClass string `form:"license_class"`
+	Status       string `form:"status"`
+	Keyword      string `form:"keyword"`
+	Page         int    `form:"page"`
+	PageSize     int    `form:"page_size"`
+}
+
+// DriverAvailabilityReq 司机可用性查询参数
+type DriverAvailabilityReq struct {
+	HomeNodeID    string  `form:"home_node_id"`
+	VehicleID     string  `form:"vehicle_id"`     // 指定时校验准驾车型
+	Hours         float64 `form:"hours"`          // 拟指派任务的驾驶时长（小时）
+	OnlyAvailable bool    `form:"only_available"` // 仅返回可指派的司机
+}
+
+// DriverAvailability 司机当前排班与可指派情况（驾驶时长单位为小时，按当日统计）
+type DriverAvailability struct {
+	DriverID       string   `json:"driver_id"`
+	DriverName     string   `json:"driver_name"`
+	LicenseClass   string   `json:"license_class"`
+	HomeNodeID     string   `json:"home_node_id"`
+	ActiveTaskIDs  []string `json:"active_task_ids"`
+	ScheduledHours float64  `json:"scheduled_hours"`
+	MaxDailyHours  float64  `json:"max_daily_hours"`
+	RemainingHours float64  `json:"remaining_hours"`
+	Available      bool     `json:"available"`
+	Reason         string   `json:"reason,omitempty"` // 不可指派原因
+}
+
+// driverSchedule 司机进行中任务与当日已排驾驶时长
+type driverSchedule struct {
+	activeTaskIDs []string
+	hours         float64
+}
+
+// SetDriver 登记或整体更新司机档案（仅启用中的司机账号）
+func (s *DriverSvc) SetDriver(driverID string, req *DriverReq) (*model.Driver, error) {
+	account, err := s.accountRepo.GetByID(driverID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || account.Role != auth.RoleDriver || account.Status != "active" {
+		return nil, errno.ErrDriverInvalid
+	}
+	if req.HomeNodeID != "" {
+		if _, err := resolveNode(s.nodeRepo, req.HomeNodeID, ""); err != nil {
+			return nil, err
+		}
+	}
+	driver := &model.Driver{
+		DriverID:      account.UserID,
+		DriverName:    account.Name,
+		Phone:         strings.TrimSpace(req.Phone),
+		LicenseClass:  req.LicenseClass,
+		HomeNodeID:    req.HomeNodeID,
+		MaxDailyHours: req.MaxDailyHours,
+		Status:        req.Status,
+	}
+	if driver.MaxDailyHours == 0 {
+		driver.MaxDailyHours = 8
+	}
+	if driver.Status == "" {
+		driver.Status = "active"
+	}
+	if err := driver.Validate(); err != nil {
+		return nil, err
+	}
+	// 保留首次登记时间
+	if existing, err := s.driverRepo.GetByID(driverID); err == nil {
+		driver.CreatedAt = existing.CreatedAt
+	}
+	if err := s.driverRepo.Save(driver); err != nil {
+		return nil, err
+	}
+	return driver, nil
+}
+
+// GetDriver 查询司机档案
+func (s *DriverSvc) GetDriver(driverID string) (*model.Driver, error) {
+	return s.driverRepo.GetByID(driverID)
+}
+
+// ListDrivers 按常驻节点/准驾车型/状态/姓名分页查询司机档案
+func (s *DriverSvc) ListDrivers(req *DriverListReq) ([]*model.Driver, int64, error) {
+	normalizePage(&req.Page, &req.PageSize)
+	return s.driverRepo.List(repository.DriverQuery{
+		HomeNodeID:   req.HomeNodeID,
+		LicenseClass: strings.ToUpper(strings.TrimSpace(req.LicenseClass)),
+		Status:       req.Status,
+		Keyword:      strings.TrimSpace(req.Keyword),
+		Page:         req.Page,
+		PageSize:     req.PageSize,
+	})
+}
+
+// DeleteDriver 删除司机档案（仍有进行中运输任务的司机不可删除）
+func (s *DriverSvc) DeleteDriver(driverID string) error {
+	if _, err := s.driverRepo.GetByID(driverID); err != nil {
+		return err
+	}
+	active, err := s.transportRepo.ListActiveTasksByDriverIDs([]string{driverID})
+	if err != nil {
+		return err
+	}
+	if len(active) > 0 {
+		return errno.ErrDriverInUse
+	}
+	return s.driverRepo.Delete(driverID)
+}
+
+// DriverAvailability 查询启用中司机的排班情况：无进行中任务、当日剩余驾驶时长足够且准驾车型相符时可指派；
+// 可指派的司机在前，同组内按剩余时长由多到少排序
+func (s *DriverSvc) DriverAvailability(req *DriverAvailabilityReq) ([]*DriverAvailability, error) {
+	if req.Hours < 0 {
+		return nil, errno.ErrParamInvalid
+	}
+	var vehicle *model.Vehicle
+	if req.VehicleID != "" {
+		v, err := s.vehicleRepo.GetByID(req.VehicleID)
+		if err != nil {
+			return nil, err
+		}
+		vehicle = v
+	}
+	drivers, err := s.driverRepo.ListAll(repository.DriverQuery{HomeNodeID: req.HomeNodeID, Status: "active"})
+	if err != nil {
+		return nil, err
+	}
+	driverIDs := make([]string, 0, len(drivers))
+	for _, d := range drivers {
+		driverIDs = append(driverIDs, d.DriverID)
+	}
+	schedules, err := driverSchedules(s.transportRepo, driverIDs, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*DriverAvailability, 0, len(drivers))
+	for _, d := range drivers {
+		sched := schedules[d.DriverID]
+		item := &DriverAvailability{
+			DriverID:       d.DriverID,
+			DriverName:     d.DriverName,
+			LicenseClass:   d.LicenseClass,
+			HomeNodeID:     d.HomeNodeID,
+			ActiveTaskIDs:  sched.activeTaskIDs,
+			ScheduledHours: roundHours(sched.hours),
+			MaxDailyHours:  d.MaxDailyHours,
+			RemainingHours: roundHours(math.Max(d.MaxDailyHours-sched.hours, 0)),
+			Available:      true,
+		}
+		switch {
+		case len(sched.activeTaskIDs) > 0:
+			item.Available, item.Reason = false, errno.ErrDriverBusy.Error()
+		case vehicle != nil && !d.CanDrive(vehicle.Type):
+			item.Available, item.Reason = false, errno.ErrDriverLicenseMismatch.Error()
+		case d.CheckDailyHours(sched.hours, req.Hours) != nil:
+			item.Available, item.Reason = false, errno.ErrDriverHoursExceeded.Error()
+		}
+		if req.OnlyAvailable && !item.Available {
+			continue
+		}
+		result = append(result, item)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Available != result[j].Available {
+			return result[i].Available
+		}
+		return result[i].RemainingHours > result[j].RemainingHours
+	})
+	return result, nil
+}
+
+// checkDriverSchedule 校验运输任务的司机排班（须在事务中调用，锁定司机档案避免并发重复指派）：
+// 档案启用、准驾车型与车辆相符、无进行中任务、当日驾驶时长加上本任务不超过上限
+func checkDriverSchedule(repos *repository.Repositories, vehicle *model.Vehicle, task *model.TransportTask) (*model.Driver, error) {
+	driver, err := repos.Driver.LockByID(task.DriverID)
+	if err != nil {
+		return nil, err
+	}
+	if driver.Status != "active" {
+		return nil, errno.ErrDriverUnavailable
+	}
+	if !driver.CanDrive(vehicle.Type) {
+		return nil, errno.ErrDriverLicenseMismatch
+	}
+	schedules, err := driverSchedules(repos.Transport, []string{driver.DriverID}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	sched := schedules[driver.DriverID]
+	if len(sched.activeTaskIDs) > 0 {
+		return nil, errno.ErrDriverBusy
+	}
+	if err := driver.CheckDailyHours(sched.hours, task.DrivingHours()); err != nil {
+		return nil, err
+	}
+	return driver, nil
+}
+
+// driverSchedules 统计司机进行中任务与当日（自now所在日零点起创建的任务）已排驾驶时长，未排班的司机返回零值
+func driverSchedules(repo repository.TransportRepo, driverIDs []string, now time.Time) (map[string]driverSchedule, error) {
+	schedules := make(map[string]driverSchedule, len(driverIDs))
+	active, err := repo.ListActiveTasksByDriverIDs(driverIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range active {
+		sched := schedules[t.DriverID]
+		sched.activeTaskIDs = append(sched.activeTaskIDs, t.TaskID)
+		schedules[t.DriverID] = sched
+	}
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	today, err := repo.ListDriverTasksSince(driverIDs, dayStart)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range today {
+		sched := schedules[t.DriverID]
+		sched.hours += t.DrivingHours()
+		schedules[t.DriverID] = sched
+	}
+	return schedules, nil
+}
+
+// roundHours 时长保留两位小数
+func roundHours(h float64) float64 {
+	return math.Round(h*100) / 100
+}
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/internal/repository"
+	"github.com/LFrankl/fdu-lab3/internal/routing"
+)
+
+// 送达时间预测经验参数（时长单位为小时）
+const (
+	etaLineHaulSpeed  = 60.0 // 干线平均车速(km/h)
+	etaRoadFactor     = 1.3  // 直线距离换算道路距离的系数
+	etaSortingDwell   = 3.0  // 分拣中心分拣与中转停留
+	etaStationDwell   = 4.0  // 网点到站至派送出车
+	etaLastMile       = 2.0  // 派送出车至送达
+	etaStopMinutes    = 12.0 // 派送途中每站平均耗时（分钟）
+	etaAbnormalDelay  = 24.0 // 异常处理额外延误
+	etaDefaultHours   = 48.0 // 无位置信息时的默认全程时效
+	etaLaneMinSamples = 3    // 采用线路历史时效的最少样本数
+	etaLaneDays       = 30   // 线路历史时效统计天数
+)
+
+// etaConfidenceLevels 置信度由高到低
+var etaConfidenceLevels = []string{"high", "medium", "low"}
+
+// etaEstimate 一次预测的中间结果
+type etaEstimate struct {
+	at         time.Time
+	basis      string
+	confidence string
+}
+
+// refreshPackageETA 包裹状态变更后重新预测送达时间并保存；已签收时回填实际送达时间，退回中/已退回不再预测
+func refreshPackageETA(repos *repository.Repositories, pkg *model.Package) error {
+	now := time.Now()
+	if pkg.Status == "delivered" {
+		return repos.ETA.FillActualDelivered(pkg.PackageID, now)
+	}
+	eta, err := predictPackageETA(repos, pkg, now)
+	if err != nil || eta == nil {
+		return err
+	}
+	return repos.ETA.Create(eta)
+}
+
+// predictPackageETA 按包裹当前状态预测送达时间窗口：
+// 派送中按派送任务中的站序，已到站按所在节点，运输中/已绑定运输任务按任务预计到达、线路历史时效与路线距离，
+// 其余按当前位置至收件地址的距离估算；异常状态追加延误并降为低置信度
+func predictPackageETA(repos *repository.Repositories, pkg *model.Package, now time.Time) (*model.PackageETA, error) {
+	var (
+		est *etaEstimate
+		err error
+	)
+	switch pkg.Status {
+	case "delivered", "returning", "returned":
+		return nil, nil
+	case "delivering", "delivery_abnormal":
+		est, err = deliveryLegETA(repos, pkg, now)
+	case "arrived":
+		est, err = nodeETA(repos, pkg, pkg.CurrentNodeID, now)
+	default:
+		var task *model.TransportTask
+		if task, err = repos.Transport.FindOpenTaskByPackageID(pkg.PackageID); err == nil && task != nil {
+			est, err = transportLegETA(repos, pkg, task, now)
+		} else if err == nil {
+			est, err = positionETA(repos, pkg, now)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newPackageETA(pkg, est, now), nil
+}
+
+// collectedPackageETA 新揽收包裹的首次预测（与predictPackageETA对仅有揽收轨迹的包裹结果一致），批量导入时免逐条查询
+func collectedPackageETA(pkg *model.Package, collection *model.PackageTrace, now time.Time) *model.PackageETA {
+	return newPackageETA(pkg, locatedETA(pkg, collection.Longitude, collection.Latitude, now), now)
+}
+
+// newPackageETA 由预测结果生成预测记录：异常状态追加延误并降为低置信度，按置信度确定送达窗口
+func newPackageETA(pkg *model.Package, est *etaEstimate, now time.Time) *model.PackageETA {
+	if isAbnormalStatus(pkg.Status) {
+		est.at = est.at.Add(hours(etaAbnormalDelay))
+		est.confidence = "low"
+	}
+	start, end := etaWindow(est, now)
+	return &model.PackageETA{
+		PackageID:     pkg.PackageID,
+		PackageStatus: pkg.Status,
+		Basis:         est.basis,
+		EstimatedTime: est.at,
+		WindowStart:   start,
+		WindowEnd:     end,
+		Confidence:    est.confidence,
+		PredictedAt:   now,
+	}
+}
+
+// deliveryLegETA 派送段：按包裹在派送任务中的顺序，前方每个未签收站点计入平均站点耗时；未出车的任务追加出车准备时间
+func deliveryLegETA(repos *repository.Repositories, pkg *model.Package, now time.Time) (*etaEstimate, error) {
+	taskIDs, err := repos.Delivery.FindOpenTaskIDsByPackageIDs([]string{pkg.PackageID}, "")
+	if err != nil {
+		return nil, err
+	}
+	taskID, ok := taskIDs[pkg.PackageID]
+	if !ok {
+		return nodeETA(repos, pkg, pkg.CurrentNodeID, now)
+	}
+	task, err := repos.Delivery.GetTaskByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	dtps, err := repos.Delivery.ListTaskPackages(taskID)
+	if err != nil {
+		return nil, err
+	}
+	ahead := 0
+	for _, dtp := range dtps {
+		if dtp.PackageID == pkg.PackageID {
+			break
+		}
+		if dtp.SignInfo.SignTime.IsZero() {
+			ahead++
+		}
+	}
+	est := &etaEstimate{
+		at:         now.Add(time.Duration(float64(ahead+1) * etaStopMinutes * float64(time.Minute))),
+		basis:      "delivery_sequence",
+		confidence: "high",
+	}
+	if task.Status == "pending" {
+		est.at = est.at.Add(time.Hour)
+		est.confidence = "medium"
+	}
+	return est, nil
+}
+
+// transportLegETA 运输段：到达时间综合任务预计到达时间与线路历史时效（两者均有时取均值），均缺失时按路线距离估算；
+// 已过预计到达时间仍未到站的顺延1小时并降低置信度，再叠加到达节点至送达的时长
+func transportLegETA(repos *repository.Repositories, pkg *model.Package, task *model.TransportTask, now time.Time) (*etaEstimate, error) {
+	departure := transportDeparture(repos, pkg, task, now)
+	lane := repository.LaneTransit{}
+	if task.StartNodeID != "" && task.EndNodeID != "" {
+		var err error
+		lane, err = repos.ETA.LaneTransit(task.StartNodeID, task.EndNodeID, now.AddDate(0, 0, -etaLaneDays))
+		if err != nil {
+			return nil, err
+		}
+	}
+	hasEstimated := !task.EstimatedTime.IsZero() && task.EstimatedTime.After(departure)
+	hasLane := lane.Samples >= etaLaneMinSamples
+	laneArrival := departure.Add(time.Duration(lane.AvgMinutes * float64(time.Minute)))
+
+	est := &etaEstimate{}
+	switch {
+	case hasEstimated && hasLane:
+		est.at = task.EstimatedTime.Add(laneArrival.Sub(task.EstimatedTime) / 2)
+		est.basis, est.confidence = "blended", "high"
+	case hasEstimated:
+		est.at = task.EstimatedTime
+		est.basis, est.confidence = "transport_task", "medium"
+	case hasLane:
+		est.at = laneArrival
+		est.basis, est.confidence = "lane_history", "medium"
+	case task.Route.Distance > 0:
+		est.at = departure.Add(hours(task.Route.Distance / etaLineHaulSpeed))
+		est.basis, est.confidence = "route_distance", "low"
+	default:
+		est.at = departure.Add(hours(etaDefaultHours / 2))
+		est.basis, est.confidence = "default", "low"
+	}
+	if est.at.Before(now) {
+		est.at = now.Add(time.Hour)
+		est.confidence = lowerConfidence(est.confidence)
+	}
+	remaining, known, err := downstreamHours(repos, pkg, task.EndNodeID)
+	if err != nil {
+		return nil, err
+	}
+	est.at = est.at.Add(hours(remaining))
+	if !known {
+		est.confidence = lowerConfidence(est.confidence)
+	}
+	return est, nil
+}
+
+// transportDeparture 运输任务出发时间：运输中的包裹取本任务出发节点的最近出发轨迹，否则视为即刻出发
+func transportDeparture(repos *repository.Repositories, pkg *model.Package, task *model.TransportTask, now time.Time) time.Time {
+	if pkg.Status != "transporting" && pkg.Status != "transport_abnormal" {
+		return now
+	}
+	traces, err := repos.Package.GetTracesByPackageID(pkg.PackageID)
+	if err != nil {
+		return now
+	}
+	for i := len(traces) - 1; i >= 0; i-- {
+		t := traces[i]
+		if t.NodeType == "transporting" && t.NodeID == task.StartNodeID && !t.OperationTime.Before(task.CreatedAt) {
+			return t.OperationTime
+		}
+	}
+	return now
+}
+
+// nodeETA 已到站：网点按派送准备与末端时长估算，分拣中心按至收件地址的距离估算；节点未知时按当前位置估算
+func nodeETA(repos *repository.Repositories, pkg *model.Package, nodeID string, now time.Time) (*etaEstimate, error) {
+	if nodeID == "" {
+		return positionETA(repos, pkg, now)
+	}
+	remaining, known, err := downstreamHours(repos, pkg, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	est := &etaEstimate{at: now.Add(hours(remaining)), basis: "current_node", confidence: "medium"}
+	if !known {
+		est.basis, est.confidence = "default", "low"
+	}
+	return est, nil
+}
+
+// positionETA 未进入运输任务：按最近一条带坐标的轨迹至收件地址的距离估算（未分拣的追加分拣时长），
+// 位置未知时按揽收时间加默认全程时效
+func positionETA(repos *repository.Repositories, pkg *model.Package, now time.Time) (*etaEstimate, error) {
+	traces, err := repos.Package.GetTracesByPackageID(pkg.PackageID)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(traces) - 1; i >= 0; i-- {
+		if t := traces[i]; t.Longitude != 0 || t.Latitude != 0 {
+			return locatedETA(pkg, t.Longitude, t.Latitude, now), nil
+		}
+	}
+	return locatedETA(pkg, 0, 0, now), nil
+}
+
+// locatedETA 按所在坐标至收件地址的距离估算（未分拣的追加分拣时长），任一坐标未知时按揽收时间加默认全程时效
+func locatedETA(pkg *model.Package, lng, lat float64, now time.Time) *etaEstimate {
+	if remaining, ok := lineHaulHours(lng, lat, pkg); ok {
+		if pkg.Status == "pending" || pkg.Status == "collected" || pkg.Status == "abnormal" {
+			remaining += etaSortingDwell
+		}
+		return &etaEstimate{at: now.Add(hours(remaining)), basis: "route_distance", confidence: "low"}
+	}
+	at := pkg.CreatedAt.Add(hours(etaDefaultHours))
+	if earliest := now.Add(hours(etaLastMile)); pkg.CreatedAt.IsZero() || at.Before(earliest) {
+		at = earliest
+	}
+	return &etaEstimate{at: at, basis: "default", confidence: "low"}
+}
+
+// downstreamHours 自节点至送达的时长：网点为派送准备与末端时长，分拣中心追加干线距离时长；节点或坐标未知时返回默认时长且known为false
+func downstreamHours(repos *repository.Repositories, pkg *model.Package, nodeID string) (remaining float64, known bool, err error) {
+	if nodeID == "" {
+		return etaDefaultHours / 2, false, nil
+	}
+	node, err := repos.Node.GetByID(nodeID)
+	if err != nil {
+		return 0, false, err
+	}
+	if node.Type == "station" {
+		return etaStationDwell + etaLastMile, true, nil
+	}
+	if remaining, ok := lineHaulHours(node.Longitude, node.Latitude, pkg); ok {
+		return remaining, true, nil
+	}
+	return etaDefaultHours / 2, false, nil
+}
+
+// lineHaulHours 自坐标点经分拣中转至送达的时长（干线按直线距离折算道路距离），任一坐标缺失时ok为false
+func lineHaulHours(lng, lat float64, pkg *model.Package) (float64, bool) {
+	if (lng == 0 && lat == 0) || (pkg.ReceiverLongitude == 0 && pkg.ReceiverLatitude == 0) {
+		return 0, false
+	}
+	km := routing.Distance(lng, lat, pkg.ReceiverLongitude, pkg.ReceiverLatitude) * etaRoadFactor
+	return km/etaLineHaulSpeed + etaSortingDwell + etaStationDwell + etaLastMile, true
+}
+
+// etaWindow 按置信度与剩余时长确定送达窗口（高/中/低分别为剩余时长的±10%/20%/35%，至少±0.5/1/2小时），窗口不早于当前时间
+func etaWindow(est *etaEstimate, now time.Time) (start, end time.Time) {
+	ratio, minimum := 0.35, 2.0
+	switch est.confidence {
+	case "high":
+		ratio, minimum = 0.1, 0.5
+	case "medium":
+		ratio, minimum = 0.2, 1.0
+	}
+	spread := hours(math.Max(est.at.Sub(now).Hours()*ratio, minimum))
+	start, end = est.at.Add(-spread), est.at.Add(spread)
+	if start.Before(now) {
+		start = now
+	}
+	return start, end
+}
+
+// lowerConfidence 置信度降低一级（最低为low）
+func lowerConfidence(confidence string) string {
+	for i, level := range etaConfidenceLevels {
+		if level == confidence && i+1 < len(etaConfidenceLevels) {
+			return etaConfidenceLevels[i+1]
+		}
+	}
+	return "low"
+}
+
+// hours 小时数转换为时长
+func hours(h float64) time.Duration {
+	return time.Duration(h * float64(time.Hour))
+}
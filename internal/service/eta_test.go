@@ -0,0 +1,183 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/internal/repository"
+	"github.com/LFrankl/fdu-lab3/internal/routing"
+)
+
+func TestETAWindow(t *testing.T) {
+	now := time.Date(2024, 5, 1, 8, 0, 0, 0, time.Local)
+	tests := []struct {
+		name       string
+		confidence string
+		ahead      float64 // 预测时间距当前的小时数
+		wantStart  float64 // 窗口起止相对当前的小时数
+		wantEnd    float64
+	}{
+		{"高置信度±10%", "high", 10, 9, 11},
+		{"高置信度至少±0.5小时", "high", 2, 1.5, 2.5},
+		{"中置信度±20%", "medium", 10, 8, 12},
+		{"中置信度至少±1小时", "medium", 3, 2, 4},
+		{"低置信度±35%", "low", 20, 13, 27},
+		{"低置信度至少±2小时且不早于当前", "low", 1, 0, 3},
+		{"未知置信度按低置信度", "", 20, 13, 27},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			est := &etaEstimate{at: now.Add(hours(tt.ahead)), confidence: tt.confidence}
+			start, end := etaWindow(est, now)
+			if got := start.Sub(now).Hours(); math.Abs(got-tt.wantStart) > 1e-6 {
+				t.Fatalf("窗口起点 = +%.2f小时, want +%.2f", got, tt.wantStart)
+			}
+			if got := end.Sub(now).Hours(); math.Abs(got-tt.wantEnd) > 1e-6 {
+				t.Fatalf("窗口终点 = +%.2f小时, want +%.2f", got, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestLowerConfidence(t *testing.T) {
+	tests := []struct {
+		confidence string
+		want       string
+	}{
+		{"high", "medium"},
+		{"medium", "low"},
+		{"low", "low"},
+		{"", "low"},
+	}
+	for _, tt := range tests {
+		if got := lowerConfidence(tt.confidence); got != tt.want {
+			t.Errorf("lowerConfidence(%q) = %q, want %q", tt.confidence, got, tt.want)
+		}
+	}
+}
+
+func TestLocatedETA(t *testing.T) {
+	now := time.Date(2024, 5, 1, 8, 0, 0, 0, time.Local)
+	const lng, lat = 121.47, 31.23       // 当前位置
+	const rcvLng, rcvLat = 120.15, 30.28 // 收件地址
+	lineHaul := routing.Distance(lng, lat, rcvLng, rcvLat)*etaRoadFactor/etaLineHaulSpeed +
+		etaSortingDwell + etaStationDwell + etaLastMile
+	tests := []struct {
+		name      string
+		pkg       model.Package
+		lng, lat  float64
+		wantAhead float64
+		wantBasis string
+	}{
+		{"未分拣追加分拣时长", model.Package{Status: "collected", ReceiverLongitude: rcvLng, ReceiverLatitude: rcvLat},
+			lng, lat, lineHaul + etaSortingDwell, "route_distance"},
+		{"已分拣按距离估算", model.Package{Status: "sorted", ReceiverLongitude: rcvLng, ReceiverLatitude: rcvLat},
+			lng, lat, lineHaul, "route_distance"},
+		{"位置未知按揽收时间加默认时效", model.Package{Status: "sorted", CreatedAt: now.Add(-10 * time.Hour),
+			ReceiverLongitude: rcvLng, ReceiverLatitude: rcvLat}, 0, 0, etaDefaultHours - 10, "default"},
+		{"收件坐标未知按揽收时间加默认时效", model.Package{Status: "sorted", CreatedAt: now.Add(-10 * time.Hour)},
+			lng, lat, etaDefaultHours - 10, "default"},
+		{"默认时效已过至少留出末端时长", model.Package{Status: "sorted", CreatedAt: now.Add(-72 * time.Hour)},
+			0, 0, etaLastMile, "default"},
+		{"揽收时间未知至少留出末端时长", model.Package{Status: "sorted"}, 0, 0, etaLastMile, "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			est := locatedETA(&tt.pkg, tt.lng, tt.lat, now)
+			if got := est.at.Sub(now).Hours(); math.Abs(got-tt.wantAhead) > 1e-6 {
+				t.Fatalf("预测时间 = +%.3f小时, want +%.3f", got, tt.wantAhead)
+			}
+			if est.basis != tt.wantBasis || est.confidence != "low" {
+				t.Fatalf("basis/confidence = %s/%s, want %s/low", est.basis, est.confidence, tt.wantBasis)
+			}
+		})
+	}
+}
+
+func TestNewPackageETA(t *testing.T) {
+	now := time.Date(2024, 5, 1, 8, 0, 0, 0, time.Local)
+	tests := []struct {
+		name           string
+		status         string
+		wantAhead      float64
+		wantConfidence string
+	}{
+		{"正常状态保持预测", "transporting", 10, "high"},
+		{"运输异常追加延误并降为低置信度", "transport_abnormal", 10 + etaAbnormalDelay, "low"},
+		{"派送异常追加延误并降为低置信度", "delivery_abnormal", 10 + etaAbnormalDelay, "low"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			est := &etaEstimate{at: now.Add(10 * time.Hour), basis: "transport_task", confidence: "high"}
+			eta := newPackageETA(&model.Package{PackageID: "P1", Status: tt.status}, est, now)
+			if got := eta.EstimatedTime.Sub(now).Hours(); got != tt.wantAhead {
+				t.Fatalf("预测时间 = +%.2f小时, want +%.2f", got, tt.wantAhead)
+			}
+			if eta.Confidence != tt.wantConfidence || eta.PackageStatus != tt.status || !eta.PredictedAt.Equal(now) {
+				t.Fatalf("eta = %+v", eta)
+			}
+			if eta.WindowStart.After(eta.EstimatedTime) || eta.WindowEnd.Before(eta.EstimatedTime) {
+				t.Fatalf("送达窗口[%v, %v]不含预测时间%v", eta.WindowStart, eta.WindowEnd, eta.EstimatedTime)
+			}
+		})
+	}
+}
+
+// fakeETARepo 返回固定准确率统计的预测仓储
+type fakeETARepo struct {
+	repository.ETARepo
+	rows  []repository.ETAAccuracy
+	since time.Time
+}
+
+func (r *fakeETARepo) Accuracy(since time.Time) ([]repository.ETAAccuracy, error) {
+	r.since = since
+	return r.rows, nil
+}
+
+func TestETAAccuracy(t *testing.T) {
+	tests := []struct {
+		name        string
+		days        int
+		rows        []repository.ETAAccuracy
+		wantDays    int
+		wantSamples int64
+		wantHitRate float64
+		wantMAE     float64
+	}{
+		{"无样本", 7, nil, 7, 0, 0, 0},
+		{"按样本数加权汇总", 0, []repository.ETAAccuracy{
+			{Confidence: "high", Samples: 10, Hits: 8, MeanAbsErrorMinutes: 30},
+			{Confidence: "low", Samples: 30, Hits: 15, MeanAbsErrorMinutes: 120},
+		}, 30, 40, 0.575, 1.63},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeETARepo{rows: tt.rows}
+			s := &packageService{etaRepo: repo}
+			report, err := s.ETAAccuracy(tt.days)
+			if err != nil {
+				t.Fatalf("ETAAccuracy() error = %v", err)
+			}
+			if days := time.Since(repo.since).Hours() / 24; math.Abs(days-float64(tt.wantDays)) > 0.1 {
+				t.Fatalf("统计起点为%.1f天前, want %d天前", days, tt.wantDays)
+			}
+			if report.Samples != tt.wantSamples || report.HitRate != tt.wantHitRate || report.MeanAbsErrorHours != tt.wantMAE {
+				t.Fatalf("report = %d/%v/%v, want %d/%v/%v", report.Samples, report.HitRate, report.MeanAbsErrorHours,
+					tt.wantSamples, tt.wantHitRate, tt.wantMAE)
+			}
+			if len(report.ByConfidence) != len(tt.rows) {
+				t.Fatalf("分组数 = %d, want %d", len(report.ByConfidence), len(tt.rows))
+			}
+			for i, g := range report.ByConfidence {
+				row := tt.rows[i]
+				if g.Confidence != row.Confidence || g.HitRate != ratio(float64(row.Hits), float64(row.Samples)) ||
+					g.MeanAbsErrorHours != roundHours(row.MeanAbsErrorMinutes/60) {
+					t.Fatalf("分组%s = %+v", row.Confidence, g)
+				}
+			}
+		})
+	}
+}
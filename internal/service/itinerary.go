@@ -0,0 +1,254 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/internal/repository"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+// ItineraryHop 包裹行程中的一跳（已经过/当前/待经过）
+type ItineraryHop struct {
+	NodeID    string  `json:"node_id,omitempty"`
+	NodeName  string  `json:"node_name"`
+	NodeType  string  `json:"node_type"` // sorting_center/station/collection/receiver
+	Address   string  `json:"address,omitempty"`
+	Longitude float64 `json:"longitude,omitempty"`
+	Latitude  float64 `json:"latitude,omitempty"`
+	Status    string  `json:"status"`               // done/current/upcoming
+	Source    string  `json:"source"`               // trace/transport_route/planned
+	ReachedAt string  `json:"reached_at,omitempty"` // 已经过节点的首次到达时间
+}
+
+// itineraryTraceTypes 计入行程的未登记节点轨迹类型（揽收点、签收地址）
+var itineraryTraceTypes = map[string]bool{"collection": true, "delivered": true}
+
+// packageItinerary 包裹计划行程：已经过节点取自轨迹；运输中或已绑定运输任务时追加任务路线的剩余节点，
+// 其后按路网规划至收件区县所属网点，最后为收件地址。返回行程与下一节点名称（无后续节点时为空）
+func (s *packageService) packageItinerary(pkg *model.Package, traces []model.PackageTrace) ([]*ItineraryHop, string, error) {
+	hops := traceHops(traces)
+	switch pkg.Status {
+	case "delivered", "returned":
+		return hops, "", nil
+	case "returning":
+		markLastCurrent(hops)
+		return hops, "", nil
+	case "delivering", "delivery_abnormal":
+		// 派送途中：网点已离开，收件地址为当前目标
+		hops = append(hops, receiverHop(pkg, "current"))
+		return hops, pkg.ReceiverAddress, nil
+	}
+
+	task, err := s.transportRepo.FindOpenTaskByPackageID(pkg.PackageID)
+	if err != nil {
+		return nil, "", err
+	}
+	inTransit := task != nil && (pkg.Status == "transporting" || pkg.Status == "transport_abnormal")
+	if !inTransit {
+		markLastCurrent(hops)
+	}
+	from := ""
+	if len(hops) > 0 {
+		from = hops[len(hops)-1].NodeName
+	}
+	if task != nil {
+		for _, hop := range s.transportRouteHops(task, from) {
+			hops = appendHop(hops, hop)
+		}
+		from = task.EndNode
+	}
+	for _, hop := range s.plannedHops(pkg, from) {
+		hops = appendHop(hops, hop)
+	}
+	hops = append(hops, receiverHop(pkg, "upcoming"))
+
+	// 运输途中：前往的下一节点标记为当前
+	if inTransit {
+		for _, hop := range hops {
+			if hop.Status == "upcoming" {
+				hop.Status = "current"
+				break
+			}
+		}
+	}
+	nextNode := ""
+	for _, hop := range hops {
+		if hop.Status == "upcoming" || (inTransit && hop.Status == "current") {
+			nextNode = hop.NodeName
+			break
+		}
+	}
+	return hops, nextNode, nil
+}
+
+// traceHops 由轨迹生成已经过节点：登记节点及揽收点/签收地址，连续相同节点合并并保留首次到达时间
+func traceHops(traces []model.PackageTrace) []*ItineraryHop {
+	hops := make([]*ItineraryHop, 0, len(traces))
+	for _, t := range traces {
+		if t.NodeID == "" && !itineraryTraceTypes[t.NodeType] {
+			continue
+		}
+		if n := len(hops); n > 0 && sameHop(hops[n-1], t.NodeID, t.NodeName) {
+			continue
+		}
+		hopType := t.NodeType
+		if hopType == "delivered" {
+			hopType = "receiver"
+		}
+		hops = append(hops, &ItineraryHop{
+			NodeID:    t.NodeID,
+			NodeName:  t.NodeName,
+			NodeType:  hopType,
+			Address:   t.NodeAddress,
+			Longitude: t.Longitude,
+			Latitude:  t.Latitude,
+			Status:    "done",
+			Source:    "trace",
+			ReachedAt: t.OperationTime.Format("2006-01-02 15:04:05"),
+		})
+	}
+	return hops
+}
+
+// transportRouteHops 运输任务路线中位于from之后的节点（路线缺失时仅为任务终点），from不在路线中时取起点之后的节点
+func (s *packageService) transportRouteHops(task *model.TransportTask, from string) []*ItineraryHop {
+	var points []routePoint
+	if task.Route.RouteJSON == "" || json.Unmarshal([]byte(task.Route.RouteJSON), &points) != nil || len(points) < 2 {
+		return []*ItineraryHop{s.nodeHop(task.EndNodeID, task.EndNode, "transport_route")}
+	}
+	start := 0
+	for i, p := range points {
+		if p.Name != "" && p.Name == from {
+			start = i
+		}
+	}
+	hops := make([]*ItineraryHop, 0, len(points)-start-1)
+	for i, p := range points[start+1:] {
+		name := p.Name
+		if start+1+i == len(points)-1 && task.EndNode != "" {
+			name = task.EndNode // 终点以任务登记名称为准
+		}
+		if name == "" {
+			name = p.Address
+		}
+		hop := s.nodeHop("", name, "transport_route")
+		if hop.Address == "" {
+			hop.Address, hop.Longitude, hop.Latitude = p.Address, p.Longitude, p.Latitude
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// plannedHops 自from按路网规划至收件区县所属网点的后续节点（不含from）；无法确定网点或不可达时为空
+func (s *packageService) plannedHops(pkg *model.Package, from string) []*ItineraryHop {
+	dest := s.destinationNode(pkg)
+	if dest == "" || from == "" || dest == from {
+		return nil
+	}
+	route, err := s.graph.Plan(from, dest, "")
+	if err != nil {
+		// 当前节点不在路网中（如揽收点）或不可达：仅给出目的网点
+		return []*ItineraryHop{s.nodeHop("", dest, "planned")}
+	}
+	hops := make([]*ItineraryHop, 0, len(route.Nodes))
+	for _, n := range route.Nodes[1:] {
+		hops = append(hops, s.nodeHop("", n.Name, "planned"))
+	}
+	return hops
+}
+
+// destinationNode 收件区县所属网点名称（优先地址/名称含收件城市的启用网点），无登记网点时取收件城市的分拣中心
+func (s *packageService) destinationNode(pkg *model.Package) string {
+	city := strings.TrimSuffix(strings.TrimSpace(pkg.ReceiverCity), "市")
+	inCity := func(name, address string) bool {
+		return city != "" && (strings.Contains(name, city) || strings.Contains(address, city))
+	}
+	if pkg.ReceiverDistrict != "" {
+		stations, _, err := s.nodeRepo.List(repository.NodeListQuery{Type: "station", District: pkg.ReceiverDistrict, Page: 1, PageSize: 20})
+		if err == nil {
+			dest := ""
+			for _, n := range stations {
+				if n.Status != "active" {
+					continue
+				}
+				if inCity(n.Name, n.Address) {
+					return n.Name
+				}
+				if dest == "" {
+					dest = n.Name
+				}
+			}
+			if dest != "" {
+				return dest
+			}
+		}
+	}
+	for _, n := range s.graph.Nodes() {
+		if n.Type == "sorting_center" && inCity(n.Name, n.Address) {
+			return n.Name
+		}
+	}
+	return ""
+}
+
+// nodeHop 待经过节点：优先取登记节点信息，其次取路网节点信息
+func (s *packageService) nodeHop(nodeID, name, source string) *ItineraryHop {
+	hop := &ItineraryHop{NodeID: nodeID, NodeName: name, Status: "upcoming", Source: source}
+	var (
+		node *model.Node
+		err  = errno.ErrNodeNotFound
+	)
+	if nodeID != "" {
+		node, err = s.nodeRepo.GetByID(nodeID)
+	} else if name != "" {
+		node, err = s.nodeRepo.GetByName(name)
+	}
+	if err == nil {
+		hop.NodeID, hop.NodeName, hop.NodeType = node.NodeID, node.Name, node.Type
+		hop.Address, hop.Longitude, hop.Latitude = node.Address, node.Longitude, node.Latitude
+		return hop
+	}
+	if n, ok := s.graph.Node(name); ok {
+		hop.NodeType, hop.Address, hop.Longitude, hop.Latitude = n.Type, n.Address, n.Longitude, n.Latitude
+	}
+	return hop
+}
+
+// receiverHop 收件地址节点
+func receiverHop(pkg *model.Package, status string) *ItineraryHop {
+	return &ItineraryHop{
+		NodeName:  pkg.ReceiverAddress,
+		NodeType:  "receiver",
+		Address:   pkg.ReceiverProvince + pkg.ReceiverCity + pkg.ReceiverDistrict + pkg.ReceiverAddress,
+		Longitude: pkg.ReceiverLongitude,
+		Latitude:  pkg.ReceiverLatitude,
+		Status:    status,
+		Source:    "planned",
+	}
+}
+
+// appendHop 追加待经过节点，与上一节点相同时跳过
+func appendHop(hops []*ItineraryHop, hop *ItineraryHop) []*ItineraryHop {
+	if n := len(hops); n > 0 && sameHop(hops[n-1], hop.NodeID, hop.NodeName) {
+		return hops
+	}
+	return append(hops, hop)
+}
+
+// sameHop 节点ID相同（均有ID时）或名称相同即视为同一节点
+func sameHop(hop *ItineraryHop, nodeID, name string) bool {
+	if hop.NodeID != "" && nodeID != "" {
+		return hop.NodeID == nodeID
+	}
+	return hop.NodeName == name
+}
+
+// markLastCurrent 最后一个已经过节点即包裹当前所在节点
+func markLastCurrent(hops []*ItineraryHop) {
+	if n := len(hops); n > 0 {
+		hops[n-1].Status = "current"
+	}
+}
@@ -0,0 +1,669 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/LFrankl/fdu-lab3/internal/auth"
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/internal/repository"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+// memStore 服务层测试用的内存数据集：仓储读写副本（与数据库一样，修改须经仓储保存才生效）
+type memStore struct {
+	packages      map[string]model.Package
+	traces        []model.PackageTrace
+	records       map[string]model.AbnormalRecord
+	transports    map[string]model.TransportTask
+	transportPkgs map[string][]string // 运输任务ID → 绑定包裹（按绑定顺序）
+	deliveries    map[string]model.DeliveryTask
+	deliveryPkgs  map[string][]model.DeliveryTaskPackage
+	couriers      map[string]model.CourierArea
+	nodes         map[string]model.Node
+	vehicles      map[string]model.Vehicle
+	drivers       map[string]model.Driver
+	etas          []model.PackageETA
+	seq           int
+	duplicates    *int // 接下来模拟运单号重复的批量写入次数（不随事务回滚）
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		packages:      map[string]model.Package{},
+		records:       map[string]model.AbnormalRecord{},
+		transports:    map[string]model.TransportTask{},
+		transportPkgs: map[string][]string{},
+		deliveries:    map[string]model.DeliveryTask{},
+		deliveryPkgs:  map[string][]model.DeliveryTaskPackage{},
+		couriers:      map[string]model.CourierArea{},
+		nodes:         map[string]model.Node{},
+		vehicles:      map[string]model.Vehicle{},
+		drivers:       map[string]model.Driver{},
+		duplicates:    new(int),
+	}
+}
+
+// clone 深拷贝数据集（事务内的修改作用于副本）
+func (s *memStore) clone() *memStore {
+	c := &memStore{
+		packages:      make(map[string]model.Package, len(s.packages)),
+		traces:        append([]model.PackageTrace(nil), s.traces...),
+		records:       make(map[string]model.AbnormalRecord, len(s.records)),
+		transports:    make(map[string]model.TransportTask, len(s.transports)),
+		transportPkgs: make(map[string][]string, len(s.transportPkgs)),
+		deliveries:    make(map[string]model.DeliveryTask, len(s.deliveries)),
+		deliveryPkgs:  make(map[string][]model.DeliveryTaskPackage, len(s.deliveryPkgs)),
+		couriers:      make(map[string]model.CourierArea, len(s.couriers)),
+		nodes:         make(map[string]model.Node, len(s.nodes)),
+		vehicles:      make(map[string]model.Vehicle, len(s.vehicles)),
+		drivers:       make(map[string]model.Driver, len(s.drivers)),
+		etas:          append([]model.PackageETA(nil), s.etas...),
+		seq:           s.seq,
+		duplicates:    s.duplicates,
+	}
+	for k, v := range s.packages {
+		c.packages[k] = v
+	}
+	for k, v := range s.records {
+		c.records[k] = v
+	}
+	for k, v := range s.transports {
+		c.transports[k] = v
+	}
+	for k, v := range s.transportPkgs {
+		c.transportPkgs[k] = append([]string(nil), v...)
+	}
+	for k, v := range s.deliveries {
+		c.deliveries[k] = v
+	}
+	for k, v := range s.deliveryPkgs {
+		c.deliveryPkgs[k] = append([]model.DeliveryTaskPackage(nil), v...)
+	}
+	for k, v := range s.couriers {
+		c.couriers[k] = v
+	}
+	for k, v := range s.nodes {
+		c.nodes[k] = v
+	}
+	for k, v := range s.vehicles {
+		c.vehicles[k] = v
+	}
+	for k, v := range s.drivers {
+		c.drivers[k] = v
+	}
+	return c
+}
+
+// nextID 生成测试内唯一ID
+func (s *memStore) nextID(prefix string) string {
+	s.seq++
+	return fmt.Sprintf("%s%04d", prefix, s.seq)
+}
+
+// repos 基于数据集创建仓储集合
+func (s *memStore) repos() *repository.Repositories {
+	return &repository.Repositories{
+		Package:   &memPackageRepo{s: s},
+		Transport: &memTransportRepo{s: s},
+		Delivery:  &memDeliveryRepo{s: s},
+		Courier:   &memCourierRepo{s: s},
+		Node:      &memNodeRepo{s: s},
+		Vehicle:   &memVehicleRepo{s: s},
+		Driver:    &memDriverRepo{s: s},
+		ETA:       &memETARepo{s: s},
+	}
+}
+
+// memUnitOfWork 内存工作单元：fn作用于数据集副本，成功时整体替换，返回error或panic时丢弃副本
+type memUnitOfWork struct {
+	s *memStore
+}
+
+func (u *memUnitOfWork) Transaction(fn func(repos *repository.Repositories) error) error {
+	tx := u.s.clone()
+	if err := fn(tx.repos()); err != nil {
+		return err
+	}
+	*u.s = *tx
+	return nil
+}
+
+// racingUnitOfWork 事务内读取任务后模拟其他事务先行提交（推进库中任务版本号），用于验证乐观锁冲突时整体回滚
+type racingUnitOfWork struct {
+	s *memStore
+}
+
+func (u *racingUnitOfWork) Transaction(fn func(repos *repository.Repositories) error) error {
+	tx := u.s.clone()
+	repos := tx.repos()
+	repos.Transport = &racingTransportRepo{memTransportRepo{s: tx}}
+	repos.Delivery = &racingDeliveryRepo{memDeliveryRepo{s: tx}}
+	if err := fn(repos); err != nil {
+		return err
+	}
+	*u.s = *tx
+	return nil
+}
+
+type racingTransportRepo struct {
+	memTransportRepo
+}
+
+func (r *racingTransportRepo) GetTaskByID(taskID string) (*model.TransportTask, error) {
+	task, err := r.memTransportRepo.GetTaskByID(taskID)
+	if err == nil {
+		stored := r.s.transports[taskID]
+		stored.Version++
+		r.s.transports[taskID] = stored
+	}
+	return task, err
+}
+
+type racingDeliveryRepo struct {
+	memDeliveryRepo
+}
+
+func (r *racingDeliveryRepo) GetTaskByID(taskID string) (*model.DeliveryTask, error) {
+	task, err := r.memDeliveryRepo.GetTaskByID(taskID)
+	if err == nil {
+		stored := r.s.deliveries[taskID]
+		stored.Version++
+		r.s.deliveries[taskID] = stored
+	}
+	return task, err
+}
+
+// memPackageRepo 包裹仓储（未实现的方法调用时panic）
+type memPackageRepo struct {
+	repository.PackageRepository
+	s *memStore
+}
+
+func (r *memPackageRepo) GetByID(packageID string) (*model.Package, error) {
+	pkg, ok := r.s.packages[packageID]
+	if !ok {
+		return nil, errno.ErrPackageNotFound
+	}
+	return &pkg, nil
+}
+
+func (r *memPackageRepo) CreateBatch(pkgs []*model.Package) error {
+	if *r.s.duplicates > 0 {
+		*r.s.duplicates--
+		return errno.ErrPackageDuplicated
+	}
+	for _, pkg := range pkgs {
+		if _, ok := r.s.packages[pkg.PackageID]; ok {
+			return errno.ErrPackageDuplicated
+		}
+		r.s.packages[pkg.PackageID] = *pkg
+	}
+	return nil
+}
+
+func (r *memPackageRepo) UpdateStatus(packageID, status, reason, handler string) error {
+	pkg := r.s.packages[packageID]
+	pkg.Status = status
+	if reason != "" {
+		pkg.AbnormalReason = reason
+	}
+	if handler != "" {
+		pkg.AbnormalHandler = handler
+	}
+	r.s.packages[packageID] = pkg
+	return nil
+}
+
+func (r *memPackageRepo) UpdateCurrentNode(packageIDs []string, nodeID string) error {
+	for _, id := range packageIDs {
+		pkg := r.s.packages[id]
+		pkg.CurrentNodeID = nodeID
+		r.s.packages[id] = pkg
+	}
+	return nil
+}
+
+func (r *memPackageRepo) CreateTrace(trace *model.PackageTrace) error {
+	if trace.TraceID == "" {
+		trace.TraceID = r.s.nextID("TR")
+	}
+	r.s.traces = append(r.s.traces, *trace)
+	return nil
+}
+
+func (r *memPackageRepo) CreateTraces(traces []*model.PackageTrace) error {
+	for _, trace := range traces {
+		if err := r.CreateTrace(trace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memPackageRepo) GetTracesByPackageID(packageID string) ([]model.PackageTrace, error) {
+	var traces []model.PackageTrace
+	for _, t := range r.s.traces {
+		if t.PackageID == packageID {
+			traces = append(traces, t)
+		}
+	}
+	return traces, nil
+}
+
+func (r *memPackageRepo) CreateAbnormalRecord(record *model.AbnormalRecord) error {
+	if record.RecordID == "" {
+		record.RecordID = r.s.nextID("AR")
+	}
+	r.s.records[record.RecordID] = *record
+	return nil
+}
+
+func (r *memPackageRepo) GetAbnormalRecordByID(recordID string) (*model.AbnormalRecord, error) {
+	record, ok := r.s.records[recordID]
+	if !ok {
+		return nil, errno.ErrAbnormalRecordNotFound
+	}
+	return &record, nil
+}
+
+func (r *memPackageRepo) UpdateAbnormalRecord(record *model.AbnormalRecord, fromStatus string) error {
+	if r.s.records[record.RecordID].Status != fromStatus {
+		return errno.ErrAbnormalRecordConflict
+	}
+	r.s.records[record.RecordID] = *record
+	return nil
+}
+
+// memTransportRepo 运输仓储
+type memTransportRepo struct {
+	repository.TransportRepo
+	s *memStore
+}
+
+func (r *memTransportRepo) CreateTask(task *model.TransportTask) error {
+	r.s.transports[task.TaskID] = *task
+	return nil
+}
+
+func (r *memTransportRepo) CountOpenTasksByNodeID(nodeID string) (int64, error) {
+	var count int64
+	for _, task := range r.s.transports {
+		if (task.StartNodeID == nodeID || task.EndNodeID == nodeID) && task.Status != "completed" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memTransportRepo) GetTaskByID(taskID string) (*model.TransportTask, error) {
+	task, ok := r.s.transports[taskID]
+	if !ok {
+		return nil, errno.ErrTransportTaskNotFound
+	}
+	return &task, nil
+}
+
+func (r *memTransportRepo) UpdateTask(task *model.TransportTask) error {
+	if r.s.transports[task.TaskID].Version != task.Version {
+		return errno.ErrTransportTaskConflict
+	}
+	task.Version++
+	r.s.transports[task.TaskID] = *task
+	return nil
+}
+
+func (r *memTransportRepo) BindPackages(taskID string, packageIDs []string) error {
+	r.s.transportPkgs[taskID] = append(r.s.transportPkgs[taskID], excludeIDs(packageIDs, r.s.transportPkgs[taskID])...)
+	return nil
+}
+
+func (r *memTransportRepo) UnbindPackages(taskID string, packageIDs []string) error {
+	r.s.transportPkgs[taskID] = excludeIDs(r.s.transportPkgs[taskID], packageIDs)
+	return nil
+}
+
+func (r *memTransportRepo) GetPackageIDsByTaskID(taskID string) ([]string, error) {
+	return append([]string(nil), r.s.transportPkgs[taskID]...), nil
+}
+
+func (r *memTransportRepo) CountPackagesByTaskID(taskID string) (int, error) {
+	return len(r.s.transportPkgs[taskID]), nil
+}
+
+func (r *memTransportRepo) SumLoadByTaskID(taskID string) (weight, volume float64, err error) {
+	for _, id := range r.s.transportPkgs[taskID] {
+		pkg, ok := r.s.packages[id]
+		if !ok || pkg.DeletedAt.Valid {
+			continue
+		}
+		weight += pkg.Weight
+		volume += model.PackageVolume(&pkg)
+	}
+	return weight, volume, nil
+}
+
+func (r *memTransportRepo) FindOpenTaskByPackageID(packageID string) (*model.TransportTask, error) {
+	for taskID, pkgIDs := range r.s.transportPkgs {
+		task := r.s.transports[taskID]
+		if task.Status != "pending" && task.Status != "transporting" && task.Status != "abnormal" {
+			continue
+		}
+		for _, id := range pkgIDs {
+			if id == packageID {
+				return &task, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// memDeliveryRepo 派送仓储
+type memDeliveryRepo struct {
+	repository.DeliveryRepo
+	s *memStore
+}
+
+func (r *memDeliveryRepo) CreateTask(task *model.DeliveryTask) error {
+	r.s.deliveries[task.TaskID] = *task
+	return nil
+}
+
+func (r *memDeliveryRepo) CountOpenTasksByNodeID(nodeID string) (int64, error) {
+	var count int64
+	for _, task := range r.s.deliveries {
+		if task.StartNodeID == nodeID && task.Status != "completed" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memDeliveryRepo) GetTaskByID(taskID string) (*model.DeliveryTask, error) {
+	task, ok := r.s.deliveries[taskID]
+	if !ok {
+		return nil, errno.ErrDeliveryTaskNotFound
+	}
+	return &task, nil
+}
+
+func (r *memDeliveryRepo) UpdateTask(task *model.DeliveryTask) error {
+	if r.s.deliveries[task.TaskID].Version != task.Version {
+		return errno.ErrDeliveryTaskConflict
+	}
+	task.Version++
+	r.s.deliveries[task.TaskID] = *task
+	return nil
+}
+
+func (r *memDeliveryRepo) BindPackages(taskID string, packageIDs []string) error {
+	dtps := r.s.deliveryPkgs[taskID]
+	for _, id := range packageIDs {
+		dtps = append(dtps, model.DeliveryTaskPackage{DeliveryTaskID: taskID, PackageID: id, DeliveryOrder: len(dtps) + 1})
+	}
+	r.s.deliveryPkgs[taskID] = dtps
+	return nil
+}
+
+func (r *memDeliveryRepo) UnbindPackages(taskID string, packageIDs []string) error {
+	unbind := make(map[string]bool, len(packageIDs))
+	for _, id := range packageIDs {
+		unbind[id] = true
+	}
+	var kept []model.DeliveryTaskPackage
+	for _, dtp := range r.s.deliveryPkgs[taskID] {
+		if !unbind[dtp.PackageID] {
+			kept = append(kept, dtp)
+		}
+	}
+	r.s.deliveryPkgs[taskID] = kept
+	return nil
+}
+
+func (r *memDeliveryRepo) FindOpenTaskIDsByPackageIDs(packageIDs []string, excludeTaskID string) (map[string]string, error) {
+	found := map[string]string{}
+	for taskID, dtps := range r.s.deliveryPkgs {
+		task := r.s.deliveries[taskID]
+		if taskID == excludeTaskID || (task.Status != "pending" && task.Status != "delivering" && task.Status != "abnormal") {
+			continue
+		}
+		for _, dtp := range dtps {
+			for _, id := range packageIDs {
+				if dtp.PackageID == id {
+					found[id] = taskID
+				}
+			}
+		}
+	}
+	return found, nil
+}
+
+func (r *memDeliveryRepo) GetPackageIDsByTaskID(taskID string) ([]string, error) {
+	var ids []string
+	for _, dtp := range r.s.deliveryPkgs[taskID] {
+		ids = append(ids, dtp.PackageID)
+	}
+	return ids, nil
+}
+
+func (r *memDeliveryRepo) CountPackagesByTaskID(taskID string) (int, error) {
+	return len(r.s.deliveryPkgs[taskID]), nil
+}
+
+func (r *memDeliveryRepo) ListTaskPackages(taskID string) ([]*model.DeliveryTaskPackage, error) {
+	dtps := make([]*model.DeliveryTaskPackage, 0, len(r.s.deliveryPkgs[taskID]))
+	for _, dtp := range r.s.deliveryPkgs[taskID] {
+		dtp := dtp
+		dtps = append(dtps, &dtp)
+	}
+	sort.SliceStable(dtps, func(i, j int) bool { return dtps[i].DeliveryOrder < dtps[j].DeliveryOrder })
+	return dtps, nil
+}
+
+func (r *memDeliveryRepo) GetDeliveryTaskPackage(deliveryTaskID, packageID string) (*model.DeliveryTaskPackage, error) {
+	for _, dtp := range r.s.deliveryPkgs[deliveryTaskID] {
+		if dtp.PackageID == packageID {
+			return &dtp, nil
+		}
+	}
+	return nil, errno.ErrPackageNotBindToDeliveryTask
+}
+
+func (r *memDeliveryRepo) CountOpenLoadByCourierIDs(courierIDs []string) (map[string]repository.CourierLoad, error) {
+	loads := make(map[string]repository.CourierLoad, len(courierIDs))
+	for taskID, task := range r.s.deliveries {
+		if task.Status != "pending" && task.Status != "delivering" && task.Status != "abnormal" {
+			continue
+		}
+		for _, id := range courierIDs {
+			if task.CourierID == id {
+				load := loads[id]
+				load.CourierID = id
+				load.Tasks++
+				load.Packages += len(r.s.deliveryPkgs[taskID])
+				loads[id] = load
+			}
+		}
+	}
+	return loads, nil
+}
+
+// memCourierRepo 派送员服务范围仓储
+type memCourierRepo struct {
+	repository.CourierAreaRepo
+	s *memStore
+}
+
+func (r *memCourierRepo) GetByCourierID(courierID string) (*model.CourierArea, error) {
+	area, ok := r.s.couriers[courierID]
+	if !ok {
+		return nil, errno.ErrCourierAreaNotFound
+	}
+	return &area, nil
+}
+
+func (r *memCourierRepo) LockByID(courierID string) (*model.CourierArea, error) {
+	return r.GetByCourierID(courierID)
+}
+
+func (r *memCourierRepo) CountByStationID(stationID string) (int64, error) {
+	var count int64
+	for _, area := range r.s.couriers {
+		if area.StationID == stationID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// memNodeRepo 网络节点仓储
+type memNodeRepo struct {
+	repository.NodeRepo
+	s *memStore
+}
+
+func (r *memNodeRepo) GetByName(name string) (*model.Node, error) {
+	for _, node := range r.s.nodes {
+		if node.Name == name {
+			return &node, nil
+		}
+	}
+	return nil, errno.ErrNodeNotFound
+}
+
+func (r *memNodeRepo) GetByID(nodeID string) (*model.Node, error) {
+	node, ok := r.s.nodes[nodeID]
+	if !ok {
+		return nil, errno.ErrNodeNotFound
+	}
+	return &node, nil
+}
+
+func (r *memNodeRepo) CountChildren(nodeID string) (int64, error) {
+	var count int64
+	for _, node := range r.s.nodes {
+		if node.ParentID == nodeID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memNodeRepo) Delete(nodeID string) error {
+	delete(r.s.nodes, nodeID)
+	return nil
+}
+
+// memDriverRepo 司机档案仓储
+type memDriverRepo struct {
+	repository.DriverRepo
+	s *memStore
+}
+
+func (r *memDriverRepo) CountByHomeNodeID(nodeID string) (int64, error) {
+	var count int64
+	for _, driver := range r.s.drivers {
+		if driver.HomeNodeID == nodeID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// memVehicleRepo 车辆仓储
+type memVehicleRepo struct {
+	repository.VehicleRepo
+	s *memStore
+}
+
+func (r *memVehicleRepo) GetByID(vehicleID string) (*model.Vehicle, error) {
+	vehicle, ok := r.s.vehicles[vehicleID]
+	if !ok {
+		return nil, errno.ErrVehicleNotFound
+	}
+	return &vehicle, nil
+}
+
+// memETARepo 送达时间预测仓储（无线路历史）
+type memETARepo struct {
+	repository.ETARepo
+	s *memStore
+}
+
+func (r *memETARepo) Create(eta *model.PackageETA) error {
+	r.s.etas = append(r.s.etas, *eta)
+	return nil
+}
+
+func (r *memETARepo) CreateBatch(etas []*model.PackageETA) error {
+	for _, eta := range etas {
+		r.s.etas = append(r.s.etas, *eta)
+	}
+	return nil
+}
+
+func (r *memETARepo) FillActualDelivered(packageID string, deliveredAt time.Time) error {
+	return nil
+}
+
+func (r *memETARepo) LaneTransit(fromNodeID, toNodeID string, since time.Time) (repository.LaneTransit, error) {
+	return repository.LaneTransit{}, nil
+}
+
+// testAdmin 测试用管理员身份
+var testAdmin = &auth.Identity{UserID: "U0001", Name: "管理员", Role: auth.RoleAdmin}
+
+// addPackages 登记一批指定状态的包裹
+func (s *memStore) addPackages(status string, packageIDs ...string) {
+	for _, id := range packageIDs {
+		s.packages[id] = model.Package{PackageID: id, Status: status, Weight: 1, CreatedAt: time.Now()}
+	}
+}
+
+// addTransportTask 登记运输任务并绑定包裹
+func (s *memStore) addTransportTask(taskID, status string, packageIDs ...string) {
+	s.transports[taskID] = model.TransportTask{TaskID: taskID, Status: status, VehicleID: "V001",
+		StartNode: "上海分拣中心", EndNode: "杭州分拣中心", PackageCount: len(packageIDs), LoadWeight: float64(len(packageIDs))}
+	s.transportPkgs[taskID] = append([]string(nil), packageIDs...)
+}
+
+// addDeliveryTask 登记派送任务并绑定包裹
+func (s *memStore) addDeliveryTask(taskID, status string, packageIDs ...string) {
+	s.deliveries[taskID] = model.DeliveryTask{TaskID: taskID, Status: status, CourierID: "C001", CourierName: "派送员",
+		StartNode: "杭州西湖网点", PackageCount: len(packageIDs)}
+	for i, id := range packageIDs {
+		s.deliveryPkgs[taskID] = append(s.deliveryPkgs[taskID], model.DeliveryTaskPackage{DeliveryTaskID: taskID, PackageID: id, DeliveryOrder: i + 1})
+	}
+}
+
+// assertPackageStatus 校验包裹状态
+func assertPackageStatus(t *testing.T, s *memStore, want string, packageIDs ...string) {
+	t.Helper()
+	for _, id := range packageIDs {
+		if got := s.packages[id].Status; got != want {
+			t.Fatalf("包裹%s状态 = %s, want %s", id, got, want)
+		}
+	}
+}
+
+// assignedRecordOf 将包裹唯一的异常记录指派给测试管理员，返回记录ID
+func assignedRecordOf(t *testing.T, s *memStore, packageID string) string {
+	t.Helper()
+	var found []model.AbnormalRecord
+	for _, record := range s.records {
+		if record.PackageID == packageID {
+			found = append(found, record)
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("包裹%s异常记录数 = %d, want 1", packageID, len(found))
+	}
+	record := found[0]
+	if err := record.Assign(testAdmin.UserID, testAdmin.Name); err != nil {
+		t.Fatalf("Assign() error = %v", err)
+	}
+	s.records[record.RecordID] = record
+	return record.RecordID
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/internal/repository"
+	"github.com/LFrankl/fdu-lab3/internal/routing"
+	"github.com/LFrankl/fdu-lab3/internal/util"
+	"github.com/LFrankl/fdu-lab3/pkg/db"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+// NodeSvc 网络节点（分拣中心/网点）业务服务
+type NodeSvc struct {
+	nodeRepo      repository.NodeRepo
+	transportRepo repository.TransportRepo   // 删除前校验引用节点的运输任务
+	deliveryRepo  repository.DeliveryRepo    // 删除前校验引用节点的派送任务
+	courierRepo   repository.CourierAreaRepo // 删除前校验归属网点的派送员
+	driverRepo    repository.DriverRepo      // 删除前校验常驻节点的司机
+	geoUtils      *util.GeoUtils
+	idGen         *util.IDGenerator
+}
+
+func NewNodeSvc() *NodeSvc {
+	return &NodeSvc{
+		nodeRepo:      repository.NewNodeRepo(db.DB),
+		transportRepo: repository.NewTransportRepo(db.DB),
+		deliveryRepo:  repository.NewDeliveryRepo(db.DB),
+		courierRepo:   repository.NewCourierAreaRepo(db.DB),
+		driverRepo:    repository.NewDriverRepo(db.DB),
+		geoUtils:      util.NewGeoUtils(),
+		idGen:         util.NewIDGenerator(),
+	}
+}
+
+// NodeReq 创建/更新网络节点请求参数
+type NodeReq struct {
+	Code             string   `json:"code" binding:"required"`
+	Name             string   `json:"name" binding:"required"`
+	Type             string   `json:"type" binding:"required"` // sorting_center/station
+	Address          string   `json:"address"`
+	Longitude        float64  `json:"longitude"`
+	Latitude         float64  `json:"latitude"`
+	ParentID         string   `json:"parent_id"` // 网点所属分拣中心ID
+	OpeningHours     string   `json:"opening_hours"`
+	ServiceDistricts []string `json:"service_districts"`
+	Status           string   `json:"status"` // active/disabled，创建时默认active
+}
+
+// NodeListReq 网络节点查询参数
+type NodeListReq struct {
+	Type     string `form:"type"`
+	ParentID string `form:"parent_id"`
+	District string `form:"district"`
+	Keyword  string `form:"keyword"`
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+}
+
+// CreateNode 创建网络节点（未提供坐标时按地址解析）
+func (s *NodeSvc) CreateNode(req *NodeReq) (*model.Node, error) {
+	node := &model.Node{NodeID: s.idGen.GenerateNodeID(), Status: "active"}
+	if err := s.applyReq(node, req); err != nil {
+		return nil, err
+	}
+	if err := s.nodeRepo.Create(node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// UpdateNode 更新网络节点（整体覆盖可编辑字段）
+func (s *NodeSvc) UpdateNode(nodeID string, req *NodeReq) (*model.Node, error) {
+	node, err := s.nodeRepo.GetByID(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyReq(node, req); err != nil {
+		return nil, err
+	}
+	if err := s.nodeRepo.Update(node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// GetNode 查询网络节点详情
+func (s *NodeSvc) GetNode(nodeID string) (*model.Node, error) {
+	return s.nodeRepo.GetByID(nodeID)
+}
+
+// ListNodes 按类型/上级/服务区县/关键字分页查询网络节点
+func (s *NodeSvc) ListNodes(req *NodeListReq) ([]*model.Node, int64, error) {
+	normalizePage(&req.Page, &req.PageSize)
+	return s.nodeRepo.List(repository.NodeListQuery{
+		Type:     req.Type,
+		ParentID: req.ParentID,
+		District: strings.TrimSpace(req.District),
+		Keyword:  strings.TrimSpace(req.Keyword),
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	})
+}
+
+// DeleteNode 删除网络节点（仍有挂靠网点、未完成的运输/派送任务、归属派送员或常驻司机的节点不可删除）
+func (s *NodeSvc) DeleteNode(nodeID string) error {
+	if _, err := s.nodeRepo.GetByID(nodeID); err != nil {
+		return err
+	}
+	references := []struct {
+		what  string
+		count func(nodeID string) (int64, error)
+	}{
+		{"挂靠网点", s.nodeRepo.CountChildren},
+		{"未完成的运输任务", s.transportRepo.CountOpenTasksByNodeID},
+		{"未完成的派送任务", s.deliveryRepo.CountOpenTasksByNodeID},
+		{"归属派送员", s.courierRepo.CountByStationID},
+		{"常驻司机", s.driverRepo.CountByHomeNodeID},
+	}
+	for _, ref := range references {
+		n, err := ref.count(nodeID)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return fmt.Errorf("%w：%d个%s", errno.ErrNodeInUse, n, ref.what)
+		}
+	}
+	return s.nodeRepo.Delete(nodeID)
+}
+
+// SeedFromNetwork 将路网中尚未登记的节点导入节点表（启动时调用，保证任务引用的节点与路网一致）
+func (s *NodeSvc) SeedFromNetwork(graph *routing.Graph) error {
+	// 先导入分拣中心，网点导入时即可解析上级
+	for _, nodeType := range []string{"sorting_center", "station"} {
+		for _, n := range graph.Nodes() {
+			if n.Type != nodeType {
+				continue
+			}
+			if _, err := s.nodeRepo.GetByName(n.Name); err == nil {
+				continue
+			} else if !errors.Is(err, errno.ErrNodeNotFound) {
+				return err
+			}
+			req := &NodeReq{
+				Code:             n.Code,
+				Name:             n.Name,
+				Type:             n.Type,
+				Address:          n.Address,
+				Longitude:        n.Longitude,
+				Latitude:         n.Latitude,
+				ServiceDistricts: n.Districts,
+			}
+			if n.Parent != "" {
+				parent, err := s.nodeRepo.GetByName(n.Parent)
+				if err != nil {
+					return err
+				}
+				req.ParentID = parent.NodeID
+			}
+			if _, err := s.CreateNode(req); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyReq 将请求参数写入节点并校验（名称/编码唯一、上级须为分拣中心）
+func (s *NodeSvc) applyReq(node *model.Node, req *NodeReq) error {
+	node.Code = req.Code
+	node.Name = req.Name
+	node.Type = req.Type
+	node.Address = req.Address
+	node.Longitude = req.Longitude
+	node.Latitude = req.Latitude
+	node.ParentID = strings.TrimSpace(req.ParentID)
+	node.OpeningHours = strings.TrimSpace(req.OpeningHours)
+	node.ServiceDistricts = req.ServiceDistricts
+	if req.Status != "" {
+		node.Status = req.Status
+	}
+	node.Normalize()
+	if err := node.Validate(); err != nil {
+		return err
+	}
+	if node.ParentID != "" {
+		parent, err := s.nodeRepo.GetByID(node.ParentID)
+		if err != nil {
+			if errors.Is(err, errno.ErrNodeNotFound) {
+				return errno.ErrNodeParentInvalid
+			}
+			return err
+		}
+		if parent.Type != "sorting_center" {
+			return errno.ErrNodeParentInvalid
+		}
+	}
+	exists, err := s.nodeRepo.ExistsByNameOrCode(node.Name, node.Code, node.NodeID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errno.ErrNodeExists
+	}
+	// 未提供坐标时按地址解析
+	if node.Longitude == 0 && node.Latitude == 0 && node.Address != "" {
+		lng, lat, err := s.geoUtils.GetCoordinates(node.Address)
+		if err != nil {
+			log.Printf("节点地址%s解析失败，坐标记为0: %v", node.Address, err)
+		} else {
+			node.Longitude, node.Latitude = lng, lat
+		}
+	}
+	return nil
+}
+
+// resolveNode 按ID或名称（去除首尾空白）解析网络节点，ID优先；节点不存在或已停用时返回错误
+func resolveNode(repo repository.NodeRepo, nodeID, name string) (*model.Node, error) {
+	var (
+		node *model.Node
+		err  error
+	)
+	if nodeID = strings.TrimSpace(nodeID); nodeID != "" {
+		node, err = repo.GetByID(nodeID)
+	} else if name = strings.TrimSpace(name); name != "" {
+		node, err = repo.GetByName(name)
+	} else {
+		return nil, errno.ErrParamInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if node.Status == "disabled" {
+		return nil, errno.ErrNodeDisabled
+	}
+	return node, nil
+}
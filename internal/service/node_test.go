@@ -0,0 +1,59 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+func TestDeleteNodeInUse(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(s *memStore)
+		wantErr error
+	}{
+		{"无引用可删除", func(*memStore) {}, nil},
+		{"仍有挂靠网点", func(s *memStore) {
+			s.nodes["N2"] = model.Node{NodeID: "N2", Name: "网点", ParentID: "N1"}
+		}, errno.ErrNodeInUse},
+		{"到达该节点的运输任务未完成", func(s *memStore) {
+			s.transports["T1"] = model.TransportTask{TaskID: "T1", StartNodeID: "N9", EndNodeID: "N1", Status: "arrived"}
+		}, errno.ErrNodeInUse},
+		{"运输任务已完成", func(s *memStore) {
+			s.transports["T1"] = model.TransportTask{TaskID: "T1", StartNodeID: "N1", EndNodeID: "N9", Status: "completed"}
+		}, nil},
+		{"从该节点出发的派送任务未完成", func(s *memStore) {
+			s.deliveries["D1"] = model.DeliveryTask{TaskID: "D1", StartNodeID: "N1", Status: "abnormal"}
+		}, errno.ErrNodeInUse},
+		{"仍有归属派送员", func(s *memStore) {
+			s.couriers["C1"] = model.CourierArea{CourierID: "C1", StationID: "N1"}
+		}, errno.ErrNodeInUse},
+		{"仍有常驻司机", func(s *memStore) {
+			s.drivers["DR1"] = model.Driver{DriverID: "DR1", HomeNodeID: "N1"}
+		}, errno.ErrNodeInUse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemStore()
+			s.nodes["N1"] = model.Node{NodeID: "N1", Name: "分拣中心", Type: "sorting_center"}
+			tt.setup(s)
+			repos := s.repos()
+			svc := &NodeSvc{
+				nodeRepo:      repos.Node,
+				transportRepo: repos.Transport,
+				deliveryRepo:  repos.Delivery,
+				courierRepo:   repos.Courier,
+				driverRepo:    repos.Driver,
+			}
+			err := svc.DeleteNode("N1")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeleteNode() error = %v, want %v", err, tt.wantErr)
+			}
+			if _, kept := s.nodes["N1"]; kept == (tt.wantErr == nil) {
+				t.Fatalf("节点N1是否保留 = %v, want %v", kept, tt.wantErr != nil)
+			}
+		})
+	}
+}
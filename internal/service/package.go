@@ -2,47 +2,162 @@ package service
 
 import (
 	"fmt"
+	"log"
+	"math"
 	"time"
 
+	"github.com/LFrankl/fdu-lab3/internal/auth"
 	"github.com/LFrankl/fdu-lab3/internal/model"
 	"github.com/LFrankl/fdu-lab3/internal/repository"
+	"github.com/LFrankl/fdu-lab3/internal/routing"
 	"github.com/LFrankl/fdu-lab3/internal/util"
+	"github.com/LFrankl/fdu-lab3/pkg/db"
 )
 
 // PackageService 包裹业务接口
 type PackageService interface {
-	CreatePackage(pkg *model.Package, operator, nodeName, nodeAddr string) (*model.Package, error)
+	CreatePackage(caller *auth.Identity, pkg *model.Package, nodeName, nodeAddr string) (*model.Package, error)
 	GetPackageDetail(packageID string) (map[string]interface{}, error)
-	HandleSortingAbnormal(packageID, reason, handler string) error
+	HandleSortingAbnormal(caller *auth.Identity, packageID, reason string) error
 	ChangeStatus(packageID string, status string) error
+	ChangeStatusWithAudit(caller *auth.Identity, packageID, status, reason, nodeName, nodeAddr string) (*model.Package, error)
+	ETAAccuracy(days int) (*ETAAccuracyReport, error)
+	PublicTracking(packageID, phoneSuffix string) (*PublicTracking, error)
+	SearchPackages(req *PackageSearchReq) (*PackageSearchResult, error)
+	ImportPackages(caller *auth.Identity, rows [][]string, nodeName, nodeAddr string) (*PackageImportResult, error)
 }
 
 // packageService 实现
 type packageService struct {
-	pkgRepo  repository.PackageRepository
-	geoUtils *util.GeoUtils
-	idGen    *util.IDGenerator
+	uow           repository.UnitOfWork // 跨仓储写操作的事务边界
+	pkgRepo       repository.PackageRepository
+	nodeRepo      repository.NodeRepo      // 匹配登记节点
+	etaRepo       repository.ETARepo       // 送达时间预测记录
+	transportRepo repository.TransportRepo // 绑定的运输任务路线（计划行程）
+	graph         *routing.Graph           // 路网规划（计划行程）
+	geoUtils      *util.GeoUtils
+	idGen         *util.IDGenerator
 }
 
 // NewPackageService 创建包裹服务实例
 func NewPackageService() PackageService {
 	return &packageService{
-		pkgRepo:  repository.NewPackageRepository(),
-		geoUtils: util.NewGeoUtils(),
-		idGen:    util.NewIDGenerator(),
+		uow:           repository.NewUnitOfWork(db.DB),
+		pkgRepo:       repository.NewPackageRepository(db.DB),
+		nodeRepo:      repository.NewNodeRepo(db.DB),
+		etaRepo:       repository.NewETARepo(db.DB),
+		transportRepo: repository.NewTransportRepo(db.DB),
+		graph:         routing.Default(),
+		geoUtils:      util.NewGeoUtils(),
+		idGen:         util.NewIDGenerator(),
 	}
 }
 
+// traceNode 解析轨迹节点：优先匹配登记节点，否则按传入名称与地址记录并解析坐标
+func (s *packageService) traceNode(nodeName, nodeAddr string) traceNode {
+	if node, ok := registeredTraceNode(s.nodeRepo, nodeName); ok {
+		if node.Address == "" {
+			node.Address = nodeAddr
+		}
+		return node
+	}
+	node := traceNode{Name: nodeName, Address: nodeAddr}
+	lng, lat, err := s.geoUtils.GetCoordinates(nodeAddr)
+	if err != nil {
+		log.Printf("节点地址%s解析失败，坐标记为0: %v", nodeAddr, err)
+		return node // 解析失败使用默认值
+	}
+	node.Longitude, node.Latitude = lng, lat
+	return node
+}
+
+// fillReceiverCoordinates 解析收件地址坐标（已提供坐标时跳过；详细地址解析失败时按省市区解析，仍失败记为0）
+func (s *packageService) fillReceiverCoordinates(pkg *model.Package) {
+	if pkg.ReceiverLongitude != 0 || pkg.ReceiverLatitude != 0 {
+		return
+	}
+	for _, addr := range []string{pkg.ReceiverAddress, pkg.ReceiverProvince + pkg.ReceiverCity + pkg.ReceiverDistrict} {
+		if addr == "" {
+			continue
+		}
+		if lng, lat, err := s.geoUtils.GetCoordinates(addr); err == nil {
+			pkg.ReceiverLongitude, pkg.ReceiverLatitude = lng, lat
+			return
+		}
+	}
+	log.Printf("包裹%s收件地址%s解析失败，坐标记为0", pkg.PackageID, pkg.ReceiverAddress)
+}
+
 // ChangeStatus 更改状态
 // 这里我们认为是提供一般性状态变更，异常不走这里
 func (s *packageService) ChangeStatus(packageID string, status string) error {
-	//调用底层的updateStatus
-	//id和status已在上层做过检验
-	return s.pkgRepo.UpdateStatus(packageID, status, "", "")
+	// 经由领域行为校验状态流转，非法流转返回errno.ErrPackageStatusInvalid
+	return s.uow.Transaction(func(repos *repository.Repositories) error {
+		return changePackageStatus(repos, packageID, status)
+	})
 }
 
-// CreatePackage 创建包裹并添加揽收轨迹
-func (s *packageService) CreatePackage(pkg *model.Package, operator, nodeName, nodeAddr string) (*model.Package, error) {
+// ChangeStatusWithAudit 人工变更包裹状态（运营纠错），状态、审计日志与轨迹整体事务提交
+func (s *packageService) ChangeStatusWithAudit(caller *auth.Identity, packageID, status, reason, nodeName, nodeAddr string) (*model.Package, error) {
+	operator := caller.OperatorName()
+	// 解析轨迹节点（地址解析等网络调用置于事务外）
+	node := s.traceNode(nodeName, nodeAddr)
+
+	var pkg *model.Package
+	err := s.uow.Transaction(func(repos *repository.Repositories) error {
+		var err error
+		pkg, err = repos.Package.GetByID(packageID)
+		if err != nil {
+			return err
+		}
+		oldStatus := pkg.Status
+
+		// 经由领域行为校验状态流转，异常状态同时记录原因与处理人
+		if isAbnormalStatus(status) {
+			err = markPackageAbnormal(repos, pkg, status, reason, operator)
+		} else {
+			err = savePackageStatus(repos, pkg, status)
+		}
+		if err != nil {
+			return err
+		}
+
+		// 记录审计日志：谁在何时因何变更
+		statusLog := &model.PackageStatusLog{
+			PackageID:  packageID,
+			FromStatus: oldStatus,
+			ToStatus:   pkg.Status,
+			Operator:   operator,
+			Reason:     reason,
+		}
+		if err := repos.Package.CreateStatusLog(statusLog); err != nil {
+			return err
+		}
+
+		// 创建人工变更轨迹
+		trace := &model.PackageTrace{
+			PackageID:     packageID,
+			NodeType:      "manual_correction",
+			NodeID:        node.ID,
+			NodeName:      node.Name,
+			NodeAddress:   node.Address,
+			Longitude:     node.Longitude,
+			Latitude:      node.Latitude,
+			OperationTime: time.Now(),
+			Operator:      operator,
+			Remark:        fmt.Sprintf("人工变更状态：%s → %s，原因：%s", oldStatus, pkg.Status, reason),
+		}
+		return repos.Package.CreateTrace(trace)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// CreatePackage 创建包裹并添加揽收轨迹（包裹与轨迹整体事务提交）
+func (s *packageService) CreatePackage(caller *auth.Identity, pkg *model.Package, nodeName, nodeAddr string) (*model.Package, error) {
+	operator := caller.OperatorName()
 	// 生成运单号
 	if pkg.PackageID == "" {
 		pkg.PackageID = s.idGen.GeneratePackageID()
@@ -50,31 +165,37 @@ func (s *packageService) CreatePackage(pkg *model.Package, operator, nodeName, n
 	// 这里初始化为collected只有后续检查后，才会变成sorted
 	pkg.Status = "collected"
 
-	// 创建包裹
-	if err := s.pkgRepo.Create(pkg); err != nil {
-		return nil, err
-	}
+	// 解析轨迹节点（地址解析等网络调用置于事务外）
+	node := s.traceNode(nodeName, nodeAddr)
+	// 解析收件地址坐标，派送顺序优化据此离线计算
+	s.fillReceiverCoordinates(pkg)
 
-	// 获取节点经纬度
-	lng, lat, err := s.geoUtils.GetCoordinates(nodeAddr)
-	if err != nil {
-		lng, lat = 0, 0 // 解析失败使用默认值
-	}
-
-	// 创建揽收轨迹
-	trace := &model.PackageTrace{
-		PackageID:     pkg.PackageID,
-		NodeType:      "collection",
-		NodeName:      nodeName,
-		NodeAddress:   nodeAddr,
-		Longitude:     lng,
-		Latitude:      lat,
-		OperationTime: time.Now(),
-		Operator:      operator,
-		Remark:        "包裹已揽收",
-	}
+	err := s.uow.Transaction(func(repos *repository.Repositories) error {
+		// 创建包裹
+		if err := repos.Package.Create(pkg); err != nil {
+			return err
+		}
 
-	if err := s.pkgRepo.CreateTrace(trace); err != nil {
+		// 创建揽收轨迹
+		trace := &model.PackageTrace{
+			PackageID:     pkg.PackageID,
+			NodeType:      "collection",
+			NodeID:        node.ID,
+			NodeName:      node.Name,
+			NodeAddress:   node.Address,
+			Longitude:     node.Longitude,
+			Latitude:      node.Latitude,
+			OperationTime: time.Now(),
+			Operator:      operator,
+			Remark:        "包裹已揽收",
+		}
+		if err := repos.Package.CreateTrace(trace); err != nil {
+			return err
+		}
+		// 揽收后首次预测送达时间
+		return refreshPackageETA(repos, pkg)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -86,7 +207,7 @@ func (s *packageService) GetPackageDetail(packageID string) (map[string]interfac
 	// 获取包裹基本信息
 	pkg, err := s.pkgRepo.GetByID(packageID)
 	if err != nil {
-		return nil, fmt.Errorf("包裹不存在: %v", err)
+		return nil, err
 	}
 
 	// 获取轨迹
@@ -95,6 +216,28 @@ func (s *packageService) GetPackageDetail(packageID string) (map[string]interfac
 		return nil, err
 	}
 
+	// 获取异常历史
+	records, err := s.pkgRepo.GetAbnormalRecordsByPackageID(packageID)
+	if err != nil {
+		return nil, err
+	}
+	abnormalList := make([]map[string]interface{}, 0, len(records))
+	for _, r := range records {
+		abnormalList = append(abnormalList, map[string]interface{}{
+			"record_id":         r.RecordID,
+			"abnormal_type":     r.AbnormalType,
+			"sub_type":          r.SubType,
+			"reason":            r.AbnormalReason,
+			"task_id":           r.TaskID,
+			"node_name":         r.NodeName,
+			"reporter":          r.Reporter,
+			"status":            r.Status,
+			"processing_method": r.ProcessingMethod,
+			"processor":         r.Processor,
+			"reported_at":       r.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
 	// 解析轨迹
 	//traceList := make([]map[string]interface{}, 0, len(traces))
 	//var currentNode *model.PackageTrace
@@ -137,29 +280,28 @@ func (s *packageService) GetPackageDetail(packageID string) (map[string]interfac
 	// 解析轨迹
 	traceList := make([]map[string]interface{}, 0, len(traces))
 	var currentNode *model.PackageTrace
-	var nextNode *model.PackageTrace
 
-	// 第一步：先判断traces是否为空，避免空列表导致currentNode/nextNode为nil
+	// 第一步：先判断traces是否为空，避免空列表导致currentNode为nil
 	if len(traces) > 0 {
 		for i, t := range traces {
 			traceList = append(traceList, map[string]interface{}{
 				"node_type":      t.NodeType,
+				"node_id":        t.NodeID,
 				"node_name":      t.NodeName,
 				"operation_time": t.OperationTime.Format("2006-01-02 15:04:05"),
 				"remark":         t.Remark,
 			})
 			// 当前节点：取最后一条轨迹作为当前节点（更符合业务逻辑）
 			currentNode = &traces[i]
-			// 下一个节点：只有i不是最后一个时才赋值
-			if i < len(traces)-1 {
-				nextNode = &traces[i+1]
-			} else {
-				// 最后一个节点：下一个节点置nil
-				nextNode = nil
-			}
 		}
 	}
 
+	// 计划行程：已经过节点取自轨迹，后续节点取自绑定的运输任务路线与路网规划（下一节点不可能来自历史轨迹）
+	itinerary, nextNodeName, err := s.packageItinerary(pkg, traces)
+	if err != nil {
+		return nil, err
+	}
+
 	// 第二步：安全获取当前节点/下一个节点名称（核心修复）
 	var currentNodeName string
 	if currentNode != nil {
@@ -168,10 +310,7 @@ func (s *packageService) GetPackageDetail(packageID string) (map[string]interfac
 		currentNodeName = "暂无轨迹信息" // 兜底值
 	}
 
-	var nextNodeName string
-	if nextNode != nil {
-		nextNodeName = nextNode.NodeName
-	} else {
+	if nextNodeName == "" {
 		nextNodeName = "暂无后续节点" // 兜底值
 	}
 
@@ -189,46 +328,202 @@ func (s *packageService) GetPackageDetail(packageID string) (map[string]interfac
 			"address": pkg.ReceiverAddress,
 		},
 		"current_status":         pkg.Status,
-		"current_position":       currentNodeName,    // 替换为安全值
-		"next_node":              nextNodeName,       // 替换为安全值
-		"estimated_arrival_time": "2025-12-01 10:00", // 实际场景需计算
+		"current_position":       currentNodeName, // 替换为安全值
+		"next_node":              nextNodeName,    // 替换为安全值
+		"estimated_arrival_time": "",
+		"eta":                    nil,
+		"itinerary":              itinerary,
 		"trace_history":          traceList,
+		"abnormal_history":       abnormalList,
+	}
+	// 送达时间：取最近一次预测（历史包裹尚无预测时即时计算，不落库）；已签收时为实际送达时间
+	eta, err := s.latestETA(pkg)
+	if err != nil {
+		return nil, err
+	}
+	if eta != nil {
+		if pkg.Status == "delivered" && !eta.ActualDeliveredAt.IsZero() {
+			result["estimated_arrival_time"] = eta.ActualDeliveredAt.Format("2006-01-02 15:04")
+		} else if pkg.Status != "delivered" {
+			result["estimated_arrival_time"] = eta.EstimatedTime.Format("2006-01-02 15:04")
+			result["eta"] = map[string]interface{}{
+				"estimated_time": eta.EstimatedTime.Format("2006-01-02 15:04"),
+				"window_start":   eta.WindowStart.Format("2006-01-02 15:04"),
+				"window_end":     eta.WindowEnd.Format("2006-01-02 15:04"),
+				"confidence":     eta.Confidence,
+				"basis":          eta.Basis,
+				"predicted_at":   eta.PredictedAt.Format("2006-01-02 15:04:05"),
+			}
+		}
 	}
 
 	return result, nil
 
 }
 
+// latestETA 包裹最近一次送达时间预测；预测状态与包裹当前状态不一致（如历史包裹）时即时预测，不落库
+func (s *packageService) latestETA(pkg *model.Package) (*model.PackageETA, error) {
+	eta, err := s.etaRepo.GetLatest(pkg.PackageID)
+	if err != nil {
+		return nil, err
+	}
+	if eta != nil && (eta.PackageStatus == pkg.Status || pkg.Status == "delivered") {
+		return eta, nil
+	}
+	if pkg.Status == "delivered" {
+		return nil, nil
+	}
+	return predictPackageETA(repository.NewRepositories(db.DB), pkg, time.Now())
+}
+
+// ETAAccuracyReport 送达时间预测准确率（命中率为实际送达落在预测窗口内的比例，误差单位为小时）
+type ETAAccuracyReport struct {
+	Since             time.Time           `json:"since"`
+	Samples           int64               `json:"samples"`
+	HitRate           float64             `json:"hit_rate"`
+	MeanAbsErrorHours float64             `json:"mean_abs_error_hours"`
+	ByConfidence      []*ETAAccuracyGroup `json:"by_confidence"`
+}
+
+// ETAAccuracyGroup 某置信度下的预测准确率
+type ETAAccuracyGroup struct {
+	Confidence        string  `json:"confidence"`
+	Samples           int64   `json:"samples"`
+	HitRate           float64 `json:"hit_rate"`
+	MeanAbsErrorHours float64 `json:"mean_abs_error_hours"`
+}
+
+// ETAAccuracy 统计近days天（默认30天）已签收包裹的送达时间预测准确率
+func (s *packageService) ETAAccuracy(days int) (*ETAAccuracyReport, error) {
+	if days <= 0 {
+		days = 30
+	}
+	report := &ETAAccuracyReport{Since: time.Now().AddDate(0, 0, -days), ByConfidence: []*ETAAccuracyGroup{}}
+	rows, err := s.etaRepo.Accuracy(report.Since)
+	if err != nil {
+		return nil, err
+	}
+	var hits int64
+	var errorMinutes float64
+	for _, row := range rows {
+		report.ByConfidence = append(report.ByConfidence, &ETAAccuracyGroup{
+			Confidence:        row.Confidence,
+			Samples:           row.Samples,
+			HitRate:           ratio(float64(row.Hits), float64(row.Samples)),
+			MeanAbsErrorHours: roundHours(row.MeanAbsErrorMinutes / 60),
+		})
+		report.Samples += row.Samples
+		hits += row.Hits
+		errorMinutes += row.MeanAbsErrorMinutes * float64(row.Samples)
+	}
+	report.HitRate = ratio(float64(hits), float64(report.Samples))
+	report.MeanAbsErrorHours = roundHours(ratio(errorMinutes, float64(report.Samples)) / 60)
+	return report, nil
+}
+
+// ratio 保留四位小数的比值，分母为0时返回0
+func ratio(numerator, denominator float64) float64 {
+	if denominator == 0 {
+		return 0
+	}
+	return math.Round(numerator/denominator*10000) / 10000
+}
+
 // HandleSortingAbnormal 处理分拣异常
 // 这里直接给上层调用，功能是 更新对应包裹的状态为不正常，然后，把异常传到db上传
-func (s *packageService) HandleSortingAbnormal(packageID, reason, handler string) error {
-	// 更新包裹状态
-	if err := s.pkgRepo.UpdateStatus(packageID, "abnormal", reason, handler); err != nil {
+// 包裹状态、异常记录、异常轨迹整体事务提交
+func (s *packageService) HandleSortingAbnormal(caller *auth.Identity, packageID, reason string) error {
+	handler := caller.OperatorName()
+	return s.uow.Transaction(func(repos *repository.Repositories) error {
+		// 更新包裹状态（仅已揽收待分拣的包裹可标记分拣异常）
+		pkg, err := repos.Package.GetByID(packageID)
+		if err != nil {
+			return err
+		}
+		if err := markPackageAbnormal(repos, pkg, "abnormal", reason, handler); err != nil {
+			return err
+		}
+
+		// 创建异常记录与异常轨迹
+		abnormalRecord := &model.AbnormalRecord{
+			PackageID:      packageID,
+			AbnormalType:   "sorting",
+			AbnormalReason: reason,
+			NodeName:       "分拣中心",
+			Reporter:       handler,
+		}
+		return recordPackageAbnormal(repos.Package, abnormalRecord, fmt.Sprintf("分拣异常：%s", reason))
+	})
+}
+
+// isAbnormalStatus 是否为包裹异常状态
+func isAbnormalStatus(status string) bool {
+	return status == "abnormal" || status == "transport_abnormal" || status == "delivery_abnormal"
+}
+
+// changePackageStatus 加载包裹并通过领域行为变更状态后持久化
+func changePackageStatus(repos *repository.Repositories, packageID, newStatus string) error {
+	pkg, err := repos.Package.GetByID(packageID)
+	if err != nil {
 		return err
 	}
+	return savePackageStatus(repos, pkg, newStatus)
+}
 
-	// 创建异常记录
-	abnormalRecord := &model.AbnormalRecord{
-		PackageID:      packageID,
-		AbnormalType:   "sorting",
-		AbnormalReason: reason,
-		Processor:      handler,
-		Status:         "pending",
+// syncPackagesStatus 任务状态变更时同步包裹状态，已处于目标状态的包裹跳过
+func syncPackagesStatus(repos *repository.Repositories, packageIDs []string, newStatus string) error {
+	for _, pkgID := range packageIDs {
+		pkg, err := repos.Package.GetByID(pkgID)
+		if err != nil {
+			return err
+		}
+		if pkg.Status == newStatus {
+			continue
+		}
+		if err := savePackageStatus(repos, pkg, newStatus); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	if err := s.pkgRepo.CreateAbnormalRecord(abnormalRecord); err != nil {
+// recordPackageAbnormal 为单个包裹记录一次异常事件：异常记录与异常轨迹（轨迹备注超长时截断）
+func recordPackageAbnormal(repo repository.PackageRepository, record *model.AbnormalRecord, remark string) error {
+	record.Status = "pending"
+	if err := repo.CreateAbnormalRecord(record); err != nil {
 		return err
 	}
-
-	// 创建异常轨迹
 	trace := &model.PackageTrace{
-		PackageID:     packageID,
+		PackageID:     record.PackageID,
 		NodeType:      "abnormal",
-		NodeName:      "分拣中心",
+		NodeName:      record.NodeName,
 		OperationTime: time.Now(),
-		Operator:      handler,
-		Remark:        fmt.Sprintf("分拣异常：%s", reason),
+		Operator:      record.Reporter,
+		Remark:        truncateRemark(remark),
 	}
+	return repo.CreateTrace(trace)
+}
 
-	return s.pkgRepo.CreateTrace(trace)
+// savePackageStatus 执行领域行为变更状态并持久化（同时重新预测送达时间），非法流转返回包装后的errno.ErrPackageStatusInvalid
+func savePackageStatus(repos *repository.Repositories, pkg *model.Package, newStatus string) error {
+	oldStatus := pkg.Status
+	if err := pkg.ChangeStatus(newStatus); err != nil {
+		return fmt.Errorf("包裹%s由%s变更为%s失败：%w", pkg.PackageID, oldStatus, newStatus, err)
+	}
+	if err := repos.Package.UpdateStatus(pkg.PackageID, pkg.Status, "", ""); err != nil {
+		return err
+	}
+	return refreshPackageETA(repos, pkg)
+}
+
+// markPackageAbnormal 执行领域行为标记包裹异常并持久化
+func markPackageAbnormal(repos *repository.Repositories, pkg *model.Package, abnormalStatus, reason, handler string) error {
+	oldStatus := pkg.Status
+	if err := pkg.MarkAbnormal(abnormalStatus, reason, handler); err != nil {
+		return fmt.Errorf("包裹%s由%s变更为%s失败：%w", pkg.PackageID, oldStatus, abnormalStatus, err)
+	}
+	if err := repos.Package.UpdateStatus(pkg.PackageID, pkg.Status, pkg.AbnormalReason, pkg.AbnormalHandler); err != nil {
+		return err
+	}
+	return refreshPackageETA(repos, pkg)
 }
@@ -0,0 +1,359 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/LFrankl/fdu-lab3/internal/auth"
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/internal/repository"
+	"github.com/LFrankl/fdu-lab3/internal/util"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+// 批量导入参数
+const (
+	importBatchSize  = 200   // 每个事务写入的包裹数
+	importMaxRows    = 10000 // 单个文件最多数据行
+	importGeoWorkers = 8     // 并发解析收件地址的协程数
+	importIDRetries  = 3     // 逐条重试时运单号/轨迹ID重复的重新生成次数
+)
+
+// ImportMaxBytes 单个导入文件的大小上限（字节）
+const ImportMaxBytes = 10 << 20
+
+// importColumn 导入文件列（列名与创建包裹请求体字段一致）
+type importColumn struct {
+	name     string
+	required bool
+	maxLen   int // 字符数上限（与表字段长度一致），0为数值列
+}
+
+// importColumns 导入文件支持的列，表头不区分大小写、顺序不限，多余的列忽略
+var importColumns = []importColumn{
+	{"sender_name", true, 64},
+	{"sender_phone", true, 20},
+	{"sender_address", true, 255},
+	{"receiver_name", true, 64},
+	{"receiver_phone", true, 20},
+	{"receiver_address", true, 255},
+	{"receiver_province", true, 32},
+	{"receiver_city", true, 32},
+	{"receiver_district", true, 32},
+	{"weight", true, 0},
+	{"length", false, 0},
+	{"width", false, 0},
+	{"height", false, 0},
+}
+
+// PackageImportRow 单行导入结果
+type PackageImportRow struct {
+	Row       int      // 表格行号（表头为第1行）
+	Values    []string // 原始单元格
+	PackageID string   // 创建成功时的运单号
+	Error     string   // 校验或写入失败原因
+	pkg       *model.Package
+	traceID   string // 揽收轨迹ID
+}
+
+// PackageImportResult 批量导入结果
+type PackageImportResult struct {
+	Header  []string
+	Rows    []*PackageImportRow
+	Total   int
+	Created int
+	Failed  int
+}
+
+// ReadImportFile 读取导入文件（.csv/.xlsx），数据行超过importMaxRows时读取中即返回errno.ErrImportTooManyRows，
+// 文件超过ImportMaxBytes时返回errno.ErrImportTooLarge，其余读取错误包装为errno.ErrImportFileInvalid
+func ReadImportFile(filename string, r io.Reader) ([][]string, error) {
+	rows, err := util.ReadSheet(filename, r, importMaxRows, ImportMaxBytes)
+	if errors.Is(err, errno.ErrImportTooManyRows) {
+		return nil, err
+	}
+	if errors.Is(err, errno.ErrImportTooLarge) {
+		return nil, fmt.Errorf("%w（%dMB）", errno.ErrImportTooLarge, ImportMaxBytes>>20)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w：%v", errno.ErrImportFileInvalid, err)
+	}
+	return rows, nil
+}
+
+// ImportPackages 批量创建包裹：逐行校验，收件地址并发解析坐标，
+// 每importBatchSize个包裹在一个事务中批量写入包裹、揽收轨迹与首次送达预测；批量写入失败时逐条重试以定位失败行
+func (s *packageService) ImportPackages(caller *auth.Identity, rows [][]string, nodeName, nodeAddr string) (*PackageImportResult, error) {
+	if len(rows) == 0 {
+		return nil, errno.ErrImportEmpty
+	}
+	columns, err := importHeader(rows[0])
+	if err != nil {
+		return nil, err
+	}
+	result := &PackageImportResult{Header: rows[0]}
+	for i, values := range rows[1:] {
+		if isBlankRow(values) {
+			continue
+		}
+		row := &PackageImportRow{Row: i + 2, Values: values}
+		if row.pkg, err = importPackage(columns, values); err != nil {
+			row.Error = err.Error()
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	if len(result.Rows) == 0 {
+		return nil, errno.ErrImportEmpty
+	}
+	if len(result.Rows) > importMaxRows {
+		return nil, fmt.Errorf("%w（%d行）", errno.ErrImportTooManyRows, importMaxRows)
+	}
+
+	var valid []*PackageImportRow
+	for _, row := range result.Rows {
+		if row.pkg != nil {
+			valid = append(valid, row)
+		}
+	}
+	// 解析轨迹节点与收件地址坐标（网络调用置于事务外）
+	node := s.traceNode(nodeName, nodeAddr)
+	s.fillReceiverCoordinatesConcurrently(valid)
+	s.assignImportIDs(valid)
+
+	operator := caller.OperatorName()
+	for start := 0; start < len(valid); start += importBatchSize {
+		batch := valid[start:min(start+importBatchSize, len(valid))]
+		if err := s.createImportBatch(batch, node, operator); err == nil {
+			continue
+		}
+		for _, row := range batch {
+			if err := s.createImportRow(row, node, operator); err != nil {
+				row.Error = "写入失败：" + err.Error()
+			}
+		}
+	}
+
+	result.Total = len(result.Rows)
+	for _, row := range result.Rows {
+		if row.Error == "" {
+			row.PackageID = row.pkg.PackageID
+			result.Created++
+		} else {
+			result.Failed++
+		}
+	}
+	return result, nil
+}
+
+// createImportBatch 在一个事务中写入一批包裹、揽收轨迹与首次送达预测
+func (s *packageService) createImportBatch(batch []*PackageImportRow, node traceNode, operator string) error {
+	now := time.Now()
+	pkgs := make([]*model.Package, 0, len(batch))
+	traces := make([]*model.PackageTrace, 0, len(batch))
+	for _, row := range batch {
+		pkgs = append(pkgs, row.pkg)
+		traces = append(traces, &model.PackageTrace{
+			TraceID:       row.traceID,
+			PackageID:     row.pkg.PackageID,
+			NodeType:      "collection",
+			NodeID:        node.ID,
+			NodeName:      node.Name,
+			NodeAddress:   node.Address,
+			Longitude:     node.Longitude,
+			Latitude:      node.Latitude,
+			OperationTime: now,
+			Operator:      operator,
+			Remark:        "包裹已揽收（批量导入）",
+		})
+	}
+	return s.uow.Transaction(func(repos *repository.Repositories) error {
+		if err := repos.Package.CreateBatch(pkgs); err != nil {
+			return err
+		}
+		if err := repos.Package.CreateTraces(traces); err != nil {
+			return err
+		}
+		// 揽收后首次预测送达时间（新包裹仅有揽收轨迹，按揽收节点坐标直接计算）
+		etas := make([]*model.PackageETA, 0, len(pkgs))
+		for i, pkg := range pkgs {
+			etas = append(etas, collectedPackageETA(pkg, traces[i], now))
+		}
+		return repos.ETA.CreateBatch(etas)
+	})
+}
+
+// createImportRow 单独写入一行；运单号或轨迹ID与已有数据重复时重新生成后重试
+func (s *packageService) createImportRow(row *PackageImportRow, node traceNode, operator string) error {
+	err := s.createImportBatch([]*PackageImportRow{row}, node, operator)
+	for i := 0; i < importIDRetries && errors.Is(err, errno.ErrPackageDuplicated); i++ {
+		row.pkg.PackageID = s.idGen.GeneratePackageID()
+		row.traceID = s.idGen.GenerateTraceID()
+		err = s.createImportBatch([]*PackageImportRow{row}, node, operator)
+	}
+	return err
+}
+
+// importHeader 解析表头，返回列名到列序号的映射；缺少必填列时返回errno.ErrImportHeaderInvalid
+func importHeader(header []string) (map[string]int, error) {
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := columns[name]; !ok && name != "" {
+			columns[name] = i
+		}
+	}
+	var missing []string
+	for _, col := range importColumns {
+		if _, ok := columns[col.name]; col.required && !ok {
+			missing = append(missing, col.name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w：%s", errno.ErrImportHeaderInvalid, strings.Join(missing, "、"))
+	}
+	return columns, nil
+}
+
+// importPackage 校验一行数据并构建包裹：必填列非空、文本不超过字段长度、电话仅含数字与+-、重量为正数、尺寸非负
+func importPackage(columns map[string]int, values []string) (*model.Package, error) {
+	cell := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(values) {
+			return strings.TrimSpace(values[i])
+		}
+		return ""
+	}
+	var problems []string
+	numbers := make(map[string]float64, 4)
+	for _, col := range importColumns {
+		v := cell(col.name)
+		switch {
+		case v == "":
+			if col.required {
+				problems = append(problems, col.name+"不能为空")
+			}
+		case col.maxLen > 0:
+			if utf8.RuneCountInString(v) > col.maxLen {
+				problems = append(problems, fmt.Sprintf("%s超过%d个字符", col.name, col.maxLen))
+			}
+		default:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 0 || (col.name == "weight" && f == 0) {
+				problems = append(problems, col.name+"须为正数")
+				continue
+			}
+			numbers[col.name] = f
+		}
+	}
+	for _, name := range []string{"sender_phone", "receiver_phone"} {
+		if v := cell(name); v != "" && strings.Trim(v, "0123456789+-") != "" {
+			problems = append(problems, name+"格式不正确")
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, "；"))
+	}
+	return &model.Package{
+		SenderName:       cell("sender_name"),
+		SenderPhone:      cell("sender_phone"),
+		SenderAddress:    cell("sender_address"),
+		ReceiverName:     cell("receiver_name"),
+		ReceiverPhone:    cell("receiver_phone"),
+		ReceiverAddress:  cell("receiver_address"),
+		ReceiverProvince: cell("receiver_province"),
+		ReceiverCity:     cell("receiver_city"),
+		ReceiverDistrict: cell("receiver_district"),
+		Weight:           numbers["weight"],
+		Length:           numbers["length"],
+		Width:            numbers["width"],
+		Height:           numbers["height"],
+		Status:           "collected",
+	}, nil
+}
+
+// isBlankRow 是否为空行（全部单元格为空）
+func isBlankRow(values []string) bool {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// fillReceiverCoordinatesConcurrently 并发解析收件地址坐标（地理编码器带缓存，批次内的重复地址多可直接命中）
+func (s *packageService) fillReceiverCoordinatesConcurrently(rows []*PackageImportRow) {
+	jobs := make(chan *model.Package)
+	var wg sync.WaitGroup
+	for i := 0; i < importGeoWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pkg := range jobs {
+				s.fillReceiverCoordinates(pkg)
+			}
+		}()
+	}
+	for _, row := range rows {
+		jobs <- row.pkg
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// assignImportIDs 生成运单号与揽收轨迹ID，同一批次内去重（ID按秒级时间戳加随机串生成，批量时同秒内易重复）
+func (s *packageService) assignImportIDs(rows []*PackageImportRow) {
+	pkgIDs := make(map[string]bool, len(rows))
+	traceIDs := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		for row.pkg.PackageID == "" || pkgIDs[row.pkg.PackageID] {
+			row.pkg.PackageID = s.idGen.GeneratePackageID()
+		}
+		pkgIDs[row.pkg.PackageID] = true
+		for row.traceID == "" || traceIDs[row.traceID] {
+			row.traceID = s.idGen.GenerateTraceID()
+		}
+		traceIDs[row.traceID] = true
+	}
+}
+
+// WriteCSV 输出逐行导入结果：原始列后追加package_id、result（created/failed）、error三列，带UTF-8 BOM便于Excel打开
+func (r *PackageImportResult) WriteCSV(w io.Writer) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	header := append(append([]string{}, r.Header...), "package_id", "result", "error")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range r.Rows {
+		record := make([]string, len(r.Header), len(r.Header)+3)
+		copy(record, row.Values)
+		status := "created"
+		if row.Error != "" {
+			status = "failed"
+		}
+		if err := cw.Write(append(record, row.PackageID, status, row.Error)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// CSV 导入结果CSV内容
+func (r *PackageImportResult) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := r.WriteCSV(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/internal/util"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+// importHeaderRow 导入测试用表头（顺序与大小写与请求体字段不同）
+var importHeaderRow = []string{"Weight", "sender_name", "sender_phone", "sender_address", "receiver_name", "receiver_phone",
+	"receiver_address", "receiver_province", "receiver_city", "receiver_district", "remark"}
+
+// importRow 导入测试用数据行
+func importRow(weight, receiver string) []string {
+	return []string{weight, "商家", "021-5555", "上海市浦东新区张江路1号", receiver, "13800000000",
+		"浙江省杭州市西湖区文三路1号", "浙江省", "杭州市", "西湖区", "忽略的列"}
+}
+
+func newImportService(s *memStore) *packageService {
+	return &packageService{
+		uow:      &memUnitOfWork{s: s},
+		nodeRepo: &memNodeRepo{s: s},
+		geoUtils: util.NewGeoUtils(),
+		idGen:    util.NewIDGenerator(),
+	}
+}
+
+func TestImportPackages(t *testing.T) {
+	s := newMemStore()
+	s.nodes["N001"] = model.Node{NodeID: "N001", Name: "上海分拣中心", Address: "上海市浦东新区", Type: "sorting_center"}
+	rows := [][]string{importHeaderRow, importRow("1.5", "张三"), importRow("abc", "李四"), {"", " "}, importRow("2", "王五")}
+
+	result, err := newImportService(s).ImportPackages(testAdmin, rows, "上海分拣中心", "")
+	if err != nil {
+		t.Fatalf("ImportPackages() error = %v", err)
+	}
+	// 空行忽略，校验失败的行单独记录，其余行写入包裹、揽收轨迹与送达预测
+	if result.Total != 3 || result.Created != 2 || result.Failed != 1 {
+		t.Fatalf("导入结果 总数/成功/失败 = %d/%d/%d, want 3/2/1", result.Total, result.Created, result.Failed)
+	}
+	if failed := result.Rows[1]; failed.Row != 3 || !strings.Contains(failed.Error, "weight须为正数") || failed.PackageID != "" {
+		t.Fatalf("失败行 = 第%d行（%q，运单号%q）, want 第3行weight须为正数", failed.Row, failed.Error, failed.PackageID)
+	}
+	if got := result.Rows[2].Row; got != 5 {
+		t.Fatalf("第三条结果行号 = %d, want 5", got)
+	}
+	if len(s.packages) != 2 || len(s.traces) != 2 || len(s.etas) != 2 {
+		t.Fatalf("包裹/轨迹/预测数 = %d/%d/%d, want 2/2/2", len(s.packages), len(s.traces), len(s.etas))
+	}
+	for _, trace := range s.traces {
+		if trace.NodeType != "collection" || trace.NodeID != "N001" || trace.Operator != testAdmin.OperatorName() {
+			t.Fatalf("揽收轨迹 = %+v, want 登记节点N001的collection轨迹", trace)
+		}
+	}
+	assertPackageStatus(t, s, "collected", result.Rows[0].PackageID, result.Rows[2].PackageID)
+}
+
+func TestImportPackagesDuplicateRetry(t *testing.T) {
+	s := newMemStore()
+	// 批量写入与首行逐条写入均报运单号重复：整批回滚后逐条重试，首行重新生成运单号后写入
+	*s.duplicates = 2
+	rows := [][]string{importHeaderRow, importRow("1", "张三"), importRow("2", "李四")}
+
+	result, err := newImportService(s).ImportPackages(testAdmin, rows, "上海分拣中心", "上海市浦东新区")
+	if err != nil {
+		t.Fatalf("ImportPackages() error = %v", err)
+	}
+	if result.Created != 2 || result.Failed != 0 {
+		t.Fatalf("导入结果 成功/失败 = %d/%d, want 2/0", result.Created, result.Failed)
+	}
+	if len(s.packages) != 2 || len(s.traces) != 2 {
+		t.Fatalf("包裹/轨迹数 = %d/%d, want 2/2", len(s.packages), len(s.traces))
+	}
+	for _, row := range result.Rows {
+		if _, ok := s.packages[row.PackageID]; !ok {
+			t.Fatalf("结果运单号%s未写入", row.PackageID)
+		}
+	}
+}
+
+func TestImportPackagesInvalidInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    [][]string
+		wantErr error
+	}{
+		{"空文件", nil, errno.ErrImportEmpty},
+		{"仅有表头与空行", [][]string{importHeaderRow, {""}}, errno.ErrImportEmpty},
+		{"缺少必填列", [][]string{{"sender_name", "weight"}, {"商家", "1"}}, errno.ErrImportHeaderInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemStore()
+			if _, err := newImportService(s).ImportPackages(testAdmin, tt.rows, "上海分拣中心", ""); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ImportPackages() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(s.packages) != 0 {
+				t.Fatalf("包裹数 = %d, want 0", len(s.packages))
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/internal/repository"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+// PackageSearchReq 包裹检索参数（日期按天，结束日期含当天；翻页时传入上一页返回的next_cursor，其余条件保持不变）
+type PackageSearchReq struct {
+	ReceiverPhone    string    `form:"receiver_phone"`
+	SenderPhone      string    `form:"sender_phone"`
+	ReceiverName     string    `form:"receiver_name"` // 前缀匹配
+	ReceiverCity     string    `form:"receiver_city"`
+	ReceiverDistrict string    `form:"receiver_district"`
+	Status           string    `form:"status"` // 多个状态以逗号分隔
+	CurrentNodeID    string    `form:"current_node_id"`
+	StartDate        time.Time `form:"start_date" time_format:"2006-01-02" time_location:"Local"`
+	EndDate          time.Time `form:"end_date" time_format:"2006-01-02" time_location:"Local"`
+	Sort             string    `form:"sort"`  // created_at（默认）/updated_at
+	Order            string    `form:"order"` // desc（默认）/asc
+	Cursor           string    `form:"cursor"`
+	PageSize         int       `form:"page_size"`
+}
+
+// PackageSearchResult 包裹检索结果：has_more为true时以next_cursor获取下一页
+type PackageSearchResult struct {
+	Packages   []*model.Package
+	PageSize   int
+	HasMore    bool
+	NextCursor string
+}
+
+// SearchPackages 按收/寄件人电话、收件人姓名前缀、收件地区、状态、所在节点、创建日期检索包裹，按游标分页
+func (s *packageService) SearchPackages(req *PackageSearchReq) (*PackageSearchResult, error) {
+	query, err := req.toQuery()
+	if err != nil {
+		return nil, err
+	}
+	// 多取一条判断是否还有下一页
+	pkgs, err := s.pkgRepo.Search(query)
+	if err != nil {
+		return nil, err
+	}
+	result := &PackageSearchResult{Packages: pkgs, PageSize: req.PageSize}
+	if len(pkgs) > req.PageSize {
+		result.Packages, result.HasMore = pkgs[:req.PageSize], true
+		last := result.Packages[req.PageSize-1]
+		sortValue := last.CreatedAt
+		if query.SortField == "updated_at" {
+			sortValue = last.UpdatedAt
+		}
+		result.NextCursor = encodePackageCursor(req.Sort, req.Order, sortValue, last.PackageID)
+	}
+	return result, nil
+}
+
+// toQuery 校验参数、规整排序与每页条数（回写到r）并转换为仓储查询条件
+func (r *PackageSearchReq) toQuery() (repository.PackageSearchQuery, error) {
+	if !r.StartDate.IsZero() && !r.EndDate.IsZero() && r.EndDate.Before(r.StartDate) {
+		return repository.PackageSearchQuery{}, errno.ErrParamInvalid
+	}
+	if r.Sort == "" {
+		r.Sort = "created_at"
+	}
+	if r.Order == "" {
+		r.Order = "desc"
+	}
+	if (r.Sort != "created_at" && r.Sort != "updated_at") || (r.Order != "asc" && r.Order != "desc") {
+		return repository.PackageSearchQuery{}, errno.ErrParamInvalid
+	}
+	page := 1
+	normalizePage(&page, &r.PageSize)
+	query := repository.PackageSearchQuery{
+		ReceiverPhone:    strings.TrimSpace(r.ReceiverPhone),
+		SenderPhone:      strings.TrimSpace(r.SenderPhone),
+		ReceiverName:     strings.TrimSpace(r.ReceiverName),
+		ReceiverCity:     strings.TrimSpace(r.ReceiverCity),
+		ReceiverDistrict: strings.TrimSpace(r.ReceiverDistrict),
+		CurrentNodeID:    strings.TrimSpace(r.CurrentNodeID),
+		StartTime:        r.StartDate,
+		SortField:        r.Sort,
+		Desc:             r.Order == "desc",
+		Limit:            r.PageSize + 1,
+	}
+	for _, status := range strings.Split(r.Status, ",") {
+		if status = strings.TrimSpace(status); status != "" {
+			query.Statuses = append(query.Statuses, status)
+		}
+	}
+	if !r.EndDate.IsZero() {
+		query.EndTime = r.EndDate.AddDate(0, 0, 1)
+	}
+	if r.Cursor != "" {
+		cursor, err := decodePackageCursor(r.Cursor, r.Sort, r.Order)
+		if err != nil {
+			return repository.PackageSearchQuery{}, err
+		}
+		query.After = cursor
+	}
+	return query, nil
+}
+
+// encodePackageCursor 游标编码：排序字段、方向、排序值（纳秒时间戳）与运单号
+func encodePackageCursor(sort, order string, sortValue time.Time, packageID string) string {
+	raw := strings.Join([]string{sort, order, strconv.FormatInt(sortValue.UnixNano(), 10), packageID}, "|")
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodePackageCursor 解析游标，格式错误或排序与本次请求不一致时返回errno.ErrParamInvalid
+func decodePackageCursor(cursor, sort, order string) (*repository.PackageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errno.ErrParamInvalid
+	}
+	parts := strings.SplitN(string(raw), "|", 4)
+	if len(parts) != 4 || parts[0] != sort || parts[1] != order || parts[3] == "" {
+		return nil, errno.ErrParamInvalid
+	}
+	nanos, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, errno.ErrParamInvalid
+	}
+	return &repository.PackageCursor{SortValue: time.Unix(0, nanos), PackageID: parts[3]}, nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+func TestPackageCursorRoundTrip(t *testing.T) {
+	value := time.Date(2024, 5, 1, 8, 30, 15, 123456789, time.Local)
+	tests := []struct {
+		sort, order string
+		packageID   string
+	}{
+		{"created_at", "desc", "SF1714552215ABC"},
+		{"updated_at", "asc", "SF1714552215XYZ"},
+		{"created_at", "asc", "ID|含分隔符"},
+	}
+	for _, tt := range tests {
+		cursor := encodePackageCursor(tt.sort, tt.order, value, tt.packageID)
+		got, err := decodePackageCursor(cursor, tt.sort, tt.order)
+		if err != nil {
+			t.Fatalf("decodePackageCursor(%q) error = %v", cursor, err)
+		}
+		if !got.SortValue.Equal(value) || got.PackageID != tt.packageID {
+			t.Fatalf("decodePackageCursor() = %v/%s, want %v/%s", got.SortValue, got.PackageID, value, tt.packageID)
+		}
+	}
+}
+
+func TestDecodePackageCursorInvalid(t *testing.T) {
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	valid := encodePackageCursor("created_at", "desc", time.Now(), "SF001")
+	tests := []struct {
+		name        string
+		cursor      string
+		sort, order string
+	}{
+		{"非base64", "!!!", "created_at", "desc"},
+		{"字段不足", raw("created_at|desc|1714552215"), "created_at", "desc"},
+		{"排序字段不一致", valid, "updated_at", "desc"},
+		{"排序方向不一致", valid, "created_at", "asc"},
+		{"时间戳无效", raw("created_at|desc|abc|SF001"), "created_at", "desc"},
+		{"运单号为空", raw("created_at|desc|1714552215|"), "created_at", "desc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodePackageCursor(tt.cursor, tt.sort, tt.order); !errors.Is(err, errno.ErrParamInvalid) {
+				t.Fatalf("decodePackageCursor(%q) error = %v, want %v", tt.cursor, err, errno.ErrParamInvalid)
+			}
+		})
+	}
+}
+
+func TestPackageSearchReqToQuery(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 0, 0, 0, 0, time.Local) }
+	cursorValue := day(3).Add(90 * time.Minute)
+	tests := []struct {
+		name         string
+		req          PackageSearchReq
+		wantErr      error
+		wantSort     string
+		wantDesc     bool
+		wantLimit    int
+		wantStatuses []string
+		wantEnd      time.Time
+		wantAfter    string // 游标中的运单号，空为首页
+	}{
+		{"默认按创建时间降序每页20条", PackageSearchReq{}, nil, "created_at", true, 21, nil, time.Time{}, ""},
+		{"每页条数上限100", PackageSearchReq{Sort: "updated_at", Order: "asc", PageSize: 500}, nil, "updated_at", false, 101, nil, time.Time{}, ""},
+		{"多状态与结束日期含当天", PackageSearchReq{Status: "sorted, arrived,,", StartDate: day(1), EndDate: day(2), PageSize: 5},
+			nil, "created_at", true, 6, []string{"sorted", "arrived"}, day(3), ""},
+		{"携带游标", PackageSearchReq{Cursor: encodePackageCursor("created_at", "desc", cursorValue, "SF009")},
+			nil, "created_at", true, 21, nil, time.Time{}, "SF009"},
+		{"结束日期早于开始日期", PackageSearchReq{StartDate: day(2), EndDate: day(1)}, errno.ErrParamInvalid, "", false, 0, nil, time.Time{}, ""},
+		{"排序字段不合法", PackageSearchReq{Sort: "weight"}, errno.ErrParamInvalid, "", false, 0, nil, time.Time{}, ""},
+		{"排序方向不合法", PackageSearchReq{Order: "random"}, errno.ErrParamInvalid, "", false, 0, nil, time.Time{}, ""},
+		{"游标与排序不一致", PackageSearchReq{Sort: "updated_at", Cursor: encodePackageCursor("created_at", "desc", cursorValue, "SF009")},
+			errno.ErrParamInvalid, "", false, 0, nil, time.Time{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := tt.req.toQuery()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("toQuery() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if query.SortField != tt.wantSort || query.Desc != tt.wantDesc || query.Limit != tt.wantLimit {
+				t.Fatalf("sort/desc/limit = %s/%v/%d, want %s/%v/%d", query.SortField, query.Desc, query.Limit,
+					tt.wantSort, tt.wantDesc, tt.wantLimit)
+			}
+			if !reflect.DeepEqual(query.Statuses, tt.wantStatuses) || !query.EndTime.Equal(tt.wantEnd) {
+				t.Fatalf("statuses/end = %v/%v, want %v/%v", query.Statuses, query.EndTime, tt.wantStatuses, tt.wantEnd)
+			}
+			switch {
+			case tt.wantAfter == "" && query.After != nil:
+				t.Fatalf("首页不应带游标，got %+v", query.After)
+			case tt.wantAfter != "" && (query.After == nil || query.After.PackageID != tt.wantAfter || !query.After.SortValue.Equal(cursorValue)):
+				t.Fatalf("After = %+v, want %s@%v", query.After, tt.wantAfter, cursorValue)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"time"
+
+	"github.com/LFrankl/fdu-lab3/internal/repository"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+// 任务列表分页默认值
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// TaskListReq 任务列表查询参数（日期按天，结束日期含当天）
+type TaskListReq struct {
+	Status    string    `form:"status"`
+	StartDate time.Time `form:"start_date" time_format:"2006-01-02" time_location:"Local"`
+	EndDate   time.Time `form:"end_date" time_format:"2006-01-02" time_location:"Local"`
+	Page      int       `form:"page"`
+	PageSize  int       `form:"page_size"`
+}
+
+// toQuery 校验参数、规整分页（回写到r）并转换为仓储查询条件
+func (r *TaskListReq) toQuery() (repository.TaskListQuery, error) {
+	if !r.StartDate.IsZero() && !r.EndDate.IsZero() && r.EndDate.Before(r.StartDate) {
+		return repository.TaskListQuery{}, errno.ErrParamInvalid
+	}
+	normalizePage(&r.Page, &r.PageSize)
+	query := repository.TaskListQuery{
+		Status:    r.Status,
+		StartTime: r.StartDate,
+		Page:      r.Page,
+		PageSize:  r.PageSize,
+	}
+	if !r.EndDate.IsZero() {
+		query.EndTime = r.EndDate.AddDate(0, 0, 1)
+	}
+	return query, nil
+}
+
+// normalizePage 规整分页参数：页码从1开始，每页条数缺省取默认值且不超过上限
+func normalizePage(page, pageSize *int) {
+	if *page < 1 {
+		*page = 1
+	}
+	if *pageSize < 1 {
+		*pageSize = defaultPageSize
+	}
+	if *pageSize > maxPageSize {
+		*pageSize = maxPageSize
+	}
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/LFrankl/fdu-lab3/internal/auth"
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/internal/repository"
+	"github.com/LFrankl/fdu-lab3/internal/routing"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+// 停靠点状态
+const (
+	stopSigned  = "signed"  // 已签收，保持原顺序
+	stopPending = "pending" // 待派送
+	stopSkipped = "skipped" // 本次跳过，排在最后
+)
+
+// StopSequence 派送任务停靠顺序
+type StopSequence struct {
+	TaskID     string           `json:"task_id"`
+	DistanceKm float64          `json:"distance_km"` // 待派送停靠点的估算直线里程（不含无坐标停靠点）
+	Stops      []*SequencedStop `json:"stops"`
+}
+
+// SequencedStop 停靠点（同一收件地址的包裹共用一个派送顺序号）
+type SequencedStop struct {
+	Order      int      `json:"order"`
+	Address    string   `json:"address"`
+	Longitude  float64  `json:"longitude"`
+	Latitude   float64  `json:"latitude"`
+	Status     string   `json:"status"`
+	PackageIDs []string `json:"package_ids"`
+}
+
+// ResequenceTask 重新规划派送顺序（派送员跳过停靠点后调用）：已签收包裹保持原顺序，
+// 其余停靠点从最近签收点（尚未签收时为派送网点）出发重新排序，跳过的包裹排在最后
+func (s *DeliverySvc) ResequenceTask(caller *auth.Identity, taskID string, skippedIDs []string) (*StopSequence, error) {
+	var seq *StopSequence
+	err := s.uow.Transaction(func(repos *repository.Repositories) error {
+		task, err := repos.Delivery.GetTaskByID(taskID)
+		if err != nil {
+			return err
+		}
+		if err := checkCourierAccess(caller, task); err != nil {
+			return err
+		}
+		if task.Status != "pending" && task.Status != "delivering" {
+			return errno.ErrDeliveryTaskNotSequenceable
+		}
+		skipped := make(map[string]bool, len(skippedIDs))
+		for _, id := range skippedIDs {
+			skipped[id] = true
+		}
+		seq, err = sequenceDeliveryTask(repos, task, skipped)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return seq, nil
+}
+
+// sequenceDeliveryTask 优化派送任务停靠顺序并写回DeliveryOrder（仅使用包裹已存储的收件坐标，不发起地址解析）
+func sequenceDeliveryTask(repos *repository.Repositories, task *model.DeliveryTask, skipped map[string]bool) (*StopSequence, error) {
+	dtps, err := repos.Delivery.ListTaskPackages(task.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	bound := make(map[string]bool, len(dtps))
+	for _, dtp := range dtps {
+		bound[dtp.PackageID] = true
+	}
+	for id := range skipped {
+		if !bound[id] {
+			return nil, errno.ErrPackageNotBindToDeliveryTask
+		}
+	}
+
+	seq := &StopSequence{TaskID: task.TaskID, Stops: []*SequencedStop{}}
+	start, hasStart := taskStartPoint(repos, task)
+	lastOrder := 0
+	var pendingStops, skippedStops []*SequencedStop
+	pendingIndex := make(map[string]*SequencedStop)
+	skippedIndex := make(map[string]*SequencedStop)
+	var lastSigned *model.DeliveryTaskPackage
+	for _, dtp := range dtps {
+		pkg, err := repos.Package.GetByID(dtp.PackageID)
+		if err != nil {
+			return nil, err
+		}
+		// 已签收包裹保持原顺序，最近签收点作为重新规划的起点
+		if !dtp.SignInfo.SignTime.IsZero() {
+			seq.Stops = appendSignedStop(seq.Stops, dtp, pkg)
+			if dtp.DeliveryOrder > lastOrder {
+				lastOrder = dtp.DeliveryOrder
+			}
+			if lastSigned == nil || dtp.SignInfo.SignTime.After(lastSigned.SignInfo.SignTime) {
+				lastSigned = dtp
+				if pkg.ReceiverLongitude != 0 || pkg.ReceiverLatitude != 0 {
+					start, hasStart = routing.Stop{Longitude: pkg.ReceiverLongitude, Latitude: pkg.ReceiverLatitude}, true
+				}
+			}
+			continue
+		}
+		// 未签收包裹按收件地址合并为停靠点
+		stops, index := &pendingStops, pendingIndex
+		status := stopPending
+		if skipped[pkg.PackageID] {
+			stops, index, status = &skippedStops, skippedIndex, stopSkipped
+		}
+		key := stopKey(pkg.ReceiverAddress)
+		stop, ok := index[key]
+		if !ok {
+			stop = &SequencedStop{
+				Address:   pkg.ReceiverAddress,
+				Longitude: pkg.ReceiverLongitude,
+				Latitude:  pkg.ReceiverLatitude,
+				Status:    status,
+			}
+			index[key] = stop
+			*stops = append(*stops, stop)
+		}
+		stop.PackageIDs = append(stop.PackageIDs, pkg.PackageID)
+	}
+
+	// 待派送停靠点优化排序，其后为跳过的停靠点（二者内部均为无坐标停靠点排在最后）
+	orders := make(map[string]int)
+	for i, group := range [][]*SequencedStop{pendingStops, skippedStops} {
+		ordered, distance := orderStops(start, hasStart, group)
+		if i == 0 {
+			seq.DistanceKm = distance
+		}
+		for _, stop := range ordered {
+			lastOrder++
+			stop.Order = lastOrder
+			for _, id := range stop.PackageIDs {
+				orders[id] = lastOrder
+			}
+			seq.Stops = append(seq.Stops, stop)
+		}
+	}
+	if err := repos.Delivery.UpdateDeliveryOrders(task.TaskID, orders); err != nil {
+		return nil, err
+	}
+	return seq, nil
+}
+
+// appendDeliveryStops 追加绑定后为新包裹安排派送顺序：与已有停靠点同址的沿用其顺序号，
+// 其余从最后一个停靠点出发优化排序后接在末尾，已有包裹的顺序不变
+func appendDeliveryStops(repos *repository.Repositories, task *model.DeliveryTask, newIDs []string) error {
+	dtps, err := repos.Delivery.ListTaskPackages(task.TaskID)
+	if err != nil {
+		return err
+	}
+	isNew := make(map[string]bool, len(newIDs))
+	for _, id := range newIDs {
+		isNew[id] = true
+	}
+	start, hasStart := taskStartPoint(repos, task)
+	existing := make(map[string]int)
+	lastOrder := 0
+	var newPkgs []*model.Package
+	for _, dtp := range dtps {
+		pkg, err := repos.Package.GetByID(dtp.PackageID)
+		if err != nil {
+			return err
+		}
+		if isNew[dtp.PackageID] {
+			newPkgs = append(newPkgs, pkg)
+			continue
+		}
+		existing[stopKey(pkg.ReceiverAddress)] = dtp.DeliveryOrder
+		// 以顺序最靠后的已有停靠点作为新停靠点的起点
+		if dtp.DeliveryOrder >= lastOrder {
+			lastOrder = dtp.DeliveryOrder
+			if pkg.ReceiverLongitude != 0 || pkg.ReceiverLatitude != 0 {
+				start, hasStart = routing.Stop{Longitude: pkg.ReceiverLongitude, Latitude: pkg.ReceiverLatitude}, true
+			}
+		}
+	}
+
+	orders := make(map[string]int, len(newPkgs))
+	var stops []*SequencedStop
+	index := make(map[string]*SequencedStop)
+	for _, pkg := range newPkgs {
+		key := stopKey(pkg.ReceiverAddress)
+		if order, ok := existing[key]; ok {
+			orders[pkg.PackageID] = order
+			continue
+		}
+		stop, ok := index[key]
+		if !ok {
+			stop = &SequencedStop{Address: pkg.ReceiverAddress, Longitude: pkg.ReceiverLongitude, Latitude: pkg.ReceiverLatitude, Status: stopPending}
+			index[key] = stop
+			stops = append(stops, stop)
+		}
+		stop.PackageIDs = append(stop.PackageIDs, pkg.PackageID)
+	}
+	ordered, _ := orderStops(start, hasStart, stops)
+	for _, stop := range ordered {
+		lastOrder++
+		for _, id := range stop.PackageIDs {
+			orders[id] = lastOrder
+		}
+	}
+	return repos.Delivery.UpdateDeliveryOrders(task.TaskID, orders)
+}
+
+// orderStops 对有坐标的停靠点按路线优化排序，无坐标停靠点保持原顺序排在最后；返回排序结果与估算里程
+func orderStops(start routing.Stop, hasStart bool, stops []*SequencedStop) ([]*SequencedStop, float64) {
+	var located, unlocated []*SequencedStop
+	for _, stop := range stops {
+		if stop.Longitude == 0 && stop.Latitude == 0 {
+			unlocated = append(unlocated, stop)
+		} else {
+			located = append(located, stop)
+		}
+	}
+	if len(located) == 0 {
+		return unlocated, 0
+	}
+	points := make([]routing.Stop, len(located))
+	for i, stop := range located {
+		points[i] = routing.Stop{Key: stop.Address, Longitude: stop.Longitude, Latitude: stop.Latitude}
+	}
+	// 起点无坐标时从第一个停靠点出发
+	if !hasStart {
+		start = points[0]
+	}
+	order := routing.SequenceStops(start, points)
+	result := make([]*SequencedStop, 0, len(stops))
+	for _, idx := range order {
+		result = append(result, located[idx])
+	}
+	return append(result, unlocated...), routing.PathLength(start, points, order)
+}
+
+// taskStartPoint 派送任务起点坐标（派送网点登记坐标）
+func taskStartPoint(repos *repository.Repositories, task *model.DeliveryTask) (routing.Stop, bool) {
+	if task.StartNodeID == "" {
+		return routing.Stop{}, false
+	}
+	node, err := repos.Node.GetByID(task.StartNodeID)
+	if err != nil || (node.Longitude == 0 && node.Latitude == 0) {
+		return routing.Stop{}, false
+	}
+	return routing.Stop{Key: node.Name, Longitude: node.Longitude, Latitude: node.Latitude}, true
+}
+
+// appendSignedStop 已签收包裹按原派送顺序号合并为停靠点
+func appendSignedStop(stops []*SequencedStop, dtp *model.DeliveryTaskPackage, pkg *model.Package) []*SequencedStop {
+	for _, stop := range stops {
+		if stop.Order == dtp.DeliveryOrder {
+			stop.PackageIDs = append(stop.PackageIDs, pkg.PackageID)
+			return stops
+		}
+	}
+	return append(stops, &SequencedStop{
+		Order:      dtp.DeliveryOrder,
+		Address:    pkg.ReceiverAddress,
+		Longitude:  pkg.ReceiverLongitude,
+		Latitude:   pkg.ReceiverLatitude,
+		Status:     stopSigned,
+		PackageIDs: []string{pkg.PackageID},
+	})
+}
+
+// stopKey 收件地址归一化（去除空白）作为停靠点合并键
+func stopKey(address string) string {
+	return strings.Join(strings.Fields(address), "")
+}
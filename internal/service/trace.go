@@ -0,0 +1,178 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/internal/repository"
+)
+
+// traceNode 轨迹节点：名称、地址与坐标（坐标未知时为0）
+type traceNode struct {
+	ID        string // 网络节点ID，非登记节点为空
+	Name      string
+	Address   string
+	Longitude float64
+	Latitude  float64
+}
+
+// routePoint 运输路线节点（与TransportRoute.RouteJSON元素结构一致）
+type routePoint struct {
+	Name      string  `json:"name"`
+	Address   string  `json:"address"`
+	Longitude float64 `json:"longitude"`
+	Latitude  float64 `json:"latitude"`
+}
+
+// transportTraceNode 运输任务出发/到达节点：名称取自任务，地址与坐标取自路线首/末节点（路线缺失时为空）
+func transportTraceNode(task *model.TransportTask, arrival bool) traceNode {
+	node := traceNode{ID: task.StartNodeID, Name: task.StartNode}
+	if arrival {
+		node.ID, node.Name = task.EndNodeID, task.EndNode
+	}
+	var points []routePoint
+	if task.Route.RouteJSON == "" || json.Unmarshal([]byte(task.Route.RouteJSON), &points) != nil || len(points) == 0 {
+		return node
+	}
+	p := points[0]
+	if arrival {
+		p = points[len(points)-1]
+	}
+	node.Address, node.Longitude, node.Latitude = p.Address, p.Longitude, p.Latitude
+	return node
+}
+
+// traceRemarkMaxLen 轨迹备注列长度（按字符计）
+const traceRemarkMaxLen = 255
+
+// truncateRemark 将拼接了用户输入的备注截断至轨迹备注列长度以内（按字符截断，不切断多字节字符）
+func truncateRemark(remark string) string {
+	runes := []rune(remark)
+	if len(runes) <= traceRemarkMaxLen {
+		return remark
+	}
+	return string(runes[:traceRemarkMaxLen-1]) + "…"
+}
+
+// writeTransportTraces 运输任务状态变更后为每个绑定包裹写入轨迹，note为附加说明（如异常处理结果）
+func writeTransportTraces(repos *repository.Repositories, task *model.TransportTask, operator, note string) error {
+	var (
+		nodeType = task.Status
+		node     traceNode
+		remark   string
+	)
+	switch task.Status {
+	case "transporting":
+		node = transportTraceNode(task, false)
+		remark = fmt.Sprintf("包裹已装车，从%s发往%s（车辆%s）", task.StartNode, task.EndNode, task.VehicleID)
+	case "arrived":
+		node = transportTraceNode(task, true)
+		remark = fmt.Sprintf("包裹到达%s", task.EndNode)
+	case "completed":
+		nodeType = "transport_completed"
+		node = transportTraceNode(task, true)
+		remark = fmt.Sprintf("运输任务已完成，包裹位于%s", task.EndNode)
+	default:
+		nodeType = "transport_" + task.Status
+		node = transportTraceNode(task, false)
+		remark = fmt.Sprintf("运输任务状态变更为%s", task.Status)
+	}
+	remark = fmt.Sprintf("%s，运输任务%s", remark, task.TaskID)
+	if note != "" {
+		remark += "，" + note
+	}
+	pkgIDs, err := repos.Transport.GetPackageIDsByTaskID(task.TaskID)
+	if err != nil {
+		return err
+	}
+	return writePackageTraces(repos.Package, pkgIDs, nodeType, node, operator, remark)
+}
+
+// writeDeliveryTraces 派送任务状态变更后为每个绑定包裹写入轨迹（开始派送时已签收包裹跳过），note为附加说明
+func writeDeliveryTraces(repos *repository.Repositories, task *model.DeliveryTask, operator, note string) error {
+	node := deliveryTraceNode(repos, task)
+	nodeType := "delivery_" + task.Status
+	var remark string
+	switch task.Status {
+	case "delivering":
+		nodeType = "delivering"
+		remark = fmt.Sprintf("包裹已出库，正在派送中（派送员%s）", task.CourierName)
+	case "completed":
+		remark = "派送任务已完成"
+	default:
+		remark = fmt.Sprintf("派送任务状态变更为%s", task.Status)
+	}
+	remark = fmt.Sprintf("%s，派送任务%s", remark, task.TaskID)
+	if note != "" {
+		remark += "，" + note
+	}
+	pkgIDs, err := repos.Delivery.GetPackageIDsByTaskID(task.TaskID)
+	if err != nil {
+		return err
+	}
+	if task.Status == "delivering" {
+		pending := make([]string, 0, len(pkgIDs))
+		for _, pkgID := range pkgIDs {
+			pkg, err := repos.Package.GetByID(pkgID)
+			if err != nil {
+				return err
+			}
+			if pkg.Status != "delivered" {
+				pending = append(pending, pkgID)
+			}
+		}
+		pkgIDs = pending
+	}
+	return writePackageTraces(repos.Package, pkgIDs, nodeType, node, operator, remark)
+}
+
+// registeredTraceNode 按名称匹配登记节点，匹配成功时引用节点ID并使用节点的规范名称、地址与坐标
+func registeredTraceNode(repo repository.NodeRepo, name string) (traceNode, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return traceNode{}, false
+	}
+	n, err := repo.GetByName(name)
+	if err != nil {
+		return traceNode{}, false
+	}
+	return traceNode{ID: n.NodeID, Name: n.Name, Address: n.Address, Longitude: n.Longitude, Latitude: n.Latitude}, true
+}
+
+// deliveryTraceNode 派送任务轨迹节点：派送网点，地址与坐标取登记信息
+func deliveryTraceNode(repos *repository.Repositories, task *model.DeliveryTask) traceNode {
+	node := traceNode{ID: task.StartNodeID, Name: task.StartNode}
+	if task.StartNodeID != "" {
+		if n, err := repos.Node.GetByID(task.StartNodeID); err == nil {
+			node.Address, node.Longitude, node.Latitude = n.Address, n.Longitude, n.Latitude
+		}
+	}
+	return node
+}
+
+// writePackageTraces 为一批包裹写入同一节点的轨迹（备注超长时截断）
+func writePackageTraces(repo repository.PackageRepository, packageIDs []string, nodeType string, node traceNode, operator, remark string) error {
+	remark = truncateRemark(remark)
+	now := time.Now()
+	for _, pkgID := range packageIDs {
+		trace := &model.PackageTrace{
+			PackageID:     pkgID,
+			NodeType:      nodeType,
+			NodeID:        node.ID,
+			NodeName:      node.Name,
+			NodeAddress:   node.Address,
+			Longitude:     node.Longitude,
+			Latitude:      node.Latitude,
+			OperationTime: now,
+			Operator:      operator,
+			Remark:        remark,
+		}
+		if err := repo.CreateTrace(trace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateRemark(t *testing.T) {
+	tests := []struct {
+		name   string
+		remark string
+		want   int // 截断后的字符数
+	}{
+		{"未超长原样保留", strings.Repeat("异", traceRemarkMaxLen), traceRemarkMaxLen},
+		{"超长按字符截断", strings.Repeat("异", traceRemarkMaxLen+1), traceRemarkMaxLen},
+		{"混合字符不切断多字节", strings.Repeat("a异", traceRemarkMaxLen), traceRemarkMaxLen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateRemark(tt.remark)
+			if !utf8.ValidString(got) || utf8.RuneCountInString(got) != tt.want {
+				t.Fatalf("truncateRemark() 字符数 = %d（有效UTF-8：%v）, want %d", utf8.RuneCountInString(got), utf8.ValidString(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestReportAbnormalLongReason(t *testing.T) {
+	s := newMemStore()
+	s.addPackages("transporting", "P1")
+	s.addTransportTask("T1", "transporting", "P1")
+	reason := strings.Repeat("异", 255)
+	if err := (&TransportSvc{uow: &memUnitOfWork{s: s}}).ReportTransportAbnormal(testAdmin, "T1", "delay", reason); err != nil {
+		t.Fatalf("ReportTransportAbnormal() error = %v", err)
+	}
+	// 异常原因完整保存在记录中，拼接后的轨迹备注截断至列长度以内
+	for _, record := range s.records {
+		if record.AbnormalReason != reason {
+			t.Fatalf("异常记录原因被修改")
+		}
+	}
+	for _, trace := range s.traces {
+		if n := utf8.RuneCountInString(trace.Remark); n > traceRemarkMaxLen {
+			t.Fatalf("轨迹备注字符数 = %d, want <= %d", n, traceRemarkMaxLen)
+		}
+	}
+}
+
+func TestHandleAbnormalLongResult(t *testing.T) {
+	s := newMemStore()
+	s.addPackages("transporting", "P1")
+	s.addTransportTask("T1", "transporting", "P1")
+	svc := &TransportSvc{uow: &memUnitOfWork{s: s}}
+	if err := svc.ReportTransportAbnormal(testAdmin, "T1", "delay", "拥堵"); err != nil {
+		t.Fatalf("ReportTransportAbnormal() error = %v", err)
+	}
+	if err := svc.HandleTransportAbnormal(testAdmin, "T1", strings.Repeat("处", 512), "transporting"); err != nil {
+		t.Fatalf("HandleTransportAbnormal() error = %v", err)
+	}
+	last := s.traces[len(s.traces)-1]
+	if !strings.Contains(last.Remark, "异常已处理") {
+		t.Fatalf("最后一条轨迹备注 = %q, want 异常处理轨迹", last.Remark)
+	}
+	if n := utf8.RuneCountInString(last.Remark); n > traceRemarkMaxLen {
+		t.Fatalf("轨迹备注字符数 = %d, want <= %d", n, traceRemarkMaxLen)
+	}
+}
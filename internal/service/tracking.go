@@ -0,0 +1,192 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+// PublicTracking 面向寄/收件人的公开查件结果：姓名与手机号脱敏，不含详细地址、操作人及内部备注
+type PublicTracking struct {
+	PackageID     string               `json:"package_id"`
+	Status        string               `json:"status"`
+	Sender        PublicContact        `json:"sender"`
+	Receiver      PublicContact        `json:"receiver"`
+	Destination   string               `json:"destination"` // 收件省市区
+	NextNode      string               `json:"next_node"`
+	EstimatedTime string               `json:"estimated_arrival_time"`
+	WindowStart   string               `json:"window_start,omitempty"`
+	WindowEnd     string               `json:"window_end,omitempty"`
+	Itinerary     []*PublicTrackingHop `json:"itinerary"`
+	Traces        []*PublicTraceEvent  `json:"traces"`
+}
+
+// PublicContact 脱敏后的联系人
+type PublicContact struct {
+	Name  string `json:"name"`
+	Phone string `json:"phone"`
+}
+
+// PublicTrackingHop 公开行程节点（仅登记的网络节点显示名称）
+type PublicTrackingHop struct {
+	NodeName string `json:"node_name"`
+	Status   string `json:"status"` // done/current/upcoming
+}
+
+// PublicTraceEvent 公开轨迹（描述按轨迹类型生成，不含操作人与内部备注）
+type PublicTraceEvent struct {
+	Time        string `json:"time"`
+	Location    string `json:"location,omitempty"`
+	Description string `json:"description"`
+}
+
+// publicTraceText 轨迹类型对应的公开描述
+var publicTraceText = map[string]string{
+	"collection":          "快件已揽收",
+	"manual_correction":   "快件状态已更新",
+	"transporting":        "快件已发出，运输中",
+	"arrived":             "快件已到达",
+	"transport_completed": "快件已到达",
+	"transport_abnormal":  "快件运输异常，正在处理",
+	"transport_unbound":   "快件等待重新发运",
+	"transport_transfer":  "快件已转运",
+	"delivering":          "快件正在派送中",
+	"delivery_completed":  "快件派送已完成",
+	"delivery_abnormal":   "快件派送异常，正在处理",
+	"delivery_unbound":    "快件已退回网点，等待重新派送",
+	"delivered":           "快件已签收",
+	"abnormal":            "快件处理异常，正在处理",
+	"abnormal_resolved":   "快件异常已处理",
+}
+
+// PublicTracking 公开查件：须提供收件人或寄件人手机号后4位，运单不存在与校验失败返回同一错误
+func (s *packageService) PublicTracking(packageID, phoneSuffix string) (*PublicTracking, error) {
+	if len(phoneSuffix) != 4 || strings.IndexFunc(phoneSuffix, func(r rune) bool { return !unicode.IsDigit(r) }) >= 0 {
+		return nil, errno.ErrTrackingPhoneInvalid
+	}
+	pkg, err := s.pkgRepo.GetByID(strings.TrimSpace(packageID))
+	if errors.Is(err, errno.ErrPackageNotFound) {
+		return nil, errno.ErrTrackingVerifyFailed
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !phoneSuffixMatches(pkg.ReceiverPhone, phoneSuffix) && !phoneSuffixMatches(pkg.SenderPhone, phoneSuffix) {
+		return nil, errno.ErrTrackingVerifyFailed
+	}
+
+	traces, err := s.pkgRepo.GetTracesByPackageID(pkg.PackageID)
+	if err != nil {
+		return nil, err
+	}
+	itinerary, nextNode, err := s.packageItinerary(pkg, traces)
+	if err != nil {
+		return nil, err
+	}
+	result := &PublicTracking{
+		PackageID:   pkg.PackageID,
+		Status:      pkg.Status,
+		Sender:      PublicContact{Name: maskName(pkg.SenderName), Phone: maskPhone(pkg.SenderPhone)},
+		Receiver:    PublicContact{Name: maskName(pkg.ReceiverName), Phone: maskPhone(pkg.ReceiverPhone)},
+		Destination: pkg.ReceiverProvince + pkg.ReceiverCity + pkg.ReceiverDistrict,
+		NextNode:    publicNodeName(nextNode, pkg),
+		Itinerary:   make([]*PublicTrackingHop, 0, len(itinerary)),
+		Traces:      make([]*PublicTraceEvent, 0, len(traces)),
+	}
+	for _, hop := range itinerary {
+		result.Itinerary = append(result.Itinerary, &PublicTrackingHop{NodeName: publicHopName(hop), Status: hop.Status})
+	}
+	// 轨迹按时间倒序，最新在前
+	for i := len(traces) - 1; i >= 0; i-- {
+		result.Traces = append(result.Traces, publicTraceEvent(&traces[i]))
+	}
+	eta, err := s.latestETA(pkg)
+	if err != nil {
+		return nil, err
+	}
+	if eta != nil && pkg.Status != "delivered" {
+		result.EstimatedTime = eta.EstimatedTime.Format("2006-01-02 15:04")
+		result.WindowStart = eta.WindowStart.Format("2006-01-02 15:04")
+		result.WindowEnd = eta.WindowEnd.Format("2006-01-02 15:04")
+	}
+	return result, nil
+}
+
+// phoneSuffixMatches 手机号（仅取数字）后4位是否与输入一致（恒定时间比较）
+func phoneSuffixMatches(phone, suffix string) bool {
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, phone)
+	if len(digits) < 4 {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(digits[len(digits)-4:]), []byte(suffix)) == 1
+}
+
+// maskName 姓名脱敏：保留首字，其余以*代替（单字姓名整体隐藏）
+func maskName(name string) string {
+	runes := []rune(strings.TrimSpace(name))
+	switch len(runes) {
+	case 0:
+		return ""
+	case 1:
+		return "*"
+	}
+	return string(runes[0]) + strings.Repeat("*", len(runes)-1)
+}
+
+// maskPhone 手机号脱敏：11位手机号保留前3后4位，其他号码仅保留后4位
+func maskPhone(phone string) string {
+	phone = strings.TrimSpace(phone)
+	if len(phone) == 11 {
+		return phone[:3] + "****" + phone[7:]
+	}
+	if len(phone) <= 4 {
+		return strings.Repeat("*", len(phone))
+	}
+	return strings.Repeat("*", len(phone)-4) + phone[len(phone)-4:]
+}
+
+// publicHopName 行程节点公开名称：收件地址与未登记节点（如揽收点）不显示具体地址
+func publicHopName(hop *ItineraryHop) string {
+	switch {
+	case hop.NodeType == "receiver":
+		return "收件地址"
+	case hop.NodeType == "collection" && hop.NodeID == "":
+		return "揽收点"
+	}
+	return hop.NodeName
+}
+
+// publicNodeName 下一节点公开名称：收件地址不显示具体地址
+func publicNodeName(name string, pkg *model.Package) string {
+	if name == "" {
+		return "暂无后续节点"
+	}
+	if name == pkg.ReceiverAddress {
+		return "收件地址"
+	}
+	return name
+}
+
+// publicTraceEvent 公开轨迹：仅登记的网络节点显示位置，描述按轨迹类型生成
+func publicTraceEvent(t *model.PackageTrace) *PublicTraceEvent {
+	event := &PublicTraceEvent{
+		Time:        t.OperationTime.Format("2006-01-02 15:04:05"),
+		Description: publicTraceText[t.NodeType],
+	}
+	if event.Description == "" {
+		event.Description = "快件处理中"
+	}
+	if t.NodeID != "" {
+		event.Location = t.NodeName
+	}
+	return event
+}
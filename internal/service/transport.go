@@ -5,36 +5,66 @@ import (
 	"math/rand"
 	"time"
 
+	"github.com/LFrankl/fdu-lab3/internal/auth"
 	"github.com/LFrankl/fdu-lab3/internal/model"
 	"github.com/LFrankl/fdu-lab3/internal/repository"
+	"github.com/LFrankl/fdu-lab3/internal/routing"
+	"github.com/LFrankl/fdu-lab3/pkg/db"
 	"github.com/LFrankl/fdu-lab3/pkg/errno"
 )
 
 // TransportSvc 运输领域核心业务服务
 type TransportSvc struct {
+	uow           repository.UnitOfWork // 跨仓储写操作的事务边界
 	transportRepo repository.TransportRepo
 	packageRepo   repository.PackageRepository // 依赖包裹领域Repo（交互用）
+	nodeRepo      repository.NodeRepo          // 依赖网络节点Repo（解析起止节点）
+	vehicleRepo   repository.VehicleRepo       // 依赖车辆Repo（校验车辆与装载）
+	graph         *routing.Graph               // 路网（路径规划）
 }
 
 func NewTransportSvc() *TransportSvc {
 	return &TransportSvc{
-		transportRepo: repository.NewTransportRepo(),
-		packageRepo:   repository.NewPackageRepository(),
+		uow:           repository.NewUnitOfWork(db.DB),
+		transportRepo: repository.NewTransportRepo(db.DB),
+		packageRepo:   repository.NewPackageRepository(db.DB),
+		nodeRepo:      repository.NewNodeRepo(db.DB),
+		vehicleRepo:   repository.NewVehicleRepo(db.DB),
+		graph:         routing.Default(),
 	}
 }
 
 // CreateTransportTask 创建运输任务（含基础校验）
 func (s *TransportSvc) CreateTransportTask(req *CreateTransportTaskReq) (*model.TransportTask, error) {
 	// 1. 校验必填参数
-	if req.StartNode == "" || req.EndNode == "" || req.VehicleID == "" {
+	if req.VehicleID == "" {
 		return nil, errno.ErrParamInvalid
 	}
+	// 起止节点按ID或名称解析为登记节点，任务同时记录节点ID与规范名称
+	startNode, err := resolveNode(s.nodeRepo, req.StartNodeID, req.StartNode)
+	if err != nil {
+		return nil, err
+	}
+	endNode, err := resolveNode(s.nodeRepo, req.EndNodeID, req.EndNode)
+	if err != nil {
+		return nil, err
+	}
+	// 车辆须已登记且可用
+	vehicle, err := s.vehicleRepo.GetByID(req.VehicleID)
+	if err != nil {
+		return nil, err
+	}
+	if vehicle.Status != "active" {
+		return nil, errno.ErrVehicleUnavailable
+	}
 
 	// 2. 构建运输任务模型
 	task := &model.TransportTask{
 		TaskID:        genTaskID(), // 生成唯一任务ID（需实现ID生成逻辑）
-		StartNode:     req.StartNode,
-		EndNode:       req.EndNode,
+		StartNodeID:   startNode.NodeID,
+		StartNode:     startNode.Name,
+		EndNodeID:     endNode.NodeID,
+		EndNode:       endNode.Name,
 		Status:        "pending",
 		VehicleID:     req.VehicleID,
 		DriverID:      req.DriverID,
@@ -45,113 +75,408 @@ func (s *TransportSvc) CreateTransportTask(req *CreateTransportTaskReq) (*model.
 			Distance:  req.Distance,
 		},
 	}
-	// 3. 入库
-	if err := s.transportRepo.CreateTask(task); err != nil {
+	// 3. 未指定路线时按路网规划，填充路线与距离（未指定预计到达时间时按规划时长估算）
+	if req.RouteJSON == "" {
+		route, err := s.graph.Plan(startNode.Name, endNode.Name, req.RouteMetric)
+		if err != nil {
+			return nil, err
+		}
+		task.Route.RouteJSON = route.JSON()
+		task.Route.Distance = route.Distance
+		if task.EstimatedTime.IsZero() {
+			task.EstimatedTime = time.Now().Add(time.Duration(route.Duration) * time.Minute)
+		}
+	}
+	err = s.uow.Transaction(func(repos *repository.Repositories) error {
+		// 4. 指定司机时校验排班：不可重复指派、不超过当日驾驶时长
+		if task.DriverID != "" {
+			driver, err := checkDriverSchedule(repos, vehicle, task)
+			if err != nil {
+				return err
+			}
+			if task.DriverName == "" {
+				task.DriverName = driver.DriverName
+			}
+		}
+		// 5. 入库
+		return repos.Transport.CreateTask(task)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return task, nil
 }
 
-// ChangeTaskStatus 变更运输任务状态（含包裹状态同步）
-func (s *TransportSvc) ChangeTaskStatus(taskID, newStatus string) error {
-	// 1. 查询任务
-	task, err := s.transportRepo.GetTaskByID(taskID)
-	if err != nil {
-		return err
-	}
-	// 2. 执行领域行为：状态变更
-	if err := task.ChangeStatus(newStatus); err != nil {
-		return err
-	}
-	// 3. 同步包裹状态（核心交互逻辑）
-	if newStatus == "transporting" {
-		// 运输中：同步包裹状态为transporting
-		pkgIDs, err := s.transportRepo.GetPackageIDsByTaskID(taskID)
+// ChangeTaskStatus 变更运输任务状态（含包裹状态同步，整体事务提交）
+func (s *TransportSvc) ChangeTaskStatus(caller *auth.Identity, taskID, newStatus string) error {
+	return s.uow.Transaction(func(repos *repository.Repositories) error {
+		// 1. 查询任务并校验归属
+		task, err := repos.Transport.GetTaskByID(taskID)
 		if err != nil {
 			return err
 		}
-		for _, pkgID := range pkgIDs {
-			_ = s.packageRepo.UpdateStatus(pkgID, "transporting", "", "") // 调用包裹领域更新状态
+		if err := checkDriverAccess(caller, task); err != nil {
+			return err
 		}
-	} else if newStatus == "arrived" {
-		// 已到站：同步包裹状态为arrived
-		pkgIDs, err := s.transportRepo.GetPackageIDsByTaskID(taskID)
+		// 异常任务须经异常处理恢复，以记录处理结果
+		if task.Status == "abnormal" {
+			return errno.ErrTransportTaskIsAbnormal
+		}
+		// 2. 执行领域行为：状态变更
+		if err := task.ChangeStatus(newStatus); err != nil {
+			return err
+		}
+		// 3. 同步包裹状态（核心交互逻辑，经由包裹领域状态机校验）
+		if newStatus == "transporting" || newStatus == "arrived" {
+			// 运输中/已到站：同步包裹状态为transporting/arrived
+			pkgIDs, err := repos.Transport.GetPackageIDsByTaskID(taskID)
+			if err != nil {
+				return err
+			}
+			// 到站：包裹所在节点更新为终点节点（先于状态同步，送达时间按新节点预测）
+			if newStatus == "arrived" && task.EndNodeID != "" {
+				if err := repos.Package.UpdateCurrentNode(pkgIDs, task.EndNodeID); err != nil {
+					return err
+				}
+			}
+			if err := syncPackagesStatus(repos, pkgIDs, newStatus); err != nil {
+				return err
+			}
+		}
+		// 4. 写入包裹轨迹
+		if err := writeTransportTraces(repos, task, caller.OperatorName(), ""); err != nil {
+			return err
+		}
+		// 5. 更新任务
+		return repos.Transport.UpdateTask(task)
+	})
+}
+
+// BindPackagesToTask 绑定包裹到运输任务：已绑定包裹去重，返回本次新绑定的包裹数与绑定后的包裹数量（含包裹状态校验，整体事务提交）
+func (s *TransportSvc) BindPackagesToTask(taskID string, packageIDs []string) (bound, count int, err error) {
+	err = s.uow.Transaction(func(repos *repository.Repositories) error {
+		// 1. 查询任务及已绑定包裹，过滤出新增包裹
+		task, err := repos.Transport.GetTaskByID(taskID)
 		if err != nil {
 			return err
 		}
-		for _, pkgID := range pkgIDs {
-			_ = s.packageRepo.UpdateStatus(pkgID, "arrived", "", "")
+		boundIDs, err := repos.Transport.GetPackageIDsByTaskID(taskID)
+		if err != nil {
+			return err
+		}
+		newIDs := excludeIDs(packageIDs, boundIDs)
+		// 2. 校验包裹状态：仅已分拣（sorted）的包裹可绑定
+		for _, pkgID := range newIDs {
+			pkg, err := repos.Package.GetByID(pkgID)
+			if err != nil {
+				return err
+			}
+			if pkg.Status != "sorted" {
+				return fmt.Errorf("包裹%s状态为%s，仅已分拣包裹可绑定运输任务：%w", pkgID, pkg.Status, errno.ErrPackageNotBindable)
+			}
 		}
+		// 3. 执行领域行为：绑定包裹
+		if err := task.BindPackage(newIDs); err != nil {
+			return err
+		}
+		count = len(boundIDs)
+		if len(newIDs) == 0 {
+			return nil
+		}
+		// 4. 校验绑定后装载不超过车辆载重与容积
+		if err := checkVehicleLoad(repos, task, newIDs); err != nil {
+			return err
+		}
+		// 5. 保存关联关系
+		if err := repos.Transport.BindPackages(taskID, newIDs); err != nil {
+			return err
+		}
+		// 6. 以关联表为准重新统计包裹数量与装载，乐观锁保存任务
+		if err := refreshTransportLoad(repos, task); err != nil {
+			return err
+		}
+		if err := repos.Transport.UpdateTask(task); err != nil {
+			return err
+		}
+		bound, count = len(newIDs), task.PackageCount
+		// 7. 运输途中追加绑定的包裹直接进入运输中状态
+		if task.Status == "transporting" {
+			return syncPackagesStatus(repos, newIDs, "transporting")
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
 	}
-	// 4. 更新任务
-	return s.transportRepo.UpdateTask(task)
+	return bound, count, nil
 }
 
-// BindPackagesToTask 绑定包裹到运输任务（含包裹状态校验）
-func (s *TransportSvc) BindPackagesToTask(taskID string, packageIDs []string) error {
-	// 1. 查询任务
-	task, err := s.transportRepo.GetTaskByID(taskID)
-	if err != nil {
-		return err
+// UnbindPackagesFromTask 从运输任务解绑包裹：解绑包裹恢复为已分拣（sorted）待重新调度，
+// 包裹数量以关联表为准，返回解绑后的包裹数量（整体事务提交）
+func (s *TransportSvc) UnbindPackagesFromTask(caller *auth.Identity, taskID string, packageIDs []string) (int, error) {
+	packageIDs = excludeIDs(packageIDs, nil)
+	if len(packageIDs) == 0 {
+		return 0, errno.ErrParamInvalid
 	}
-	// 2. 校验包裹状态：仅已分拣（sorted）的包裹可绑定
-	for _, pkgID := range packageIDs {
-		pkg, err := s.packageRepo.GetByID(pkgID)
+	var count int
+	err := s.uow.Transaction(func(repos *repository.Repositories) error {
+		task, err := repos.Transport.GetTaskByID(taskID)
 		if err != nil {
 			return err
 		}
-		if pkg.Status != "sorted" {
-			return fmt.Errorf("包裹%s状态为%s，仅已分拣包裹可绑定运输任务", pkgID, pkg.Status)
+		// 1. 解绑并保存任务
+		pkgs, err := detachTransportPackages(repos, task, packageIDs)
+		if err != nil {
+			return err
 		}
+		count = task.PackageCount
+		// 2. 包裹恢复为已分拣
+		for _, pkg := range pkgs {
+			if pkg.Status == "sorted" {
+				continue
+			}
+			if err := savePackageStatus(repos, pkg, "sorted"); err != nil {
+				return err
+			}
+		}
+		// 3. 写入解绑轨迹
+		remark := fmt.Sprintf("包裹已从运输任务%s（车辆%s）解绑，待重新调度", task.TaskID, task.VehicleID)
+		return writePackageTraces(repos.Package, packageIDs, "transport_unbound", transportTraceNode(task, false), caller.OperatorName(), remark)
+	})
+	if err != nil {
+		return 0, err
 	}
-	// 3. 执行领域行为：绑定包裹
-	//这里绑定的时候要不要直接把包裹的状态也改了
-	if err := task.BindPackage(packageIDs); err != nil {
-		fmt.Println(err)
-		return err
-	}
+	return count, nil
+}
 
-	// 4. 保存任务（更新包裹数量）
-	//保存的时候，不小心把为空的一些时间也算做更新范围了
-	if err := s.transportRepo.UpdateTask(task); err != nil {
-		fmt.Println("更新任务报错：", err)
-		fmt.Printf("更新的task对象：%+v\n", task)
-		return err
+// TransferPackages 将包裹从一个运输任务整体转至另一个运输任务（如车辆故障换车）：
+// 两个任务的包裹数量以关联表为准，包裹状态随转入任务调整（运输中→transporting，待执行→sorted），记录交接轨迹（整体事务提交）
+func (s *TransportSvc) TransferPackages(caller *auth.Identity, fromTaskID string, req *TransferPackagesReq) (*TransferResult, error) {
+	if fromTaskID == req.ToTaskID {
+		return nil, errno.ErrTransferSameTask
 	}
-	// 5. 保存关联关系
-	return s.transportRepo.BindPackages(taskID, packageIDs)
+	result := &TransferResult{FromTaskID: fromTaskID, ToTaskID: req.ToTaskID}
+	err := s.uow.Transaction(func(repos *repository.Repositories) error {
+		// 1. 查询转出/转入任务，确定转运包裹（all=true时为转出任务全部包裹）
+		from, err := repos.Transport.GetTaskByID(fromTaskID)
+		if err != nil {
+			return err
+		}
+		to, err := repos.Transport.GetTaskByID(req.ToTaskID)
+		if err != nil {
+			return err
+		}
+		packageIDs := excludeIDs(req.PackageIDs, nil)
+		if req.All {
+			if packageIDs, err = repos.Transport.GetPackageIDsByTaskID(fromTaskID); err != nil {
+				return err
+			}
+		}
+		if len(packageIDs) == 0 {
+			return errno.ErrParamInvalid
+		}
+		// 2. 从转出任务解绑
+		pkgs, err := detachTransportPackages(repos, from, packageIDs)
+		if err != nil {
+			return err
+		}
+		// 3. 绑定到转入任务（仅待执行/运输中任务可绑定）
+		if err := to.BindPackage(packageIDs); err != nil {
+			return err
+		}
+		if err := checkVehicleLoad(repos, to, packageIDs); err != nil {
+			return err
+		}
+		if err := repos.Transport.BindPackages(to.TaskID, packageIDs); err != nil {
+			return err
+		}
+		if err := refreshTransportLoad(repos, to); err != nil {
+			return err
+		}
+		if err := repos.Transport.UpdateTask(to); err != nil {
+			return err
+		}
+		// 4. 包裹状态随转入任务调整
+		target := "sorted"
+		if to.Status == "transporting" {
+			target = "transporting"
+		}
+		for _, pkg := range pkgs {
+			if pkg.Status == target {
+				continue
+			}
+			if err := savePackageStatus(repos, pkg, target); err != nil {
+				return err
+			}
+		}
+		// 5. 写入交接轨迹（节点为转入任务出发节点）
+		remark := fmt.Sprintf("包裹由运输任务%s（车辆%s）转至运输任务%s（车辆%s）", from.TaskID, from.VehicleID, to.TaskID, to.VehicleID)
+		if err := writePackageTraces(repos.Package, packageIDs, "transport_transfer", transportTraceNode(to, false), caller.OperatorName(), remark); err != nil {
+			return err
+		}
+		result.PackageIDs = packageIDs
+		result.FromPackageCount = from.PackageCount
+		result.ToPackageCount = to.PackageCount
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
-// ReportTransportAbnormal 上报运输异常
-func (s *TransportSvc) ReportTransportAbnormal(taskID, abnormalType, reason, handler string) error {
-	// 1. 查询任务
+// GetTaskLoad 查询运输任务装载情况及车辆装载率（司机仅可查询本人任务）
+func (s *TransportSvc) GetTaskLoad(caller *auth.Identity, taskID string) (*TaskLoad, error) {
 	task, err := s.transportRepo.GetTaskByID(taskID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	// 2. 执行领域行为：上报异常
-	task.ReportAbnormal(abnormalType, reason, handler)
-	// 3. 同步包裹状态为transport_abnormal
-	pkgIDs, err := s.transportRepo.GetPackageIDsByTaskID(taskID)
+	if err := checkDriverAccess(caller, task); err != nil {
+		return nil, err
+	}
+	vehicle, err := s.vehicleRepo.GetByID(task.VehicleID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	for _, pkgID := range pkgIDs {
-		_ = s.packageRepo.UpdateStatus(pkgID, "transport_abnormal", reason, handler)
+	weightPct, volumePct := task.Utilization(vehicle)
+	return &TaskLoad{
+		TaskID:            task.TaskID,
+		VehicleID:         vehicle.VehicleID,
+		Plate:             vehicle.Plate,
+		PackageCount:      task.PackageCount,
+		LoadWeight:        task.LoadWeight,
+		LoadVolume:        task.LoadVolume,
+		MaxWeight:         vehicle.MaxWeight,
+		MaxVolume:         vehicle.MaxVolume,
+		WeightUtilization: weightPct,
+		VolumeUtilization: volumePct,
+	}, nil
+}
+
+// PlanRoute 按路网规划起止节点间的最优路线（不创建任务）
+func (s *TransportSvc) PlanRoute(from, to, metric string) (*routing.Route, error) {
+	if from == "" || to == "" {
+		return nil, errno.ErrParamInvalid
 	}
-	// 4. 更新任务
-	return s.transportRepo.UpdateTask(task)
+	return s.graph.Plan(from, to, metric)
 }
 
-// GetDriverTaskPackages 司机查询本人任务的包裹列表（核心场景）
-func (s *TransportSvc) GetDriverTaskPackages(driverID, taskID string) ([]*model.Package, error) {
+// HandleTransportAbnormal 处理运输异常：记录处理结果并恢复任务状态，绑定包裹状态随之恢复（整体事务提交）
+func (s *TransportSvc) HandleTransportAbnormal(caller *auth.Identity, taskID, result, newStatus string) error {
+	return s.uow.Transaction(func(repos *repository.Repositories) error {
+		// 1. 查询任务
+		task, err := repos.Transport.GetTaskByID(taskID)
+		if err != nil {
+			return err
+		}
+		// 2. 执行领域行为：处理异常（处理人取自调用方身份）
+		if err := task.HandleAbnormal(result, newStatus, caller.OperatorName()); err != nil {
+			return err
+		}
+		// 3. 恢复包裹状态：继续运输→transporting，直接完成→arrived（未发出的sorted包裹保持不变）
+		restoreStatus := "transporting"
+		if newStatus == "completed" {
+			restoreStatus = "arrived"
+		}
+		pkgIDs, err := repos.Transport.GetPackageIDsByTaskID(taskID)
+		if err != nil {
+			return err
+		}
+		var arrivedIDs []string
+		for _, pkgID := range pkgIDs {
+			pkg, err := repos.Package.GetByID(pkgID)
+			if err != nil {
+				return err
+			}
+			if pkg.Status != "transport_abnormal" && !(restoreStatus == "arrived" && pkg.Status == "transporting") {
+				continue
+			}
+			if restoreStatus == "arrived" {
+				arrivedIDs = append(arrivedIDs, pkgID)
+				if task.EndNodeID != "" {
+					pkg.CurrentNodeID = task.EndNodeID // 送达时间按到达节点预测
+				}
+			}
+			if err := savePackageStatus(repos, pkg, restoreStatus); err != nil {
+				return err
+			}
+		}
+		if task.EndNodeID != "" {
+			if err := repos.Package.UpdateCurrentNode(arrivedIDs, task.EndNodeID); err != nil {
+				return err
+			}
+		}
+		if err := writeTransportTraces(repos, task, caller.OperatorName(), "异常已处理："+result); err != nil {
+			return err
+		}
+		// 4. 更新任务
+		return repos.Transport.UpdateTask(task)
+	})
+}
+
+// ReportTransportAbnormal 上报运输异常（整体事务提交，上报人取自调用方身份）
+func (s *TransportSvc) ReportTransportAbnormal(caller *auth.Identity, taskID, abnormalType, reason string) error {
+	handler := caller.OperatorName()
+	return s.uow.Transaction(func(repos *repository.Repositories) error {
+		// 1. 查询任务并校验归属
+		task, err := repos.Transport.GetTaskByID(taskID)
+		if err != nil {
+			return err
+		}
+		if err := checkDriverAccess(caller, task); err != nil {
+			return err
+		}
+		// 2. 执行领域行为：上报异常
+		if err := task.ReportAbnormal(abnormalType, reason, handler); err != nil {
+			return err
+		}
+		// 3. 同步在途包裹状态为transport_abnormal，并逐件记录异常与轨迹（已到站及后续环节的包裹不受影响）
+		pkgIDs, err := repos.Transport.GetPackageIDsByTaskID(taskID)
+		if err != nil {
+			return err
+		}
+		nodeName := task.StartNode + "→" + task.EndNode
+		for _, pkgID := range pkgIDs {
+			pkg, err := repos.Package.GetByID(pkgID)
+			if err != nil {
+				return err
+			}
+			if pkg.Status != "sorted" && pkg.Status != "transporting" && pkg.Status != "transport_abnormal" {
+				continue
+			}
+			if pkg.Status != "transport_abnormal" {
+				if err := markPackageAbnormal(repos, pkg, "transport_abnormal", reason, handler); err != nil {
+					return err
+				}
+			}
+			record := &model.AbnormalRecord{
+				PackageID:      pkgID,
+				AbnormalType:   "transport",
+				SubType:        abnormalType,
+				AbnormalReason: reason,
+				TaskID:         taskID,
+				NodeName:       nodeName,
+				Reporter:       handler,
+			}
+			remark := fmt.Sprintf("运输异常（%s）：%s，运输任务%s", abnormalType, reason, taskID)
+			if err := recordPackageAbnormal(repos.Package, record, remark); err != nil {
+				return err
+			}
+		}
+		// 4. 更新任务
+		return repos.Transport.UpdateTask(task)
+	})
+}
+
+// GetDriverTaskPackages 司机查询本人任务的包裹列表（核心场景，调度员可查询任意任务）
+func (s *TransportSvc) GetDriverTaskPackages(caller *auth.Identity, taskID string) ([]*model.Package, error) {
 	// 1. 校验任务归属：确保任务属于该司机
 	task, err := s.transportRepo.GetTaskByID(taskID)
 	if err != nil {
 		return nil, err
 	}
-	if task.DriverID != driverID {
-		return nil, errno.ErrTransportTaskNotBelongToDriver
+	if err := checkDriverAccess(caller, task); err != nil {
+		return nil, err
 	}
 	// 2. 查询任务绑定的包裹ID
 	pkgIDs, err := s.transportRepo.GetPackageIDsByTaskID(taskID)
@@ -170,9 +495,127 @@ func (s *TransportSvc) GetDriverTaskPackages(driverID, taskID string) ([]*model.
 	return packages, nil
 }
 
+// ListDriverTasks 司机任务收件箱：按状态/日期筛选并分页，包裹数量以关联表实时统计为准
+func (s *TransportSvc) ListDriverTasks(caller *auth.Identity, driverID string, req *TaskListReq) ([]*model.TransportTask, int64, error) {
+	// 司机仅可查询本人任务
+	if caller.Role == auth.RoleDriver && caller.UserID != driverID {
+		return nil, 0, errno.ErrForbidden
+	}
+	query, err := req.toQuery()
+	if err != nil {
+		return nil, 0, err
+	}
+	tasks, total, err := s.transportRepo.ListTasksByDriverID(driverID, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	taskIDs := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		taskIDs = append(taskIDs, task.TaskID)
+	}
+	counts, err := s.transportRepo.CountPackagesByTaskIDs(taskIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, task := range tasks {
+		task.PackageCount = counts[task.TaskID]
+	}
+	return tasks, total, nil
+}
+
+// detachTransportPackages 从运输任务解绑包裹并以关联表为准保存任务：包裹须绑定在该任务上，
+// 且处于已分拣/运输中/运输异常状态（已到站包裹不可解绑），返回解绑的包裹
+func detachTransportPackages(repos *repository.Repositories, task *model.TransportTask, packageIDs []string) ([]*model.Package, error) {
+	if err := task.UnbindPackage(packageIDs); err != nil {
+		return nil, err
+	}
+	boundIDs, err := repos.Transport.GetPackageIDsByTaskID(task.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	bound := make(map[string]bool, len(boundIDs))
+	for _, id := range boundIDs {
+		bound[id] = true
+	}
+	pkgs := make([]*model.Package, 0, len(packageIDs))
+	for _, pkgID := range packageIDs {
+		if !bound[pkgID] {
+			return nil, fmt.Errorf("包裹%s：%w", pkgID, errno.ErrPackageNotBindToTask)
+		}
+		pkg, err := repos.Package.GetByID(pkgID)
+		if err != nil {
+			return nil, err
+		}
+		if pkg.Status != "sorted" && pkg.Status != "transporting" && pkg.Status != "transport_abnormal" {
+			return nil, fmt.Errorf("包裹%s状态为%s，不可从运输任务解绑：%w", pkgID, pkg.Status, errno.ErrPackageStatusInvalid)
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	if err := repos.Transport.UnbindPackages(task.TaskID, packageIDs); err != nil {
+		return nil, err
+	}
+	if err := refreshTransportLoad(repos, task); err != nil {
+		return nil, err
+	}
+	if err := repos.Transport.UpdateTask(task); err != nil {
+		return nil, err
+	}
+	return pkgs, nil
+}
+
+// refreshTransportLoad 以关联表为准重新统计运输任务包裹数量与装载重量/体积（不保存任务）
+func refreshTransportLoad(repos *repository.Repositories, task *model.TransportTask) error {
+	count, err := repos.Transport.CountPackagesByTaskID(task.TaskID)
+	if err != nil {
+		return err
+	}
+	weight, volume, err := repos.Transport.SumLoadByTaskID(task.TaskID)
+	if err != nil {
+		return err
+	}
+	task.PackageCount, task.LoadWeight, task.LoadVolume = count, weight, volume
+	return nil
+}
+
+// checkVehicleLoad 校验任务追加包裹（已绑定的不重复计入）后装载不超过车辆载重与容积；
+// 车辆未登记时返回errno.ErrVehicleNotFound（无法确认运力，不允许继续装载）
+func checkVehicleLoad(repos *repository.Repositories, task *model.TransportTask, packageIDs []string) error {
+	vehicle, err := repos.Vehicle.GetByID(task.VehicleID)
+	if err != nil {
+		return err
+	}
+	boundIDs, err := repos.Transport.GetPackageIDsByTaskID(task.TaskID)
+	if err != nil {
+		return err
+	}
+	weight, volume, err := repos.Transport.SumLoadByTaskID(task.TaskID)
+	if err != nil {
+		return err
+	}
+	for _, pkgID := range excludeIDs(packageIDs, boundIDs) {
+		pkg, err := repos.Package.GetByID(pkgID)
+		if err != nil {
+			return err
+		}
+		weight += pkg.Weight
+		volume += model.PackageVolume(pkg)
+	}
+	return vehicle.CheckLoad(weight, volume)
+}
+
+// checkDriverAccess 司机仅可访问本人承接的任务，调度员/管理员不受限
+func checkDriverAccess(caller *auth.Identity, task *model.TransportTask) error {
+	if caller.Role == auth.RoleDriver && task.DriverID != caller.UserID {
+		return errno.ErrTransportTaskNotBelongToDriver
+	}
+	return nil
+}
+
 // CreateTransportTaskReq 创建运输任务请求参数
 type CreateTransportTaskReq struct {
+	StartNodeID   string    `json:"start_node_id"` // 起止节点可传ID或名称，ID优先
 	StartNode     string    `json:"start_node"`
+	EndNodeID     string    `json:"end_node_id"`
 	EndNode       string    `json:"end_node"`
 	VehicleID     string    `json:"vehicle_id"`
 	DriverID      string    `json:"driver_id"`
@@ -180,6 +623,37 @@ type CreateTransportTaskReq struct {
 	EstimatedTime time.Time `json:"estimated_time"`
 	RouteJSON     string    `json:"route_json"`
 	Distance      float64   `json:"distance"`
+	RouteMetric   string    `json:"route_metric"` // 未指定路线时的规划指标：distance（默认）/time/cost
+}
+
+// TransferPackagesReq 运输任务间转运包裹请求参数
+type TransferPackagesReq struct {
+	ToTaskID   string   `json:"to_task_id" binding:"required"`
+	PackageIDs []string `json:"package_ids"`
+	All        bool     `json:"all"` // 转运转出任务的全部包裹
+}
+
+// TaskLoad 运输任务装载情况（装载率为百分比）
+type TaskLoad struct {
+	TaskID            string  `json:"task_id"`
+	VehicleID         string  `json:"vehicle_id"`
+	Plate             string  `json:"plate"`
+	PackageCount      int     `json:"package_count"`
+	LoadWeight        float64 `json:"load_weight"` // kg
+	LoadVolume        float64 `json:"load_volume"` // m³
+	MaxWeight         float64 `json:"max_weight"`
+	MaxVolume         float64 `json:"max_volume"`
+	WeightUtilization float64 `json:"weight_utilization"`
+	VolumeUtilization float64 `json:"volume_utilization"`
+}
+
+// TransferResult 转运结果
+type TransferResult struct {
+	FromTaskID       string   `json:"from_task_id"`
+	ToTaskID         string   `json:"to_task_id"`
+	PackageIDs       []string `json:"package_ids"`
+	FromPackageCount int      `json:"from_package_count"`
+	ToPackageCount   int      `json:"to_package_count"`
 }
 
 // genTaskID 生成唯一运输任务ID（示例实现）
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+	"gorm.io/gorm"
+)
+
+func TestTransportChangeTaskStatusSyncsPackages(t *testing.T) {
+	s := newMemStore()
+	s.addPackages("sorted", "P1", "P2")
+	s.addTransportTask("T1", "pending", "P1", "P2")
+	s.nodes["N002"] = model.Node{NodeID: "N002", Name: "杭州分拣中心", Type: "sorting_center"}
+	task := s.transports["T1"]
+	task.EndNodeID = "N002"
+	s.transports["T1"] = task
+	svc := &TransportSvc{uow: &memUnitOfWork{s: s}}
+
+	if err := svc.ChangeTaskStatus(testAdmin, "T1", "transporting"); err != nil {
+		t.Fatalf("ChangeTaskStatus(transporting) error = %v", err)
+	}
+	assertPackageStatus(t, s, "transporting", "P1", "P2")
+	if err := svc.ChangeTaskStatus(testAdmin, "T1", "arrived"); err != nil {
+		t.Fatalf("ChangeTaskStatus(arrived) error = %v", err)
+	}
+	// 到站：包裹状态与所在节点同步，每次流转为每个包裹写入一条轨迹，任务版本号逐次递增
+	assertPackageStatus(t, s, "arrived", "P1", "P2")
+	for _, id := range []string{"P1", "P2"} {
+		if got := s.packages[id].CurrentNodeID; got != "N002" {
+			t.Fatalf("包裹%s所在节点 = %s, want N002", id, got)
+		}
+	}
+	if got := len(s.traces); got != 4 {
+		t.Fatalf("轨迹数 = %d, want 4", got)
+	}
+	if task := s.transports["T1"]; task.Status != "arrived" || task.Version != 2 || task.ActualArriveTime.IsZero() {
+		t.Fatalf("任务T1 状态/版本 = %s/%d（到达时间%v）, want arrived/2", task.Status, task.Version, task.ActualArriveTime)
+	}
+}
+
+func TestTransportChangeTaskStatusRollback(t *testing.T) {
+	s := newMemStore()
+	s.addPackages("sorted", "P1")
+	s.addPackages("delivered", "P2")
+	s.addTransportTask("T1", "pending", "P1", "P2")
+	svc := &TransportSvc{uow: &memUnitOfWork{s: s}}
+
+	// P2状态不可流转为运输中：已同步的P1、任务状态与轨迹随事务整体回滚
+	err := svc.ChangeTaskStatus(testAdmin, "T1", "transporting")
+	if !errors.Is(err, errno.ErrPackageStatusInvalid) {
+		t.Fatalf("ChangeTaskStatus() error = %v, want %v", err, errno.ErrPackageStatusInvalid)
+	}
+	assertPackageStatus(t, s, "sorted", "P1")
+	assertPackageStatus(t, s, "delivered", "P2")
+	if task := s.transports["T1"]; task.Status != "pending" || task.Version != 0 {
+		t.Fatalf("任务T1 状态/版本 = %s/%d, want pending/0", task.Status, task.Version)
+	}
+	if len(s.traces) != 0 || len(s.etas) != 0 {
+		t.Fatalf("轨迹/预测写入 = %d/%d, want 0/0", len(s.traces), len(s.etas))
+	}
+}
+
+func TestDeliveryChangeTaskStatusRollback(t *testing.T) {
+	s := newMemStore()
+	s.addPackages("arrived", "P1")
+	s.addPackages("delivering", "P2")
+	s.addDeliveryTask("D1", "delivering", "P1", "P2")
+	svc := &DeliverySvc{uow: &memUnitOfWork{s: s}}
+
+	// 包裹均未签收时不可完成：任务与包裹状态保持不变
+	err := svc.ChangeTaskStatus(testAdmin, "D1", "completed")
+	if !errors.Is(err, errno.ErrPackageNotSigned) {
+		t.Fatalf("ChangeTaskStatus() error = %v, want %v", err, errno.ErrPackageNotSigned)
+	}
+	if task := s.deliveries["D1"]; task.Status != "delivering" || task.Version != 0 {
+		t.Fatalf("任务D1 状态/版本 = %s/%d, want delivering/0", task.Status, task.Version)
+	}
+	assertPackageStatus(t, s, "arrived", "P1")
+	assertPackageStatus(t, s, "delivering", "P2")
+	if len(s.traces) != 0 {
+		t.Fatalf("轨迹数 = %d, want 0", len(s.traces))
+	}
+}
+
+func TestTransportChangeTaskStatusConflict(t *testing.T) {
+	s := newMemStore()
+	s.addPackages("sorted", "P1")
+	s.addTransportTask("T1", "pending", "P1")
+	svc := &TransportSvc{uow: &racingUnitOfWork{s: s}}
+
+	// 任务在读取后被其他操作修改：保存任务时乐观锁冲突，已同步的包裹状态与轨迹一并回滚
+	err := svc.ChangeTaskStatus(testAdmin, "T1", "transporting")
+	if !errors.Is(err, errno.ErrTransportTaskConflict) {
+		t.Fatalf("ChangeTaskStatus() error = %v, want %v", err, errno.ErrTransportTaskConflict)
+	}
+	assertPackageStatus(t, s, "sorted", "P1")
+	if task := s.transports["T1"]; task.Status != "pending" || task.Version != 0 {
+		t.Fatalf("任务T1 状态/版本 = %s/%d, want pending/0", task.Status, task.Version)
+	}
+	if len(s.traces) != 0 {
+		t.Fatalf("轨迹数 = %d, want 0", len(s.traces))
+	}
+}
+
+func TestHandleTransportAbnormalSyncsPackages(t *testing.T) {
+	tests := []struct {
+		name       string
+		newStatus  string
+		wantStatus string
+	}{
+		{"恢复运输", "transporting", "transporting"},
+		{"直接完成", "completed", "arrived"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemStore()
+			s.addPackages("transporting", "P1", "P2")
+			s.addTransportTask("T1", "transporting", "P1", "P2")
+			svc := &TransportSvc{uow: &memUnitOfWork{s: s}}
+
+			if err := svc.HandleTransportAbnormal(testAdmin, "T1", "已处理", tt.newStatus); !errors.Is(err, errno.ErrTransportTaskNotAbnormal) {
+				t.Fatalf("未上报异常时HandleTransportAbnormal() error = %v, want %v", err, errno.ErrTransportTaskNotAbnormal)
+			}
+			if err := svc.ReportTransportAbnormal(testAdmin, "T1", "vehicle_fault", "车辆故障"); err != nil {
+				t.Fatalf("ReportTransportAbnormal() error = %v", err)
+			}
+			if err := svc.HandleTransportAbnormal(testAdmin, "T1", "已更换车辆", tt.newStatus); err != nil {
+				t.Fatalf("HandleTransportAbnormal() error = %v", err)
+			}
+			assertPackageStatus(t, s, tt.wantStatus, "P1", "P2")
+			task := s.transports["T1"]
+			if task.Status != tt.newStatus || task.Abnormal.HandleResult != "已更换车辆" || task.Abnormal.HandleTime.IsZero() {
+				t.Fatalf("任务T1 状态/处理结果 = %s/%s, want %s/已更换车辆", task.Status, task.Abnormal.HandleResult, tt.newStatus)
+			}
+		})
+	}
+}
+
+func TestBindPackagesVehicleLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(s *memStore)
+		wantErr error
+	}{
+		{"车辆未登记时拒绝装载", func(s *memStore) {}, errno.ErrVehicleNotFound},
+		{"超出车辆载重", func(s *memStore) {
+			s.vehicles["V001"] = model.Vehicle{VehicleID: "V001", MaxWeight: 2, MaxVolume: 10, Status: "active"}
+		}, errno.ErrVehicleOverweight},
+		{"已删除的包裹不计入装载", func(s *memStore) {
+			s.vehicles["V001"] = model.Vehicle{VehicleID: "V001", MaxWeight: 2, MaxVolume: 10, Status: "active"}
+			pkg := s.packages["P1"]
+			pkg.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+			s.packages["P1"] = pkg
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemStore()
+			s.addPackages("sorted", "P1", "P2", "P3")
+			s.addTransportTask("T1", "pending", "P1")
+			tt.setup(s)
+			_, _, err := (&TransportSvc{uow: &memUnitOfWork{s: s}}).BindPackagesToTask("T1", []string{"P2", "P3"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("BindPackagesToTask() error = %v, want %v", err, tt.wantErr)
+			}
+			wantBound := 1
+			if tt.wantErr == nil {
+				wantBound = 3
+			}
+			if got := len(s.transportPkgs["T1"]); got != wantBound {
+				t.Fatalf("任务T1绑定包裹数 = %d, want %d", got, wantBound)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/LFrankl/fdu-lab3/internal/model"
+	"github.com/LFrankl/fdu-lab3/internal/repository"
+	"github.com/LFrankl/fdu-lab3/internal/util"
+	"github.com/LFrankl/fdu-lab3/pkg/db"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+// VehicleSvc 车辆登记与维护业务服务
+type VehicleSvc struct {
+	vehicleRepo   repository.VehicleRepo
+	transportRepo repository.TransportRepo // 删除前校验未完成任务
+	idGen         *util.IDGenerator
+}
+
+func NewVehicleSvc() *VehicleSvc {
+	return &VehicleSvc{
+		vehicleRepo:   repository.NewVehicleRepo(db.DB),
+		transportRepo: repository.NewTransportRepo(db.DB),
+		idGen:         util.NewIDGenerator(),
+	}
+}
+
+// VehicleReq 创建/更新车辆请求参数
+type VehicleReq struct {
+	Plate     string  `json:"plate" binding:"required"`
+	Type      string  `json:"type" binding:"required"`
+	MaxWeight float64 `json:"max_weight" binding:"required"` // kg
+	MaxVolume float64 `json:"max_volume" binding:"required"` // m³
+	ColdChain bool    `json:"cold_chain"`
+	Status    string  `json:"status"` // active/maintenance/disabled，创建时默认active
+}
+
+// VehicleListReq 车辆查询参数
+type VehicleListReq struct {
+	Type      string `form:"type"`
+	Status    string `form:"status"`
+	ColdChain *bool  `form:"cold_chain"`
+	Keyword   string `form:"keyword"`
+	Page      int    `form:"page"`
+	PageSize  int    `form:"page_size"`
+}
+
+// CreateVehicle 登记车辆（车牌号唯一）
+func (s *VehicleSvc) CreateVehicle(req *VehicleReq) (*model.Vehicle, error) {
+	vehicle := &model.Vehicle{VehicleID: s.idGen.GenerateVehicleID(), Status: "active"}
+	if err := s.applyReq(vehicle, req); err != nil {
+		return nil, err
+	}
+	if err := s.vehicleRepo.Create(vehicle); err != nil {
+		return nil, err
+	}
+	return vehicle, nil
+}
+
+// UpdateVehicle 更新车辆（整体覆盖可编辑字段）
+func (s *VehicleSvc) UpdateVehicle(vehicleID string, req *VehicleReq) (*model.Vehicle, error) {
+	vehicle, err := s.vehicleRepo.GetByID(vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyReq(vehicle, req); err != nil {
+		return nil, err
+	}
+	if err := s.vehicleRepo.Update(vehicle); err != nil {
+		return nil, err
+	}
+	return vehicle, nil
+}
+
+// GetVehicle 查询车辆详情
+func (s *VehicleSvc) GetVehicle(vehicleID string) (*model.Vehicle, error) {
+	return s.vehicleRepo.GetByID(vehicleID)
+}
+
+// ListVehicles 按车型/状态/冷链/车牌分页查询车辆
+func (s *VehicleSvc) ListVehicles(req *VehicleListReq) ([]*model.Vehicle, int64, error) {
+	normalizePage(&req.Page, &req.PageSize)
+	return s.vehicleRepo.List(repository.VehicleListQuery{
+		Type:      req.Type,
+		Status:    req.Status,
+		ColdChain: req.ColdChain,
+		Keyword:   strings.ToUpper(strings.TrimSpace(req.Keyword)),
+		Page:      req.Page,
+		PageSize:  req.PageSize,
+	})
+}
+
+// DeleteVehicle 删除车辆（仍有未完成运输任务的车辆不可删除）
+func (s *VehicleSvc) DeleteVehicle(vehicleID string) error {
+	if _, err := s.vehicleRepo.GetByID(vehicleID); err != nil {
+		return err
+	}
+	open, err := s.transportRepo.CountOpenTasksByVehicleID(vehicleID)
+	if err != nil {
+		return err
+	}
+	if open > 0 {
+		return errno.ErrVehicleInUse
+	}
+	return s.vehicleRepo.Delete(vehicleID)
+}
+
+// applyReq 将请求写入车辆并校验（车牌号唯一）
+func (s *VehicleSvc) applyReq(vehicle *model.Vehicle, req *VehicleReq) error {
+	vehicle.Plate = req.Plate
+	vehicle.Type = req.Type
+	vehicle.MaxWeight = req.MaxWeight
+	vehicle.MaxVolume = req.MaxVolume
+	vehicle.ColdChain = req.ColdChain
+	if req.Status != "" {
+		vehicle.Status = req.Status
+	}
+	if err := vehicle.Validate(); err != nil {
+		return err
+	}
+	exists, err := s.vehicleRepo.ExistsByPlate(vehicle.Plate, vehicle.VehicleID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errno.ErrVehicleExists
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package util
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+//go:embed data/gazetteer.csv
+var embeddedGazetteer string
+
+// gazetteerEntry 地名库条目（省/市/区县三级，下级为空表示上级行政区本身）
+type gazetteerEntry struct {
+	province, city, district string
+	lng, lat                 float64
+}
+
+// GazetteerGeocoder 离线地理编码：按省/市/区县地名库匹配地址，返回最具体匹配行政区的坐标
+type GazetteerGeocoder struct {
+	entries []gazetteerEntry
+}
+
+// NewGazetteerGeocoder 加载地名库CSV（表头province,city,district,longitude,latitude），path为空时使用内置数据
+func NewGazetteerGeocoder(path string) (*GazetteerGeocoder, error) {
+	var reader io.Reader = strings.NewReader(embeddedGazetteer)
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("打开地名库失败: %w", err)
+		}
+		defer f.Close()
+		reader = f
+	}
+
+	rows, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("读取地名库失败: %w", err)
+	}
+	if len(rows) < 2 {
+		return nil, fmt.Errorf("地名库为空")
+	}
+	entries := make([]gazetteerEntry, 0, len(rows)-1)
+	for i, row := range rows[1:] {
+		if len(row) != 5 {
+			return nil, fmt.Errorf("地名库第%d行字段数应为5", i+2)
+		}
+		lng, err := strconv.ParseFloat(strings.TrimSpace(row[3]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("地名库第%d行经度不合法: %w", i+2, err)
+		}
+		lat, err := strconv.ParseFloat(strings.TrimSpace(row[4]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("地名库第%d行纬度不合法: %w", i+2, err)
+		}
+		entries = append(entries, gazetteerEntry{
+			province: strings.TrimSpace(row[0]),
+			city:     strings.TrimSpace(row[1]),
+			district: strings.TrimSpace(row[2]),
+			lng:      lng,
+			lat:      lat,
+		})
+	}
+	return &GazetteerGeocoder{entries: entries}, nil
+}
+
+// Geocode 匹配规则：条目最下级行政区须出现在地址中（全称或去掉省/市/区/县等后缀的简称），
+// 区县权重最高、其次城市、省份，同名区县依靠上级行政区区分
+func (g *GazetteerGeocoder) Geocode(address string) (lng, lat float64, err error) {
+	best, bestScore := -1, 0
+	for i, e := range g.entries {
+		score := 0
+		switch {
+		case e.district != "":
+			if !placeMatches(address, e.district) {
+				continue
+			}
+			score = 4
+		case e.city != "":
+			if !placeMatches(address, e.city) {
+				continue
+			}
+			score = 2
+		}
+		if e.city != "" && placeMatches(address, e.city) {
+			score += 2
+		}
+		if placeMatches(address, e.province) {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if best < 0 {
+		return 0, 0, fmt.Errorf("%w: %s", errno.ErrAddressUnresolved, address)
+	}
+	return g.entries[best].lng, g.entries[best].lat, nil
+}
+
+// placeSuffixes 行政区划名称后缀（长后缀在前）
+var placeSuffixes = []string{"特别行政区", "维吾尔自治区", "壮族自治区", "回族自治区", "自治区", "新区", "省", "市", "区", "县"}
+
+// placeMatches 地址中是否出现行政区全称或简称（简称至少两个字）
+func placeMatches(address, name string) bool {
+	if name == "" {
+		return false
+	}
+	if strings.Contains(address, name) {
+		return true
+	}
+	for _, suffix := range placeSuffixes {
+		if short := strings.TrimSuffix(name, suffix); short != name {
+			return len([]rune(short)) >= 2 && strings.Contains(address, short)
+		}
+	}
+	return false
+}
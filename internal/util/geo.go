@@ -5,19 +5,20 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
-	"github.com/LFrankl/fdu-lab3/config"
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
 )
 
-// GeoUtils 地理信息工具
+// GeoUtils 地理信息工具（地理编码委托给按配置选择的Geocoder）
 type GeoUtils struct {
-	amapKey string
+	geocoder Geocoder
 }
 
 // NewGeoUtils 创建地理信息工具实例
 func NewGeoUtils() *GeoUtils {
 	return &GeoUtils{
-		amapKey: config.Cfg.Geo.AmapKey,
+		geocoder: DefaultGeocoder(),
 	}
 }
 
@@ -36,17 +37,39 @@ type AmapGeoCodeResponse struct {
 
 // GetCoordinates 通过地址获取经纬度
 func (g *GeoUtils) GetCoordinates(address string) (lng, lat float64, err error) {
+	return g.geocoder.Geocode(address)
+}
+
+// AmapGeocoder 高德地图地理编码（在线接口，带超时）
+type AmapGeocoder struct {
+	key    string
+	client *http.Client
+}
+
+// NewAmapGeocoder 创建高德地理编码器
+func NewAmapGeocoder(key string, timeout time.Duration) *AmapGeocoder {
+	return &AmapGeocoder{
+		key:    key,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Geocode 调用高德地理编码接口解析地址
+func (a *AmapGeocoder) Geocode(address string) (lng, lat float64, err error) {
 	apiURL := "https://restapi.amap.com/v3/geocode/geo"
 	params := url.Values{}
 	params.Set("address", address)
-	params.Set("key", g.amapKey)
+	params.Set("key", a.key)
 	params.Set("output", "json")
 
-	resp, err := http.Get(fmt.Sprintf("%s?%s", apiURL, params.Encode()))
+	resp, err := a.client.Get(fmt.Sprintf("%s?%s", apiURL, params.Encode()))
 	if err != nil {
 		return 0, 0, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("地址解析失败: HTTP %d", resp.StatusCode)
+	}
 
 	var result AmapGeoCodeResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
@@ -54,7 +77,7 @@ func (g *GeoUtils) GetCoordinates(address string) (lng, lat float64, err error)
 	}
 
 	if result.Status != "1" || len(result.Geocodes) == 0 {
-		return 0, 0, fmt.Errorf("地址解析失败: %s", result.Info)
+		return 0, 0, fmt.Errorf("%w: %s", errno.ErrAddressUnresolved, result.Info)
 	}
 
 	// 解析经纬度
@@ -66,39 +89,3 @@ func (g *GeoUtils) GetCoordinates(address string) (lng, lat float64, err error)
 
 	return lng, lat, nil
 }
-
-// RoutePlan 路径规划（简化版）
-func (g *GeoUtils) RoutePlan(startAddr, endAddr string) ([]map[string]interface{}, error) {
-	// 实际场景调用高德路径规划API
-	startLng, startLat, err := g.GetCoordinates(startAddr)
-	if err != nil {
-		return nil, err
-	}
-
-	endLng, endLat, err := g.GetCoordinates(endAddr)
-	if err != nil {
-		return nil, err
-	}
-
-	// 模拟路线
-	return []map[string]interface{}{
-		{
-			"address":   startAddr,
-			"longitude": startLng,
-			"latitude":  startLat,
-			"type":      "start",
-		},
-		{
-			"address":   fmt.Sprintf("%s中转站", startAddr[:2]),
-			"longitude": (startLng + endLng) / 2,
-			"latitude":  (startLat + endLat) / 2,
-			"type":      "transit",
-		},
-		{
-			"address":   endAddr,
-			"longitude": endLng,
-			"latitude":  endLat,
-			"type":      "end",
-		},
-	}, nil
-}
@@ -0,0 +1,147 @@
+package util
+
+import (
+	"container/list"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LFrankl/fdu-lab3/config"
+)
+
+// 地理编码默认参数
+const (
+	defaultGeoTimeout   = 3    // 在线接口超时（秒）
+	defaultGeoCacheSize = 1024 // 解析结果缓存条数
+)
+
+// Geocoder 地理编码：将地址解析为经纬度
+type Geocoder interface {
+	Geocode(address string) (lng, lat float64, err error)
+}
+
+var (
+	defaultGeocoder     Geocoder
+	defaultGeocoderOnce sync.Once
+)
+
+// InitGeocoder 按配置初始化全局地理编码器（服务启动时、创建服务实例前调用，配置错误时返回error）
+func InitGeocoder(cfg config.GeoConfig) error {
+	geocoder, err := NewGeocoder(cfg)
+	if err != nil {
+		return err
+	}
+	defaultGeocoderOnce.Do(func() {
+		defaultGeocoder = geocoder
+	})
+	return nil
+}
+
+// DefaultGeocoder 获取全局地理编码器；未初始化时按当前配置创建，配置错误时退回内置离线地名库
+func DefaultGeocoder() Geocoder {
+	defaultGeocoderOnce.Do(func() {
+		geocoder, err := NewGeocoder(config.Cfg.Geo)
+		if err != nil {
+			log.Printf("地理编码器初始化失败，使用内置离线地名库: %v", err)
+			gazetteer, _ := NewGazetteerGeocoder("")
+			geocoder = NewCachingGeocoder(gazetteer, defaultGeoCacheSize)
+		}
+		defaultGeocoder = geocoder
+	})
+	return defaultGeocoder
+}
+
+// NewGeocoder 按配置创建地理编码器：backend为amap时调用高德接口，为空或gazetteer时使用离线地名库，并按需包装缓存
+func NewGeocoder(cfg config.GeoConfig) (Geocoder, error) {
+	var (
+		geocoder Geocoder
+		err      error
+	)
+	switch cfg.Backend {
+	case "", "gazetteer":
+		geocoder, err = NewGazetteerGeocoder(cfg.GazetteerPath)
+		if err != nil {
+			return nil, err
+		}
+	case "amap":
+		if cfg.AmapKey == "" {
+			return nil, fmt.Errorf("地理编码后端为amap时必须配置geo.amap_key")
+		}
+		timeout := cfg.Timeout
+		if timeout <= 0 {
+			timeout = defaultGeoTimeout
+		}
+		geocoder = NewAmapGeocoder(cfg.AmapKey, time.Duration(timeout)*time.Second)
+	default:
+		return nil, fmt.Errorf("不支持的地理编码后端: %s", cfg.Backend)
+	}
+
+	cacheSize := cfg.CacheSize
+	if cacheSize == 0 {
+		cacheSize = defaultGeoCacheSize
+	}
+	if cacheSize > 0 {
+		geocoder = NewCachingGeocoder(geocoder, cacheSize)
+	}
+	return geocoder, nil
+}
+
+// CachingGeocoder 地理编码缓存装饰器（LRU，仅缓存解析成功的结果）
+type CachingGeocoder struct {
+	inner Geocoder
+	size  int
+	mu    sync.Mutex
+	order *list.List               // 最近使用的在前
+	items map[string]*list.Element // 地址 → 链表节点
+}
+
+// geoCacheEntry 缓存条目
+type geoCacheEntry struct {
+	address  string
+	lng, lat float64
+}
+
+// NewCachingGeocoder 创建缓存装饰器，size为最大缓存条数
+func NewCachingGeocoder(inner Geocoder, size int) *CachingGeocoder {
+	return &CachingGeocoder{
+		inner: inner,
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+// Geocode 优先命中缓存，未命中时调用内层编码器并缓存结果
+func (c *CachingGeocoder) Geocode(address string) (lng, lat float64, err error) {
+	key := strings.TrimSpace(address)
+	c.mu.Lock()
+	if elem, ok := c.items[key]; ok {
+		c.order.MoveToFront(elem)
+		entry := elem.Value.(*geoCacheEntry)
+		c.mu.Unlock()
+		return entry.lng, entry.lat, nil
+	}
+	c.mu.Unlock()
+
+	// 调用内层编码器时不持锁，避免慢请求阻塞其他地址
+	lng, lat, err = c.inner.Geocode(key)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.order.MoveToFront(elem)
+		return lng, lat, nil
+	}
+	c.items[key] = c.order.PushFront(&geoCacheEntry{address: key, lng: lng, lat: lat})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*geoCacheEntry).address)
+	}
+	return lng, lat, nil
+}
@@ -38,6 +38,30 @@ func (g *IDGenerator) GenerateAbnormalRecordID() string {
 	return prefix + timestamp + randomStr
 }
 
+// GenerateStatusLogID 生成状态变更审计记录ID
+func (g *IDGenerator) GenerateStatusLogID() string {
+	prefix := "SL"
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	randomStr := g.generateRandomString(4)
+	return prefix + timestamp + randomStr
+}
+
+// GenerateNodeID 生成网络节点ID
+func (g *IDGenerator) GenerateNodeID() string {
+	prefix := "ND"
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	randomStr := g.generateRandomString(4)
+	return prefix + timestamp + randomStr
+}
+
+// GenerateVehicleID 生成车辆ID
+func (g *IDGenerator) GenerateVehicleID() string {
+	prefix := "VH"
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	randomStr := g.generateRandomString(4)
+	return prefix + timestamp + randomStr
+}
+
 // GenerateTransportTaskID 生成运输任务ID
 func (g *IDGenerator) GenerateTransportTaskID() string {
 	prefix := "TRAN"
@@ -57,10 +81,10 @@ func (g *IDGenerator) GenerateDeliveryTaskID() string {
 // 生成随机字符串
 func (g *IDGenerator) generateRandomString(length int) string {
 	chars := "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	// 使用全局随机源（自动播种、并发安全），避免同一时刻批量生成时按相同种子得到重复ID
 	result := make([]byte, length)
 	for i := 0; i < length; i++ {
-		result[i] = chars[r.Intn(len(chars))]
+		result[i] = chars[rand.Intn(len(chars))]
 	}
 	return string(result)
 }
@@ -0,0 +1,327 @@
+package util
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+// XLSX工作表的行列上限（列XFD）
+const (
+	xlsxMaxRow    = 1048576
+	xlsxMaxColumn = 16384
+)
+
+// ReadSheet 按文件扩展名读取CSV或XLSX表格（XLSX取第一个工作表），返回全部行（单元格已去除首尾空白）；
+// 表头之后的非空行超过maxRows（XLSX行号超过maxRows+1）时停止读取并返回errno.ErrImportTooManyRows，
+// 文件超过maxBytes字节时返回errno.ErrImportTooLarge（maxRows/maxBytes<=0时不限制）
+func ReadSheet(filename string, r io.Reader, maxRows int, maxBytes int64) ([][]string, error) {
+	limit := &rowLimit{max: maxRows}
+	if maxBytes > 0 {
+		r = &sizeLimitReader{r: r, left: maxBytes}
+	}
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return readCSV(r, limit)
+	case ".xlsx":
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return readXLSX(data, limit)
+	}
+	return nil, fmt.Errorf("不支持的文件类型%q，仅支持.csv/.xlsx", path.Ext(filename))
+}
+
+// sizeLimitReader 读取超过left字节时返回errno.ErrImportTooLarge
+type sizeLimitReader struct {
+	r    io.Reader
+	left int64
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > l.left+1 {
+		p = p[:l.left+1]
+	}
+	n, err := l.r.Read(p)
+	if l.left -= int64(n); l.left < 0 {
+		return n, errno.ErrImportTooLarge
+	}
+	return n, err
+}
+
+// rowLimit 读取过程中统计表头之后的非空行数
+type rowLimit struct {
+	max  int
+	seen int // 已读取的行数（含表头与空行）
+	rows int // 表头之后的非空行数
+}
+
+// add 记录读取的一行，超出上限时返回errno.ErrImportTooManyRows
+func (l *rowLimit) add(cells []string) error {
+	l.seen++
+	if l.seen == 1 || l.max <= 0 {
+		return nil
+	}
+	for _, c := range cells {
+		if c != "" {
+			l.rows++
+			break
+		}
+	}
+	if l.rows > l.max {
+		return fmt.Errorf("%w（%d行）", errno.ErrImportTooManyRows, l.max)
+	}
+	return nil
+}
+
+// readCSV 逐行读取CSV（兼容Excel导出的UTF-8 BOM，各行列数可不同）
+func readCSV(r io.Reader, limit *rowLimit) ([][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	var rows [][]string
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 && len(row) > 0 {
+			row[0] = strings.TrimPrefix(row[0], "\ufeff")
+		}
+		for i := range row {
+			row[i] = strings.TrimSpace(row[i])
+		}
+		if err := limit.add(row); err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// xlsx文件内的XML结构（仅解析读取单元格所需的部分）
+type (
+	xlsxWorkbook struct {
+		Sheets []struct {
+			RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	xlsxRelationships struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	xlsxSharedStrings struct {
+		Items []xlsxRichText `xml:"si"`
+	}
+	xlsxRichText struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	}
+	xlsxRow struct {
+		Index int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string       `xml:"r,attr"`
+			Type   string       `xml:"t,attr"`
+			Value  string       `xml:"v"`
+			Inline xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	}
+)
+
+// String 富文本拼接为纯文本
+func (t xlsxRichText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.Text)
+	}
+	return b.String()
+}
+
+// readXLSX 逐行读取XLSX第一个工作表；数值单元格按原值输出（科学计数法转为普通小数，避免手机号等长数字失真）
+func readXLSX(data []byte, limit *rowLimit) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("XLSX文件格式错误: %w", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+	var shared xlsxSharedStrings
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeZipXML(f, &shared); err != nil {
+			return nil, err
+		}
+	}
+	f, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("XLSX文件缺少工作表%s", sheetPath)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var rows [][]string
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("XLSX文件%s解析失败: %w", sheetPath, err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+		var row xlsxRow
+		if err := dec.DecodeElement(&row, &start); err != nil {
+			return nil, fmt.Errorf("XLSX文件%s解析失败: %w", sheetPath, err)
+		}
+		// 空行在XML中省略，按行号补齐，保证行号与表格一致（先校验行号，避免超大行号补齐海量空行）
+		if row.Index > xlsxMaxRow {
+			return nil, fmt.Errorf("行号%d超出XLSX上限", row.Index)
+		}
+		if limit.max > 0 && row.Index > limit.max+1 {
+			return nil, fmt.Errorf("%w（%d行）", errno.ErrImportTooManyRows, limit.max)
+		}
+		for row.Index > len(rows)+1 {
+			if err := limit.add(nil); err != nil {
+				return nil, err
+			}
+			rows = append(rows, nil)
+		}
+		var cells []string
+		for i, c := range row.Cells {
+			col := i
+			if c.Ref != "" {
+				if col, err = columnIndex(c.Ref); err != nil {
+					return nil, err
+				}
+			}
+			for len(cells) <= col {
+				cells = append(cells, "")
+			}
+			switch c.Type {
+			case "s":
+				idx, err := strconv.Atoi(c.Value)
+				if err != nil || idx < 0 || idx >= len(shared.Items) {
+					return nil, fmt.Errorf("单元格%s共享字符串索引无效", c.Ref)
+				}
+				cells[col] = shared.Items[idx].String()
+			case "inlineStr":
+				cells[col] = c.Inline.String()
+			case "", "n":
+				cells[col] = plainNumber(c.Value)
+			default: // str/b/e
+				cells[col] = c.Value
+			}
+			cells[col] = strings.TrimSpace(cells[col])
+		}
+		if err := limit.add(cells); err != nil {
+			return nil, err
+		}
+		rows = append(rows, cells)
+	}
+	return rows, nil
+}
+
+// firstSheetPath 由workbook.xml及其关联关系定位第一个工作表
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+	wbFile, ok := files["xl/workbook.xml"]
+	if !ok {
+		return "", fmt.Errorf("XLSX文件缺少workbook.xml")
+	}
+	var wb xlsxWorkbook
+	if err := decodeZipXML(wbFile, &wb); err != nil {
+		return "", err
+	}
+	relFile, ok := files["xl/_rels/workbook.xml.rels"]
+	if !ok || len(wb.Sheets) == 0 {
+		return fallback, nil
+	}
+	var rels xlsxRelationships
+	if err := decodeZipXML(relFile, &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != wb.Sheets[0].RID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return fallback, nil
+}
+
+// decodeZipXML 解析压缩包内的XML文件
+func decodeZipXML(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("XLSX文件%s解析失败: %w", f.Name, err)
+	}
+	return nil
+}
+
+// columnIndex 单元格引用（如"AB12"）的列序号，从0开始，超过XFD列返回错误
+func columnIndex(ref string) (int, error) {
+	col := 0
+	for i, r := range ref {
+		if r >= 'A' && r <= 'Z' {
+			if col = col*26 + int(r-'A') + 1; col > xlsxMaxColumn {
+				return 0, fmt.Errorf("单元格引用%q超出XLSX列上限", ref)
+			}
+			continue
+		}
+		if i == 0 {
+			break
+		}
+		return col - 1, nil
+	}
+	return 0, fmt.Errorf("单元格引用%q无效", ref)
+}
+
+// plainNumber 数值转为不含科学计数法的文本（如1.3812345678E10 → 13812345678）
+func plainNumber(v string) string {
+	if !strings.ContainsAny(v, "eE") {
+		return v
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return v
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,131 @@
+package util
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/LFrankl/fdu-lab3/pkg/errno"
+)
+
+// buildXLSX 在内存中生成仅含workbook、共享字符串与第一个工作表的最小XLSX
+func buildXLSX(t *testing.T, sheetRows string, shared ...string) []byte {
+	t.Helper()
+	var sst strings.Builder
+	for _, s := range shared {
+		sst.WriteString("<si><t>" + s + "</t></si>")
+	}
+	files := map[string]string{
+		"xl/workbook.xml":            `<workbook><sheets><sheet name="Sheet1" sheetId="1" r:id="rId1" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":       "<sst>" + sst.String() + "</sst>",
+		"xl/worksheets/sheet1.xml":   "<worksheet><sheetData>" + sheetRows + "</sheetData></worksheet>",
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip.Create(%s) error = %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("写入%s失败: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip.Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadSheetCSV(t *testing.T) {
+	data := "\ufeffsender_name, weight\n张三,1.5\n\n李四\n"
+	rows, err := ReadSheet("orders.CSV", strings.NewReader(data), 0, 0)
+	if err != nil {
+		t.Fatalf("ReadSheet() error = %v", err)
+	}
+	want := [][]string{{"sender_name", "weight"}, {"张三", "1.5"}, {"李四"}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Fatalf("ReadSheet() = %q, want %q", rows, want)
+	}
+}
+
+func TestReadSheetXLSX(t *testing.T) {
+	sheet := `<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>` +
+		`<row r="3"><c r="A3" t="inlineStr"><is><t> 张三 </t></is></c><c r="B3"><v>1.3812345678E10</v></c><c r="C3" t="n"><v>2.5</v></c></row>`
+	rows, err := ReadSheet("orders.xlsx", bytes.NewReader(buildXLSX(t, sheet, "sender_name", "weight")), 10, 0)
+	if err != nil {
+		t.Fatalf("ReadSheet() error = %v", err)
+	}
+	// 缺失的第2行补为空行，跳过的B1补为空单元格，科学计数法还原为普通数字
+	want := [][]string{{"sender_name", "", "weight"}, nil, {"张三", "13812345678", "2.5"}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Fatalf("ReadSheet() = %q, want %q", rows, want)
+	}
+}
+
+func TestReadSheetLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		data     func(t *testing.T) []byte
+		maxRows  int
+		maxBytes int64
+		wantErr  error // nil表示期望非领域错误（解析失败）
+	}{
+		{"CSV数据行超限", "a.csv", func(*testing.T) []byte { return []byte("h\n1\n2\n3\n") }, 2, 0, errno.ErrImportTooManyRows},
+		{"CSV文件超过大小上限", "a.csv", func(*testing.T) []byte { return []byte(strings.Repeat("a,b\n", 100)) }, 0, 64, errno.ErrImportTooLarge},
+		{"XLSX文件超过大小上限", "a.xlsx", func(t *testing.T) []byte {
+			return buildXLSX(t, `<row r="1"><c r="A1" t="inlineStr"><is><t>h</t></is></c></row>`)
+		}, 0, 64, errno.ErrImportTooLarge},
+		{"XLSX行号超过行数上限", "a.xlsx", func(t *testing.T) []byte {
+			return buildXLSX(t, `<row r="1"><c r="A1"><v>1</v></c></row><row r="1000000"><c r="A1000000"><v>1</v></c></row>`)
+		}, 10, 0, errno.ErrImportTooManyRows},
+		{"XLSX行号超出工作表上限", "a.xlsx", func(t *testing.T) []byte {
+			return buildXLSX(t, `<row r="2000000000"><c r="A2000000000"><v>1</v></c></row>`)
+		}, 0, 0, nil},
+		{"XLSX列超出XFD", "a.xlsx", func(t *testing.T) []byte {
+			return buildXLSX(t, `<row r="1"><c r="ZZZZZZ1"><v>1</v></c></row>`)
+		}, 10, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadSheet(tt.filename, bytes.NewReader(tt.data(t)), tt.maxRows, tt.maxBytes)
+			if err == nil {
+				t.Fatalf("ReadSheet() error = nil, want error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReadSheet() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    int
+		wantErr bool
+	}{
+		{"A1", 0, false},
+		{"AB12", 27, false},
+		{"XFD1", 16383, false},
+		{"XFE1", 0, true},
+		{"ZZZZZZZZZZZZZZ1", 0, true},
+		{"1A", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := columnIndex(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("columnIndex(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Fatalf("columnIndex(%q) = %d, want %d", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
@@ -16,6 +16,8 @@ func InitMySQL() error {
 	cfg := config.Cfg.MySQL
 	db, err := gorm.Open(mysql.Open(cfg.DSN), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
+		// 将唯一键冲突等数据库错误转换为gorm.ErrDuplicatedKey等通用错误，供仓储层识别
+		TranslateError: true,
 	})
 	if err != nil {
 		return err
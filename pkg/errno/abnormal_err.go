@@ -0,0 +1,16 @@
+package errno
+
+import "fmt"
+
+// 异常记录专属错误码
+var (
+	// ErrAbnormalRecordNotFound 数据操作相关
+	ErrAbnormalRecordNotFound = fmt.Errorf("异常记录不存在")
+	ErrAbnormalRecordConflict = fmt.Errorf("异常记录已被他人处理，请刷新后重试")
+	// ErrAbnormalRecordStatusInvalid 状态相关
+	ErrAbnormalRecordStatusInvalid = fmt.Errorf("异常记录状态流转不合法")
+	// ErrAbnormalMethodInvalid 处理相关
+	ErrAbnormalMethodInvalid   = fmt.Errorf("异常处理方式不合法（relabel/resort/return_to_sender）")
+	ErrAbnormalAssigneeInvalid = fmt.Errorf("指派的处理人不存在或已停用")
+	ErrAbnormalNotAssignee     = fmt.Errorf("仅被指派的处理人可处理该异常记录")
+)
@@ -0,0 +1,17 @@
+package errno
+
+import "fmt"
+
+// 认证鉴权错误码
+var (
+	// ErrUnauthorized 认证相关
+	ErrUnauthorized = fmt.Errorf("未登录或缺少访问令牌")
+	ErrTokenInvalid = fmt.Errorf("访问令牌无效")
+	ErrTokenExpired = fmt.Errorf("访问令牌已过期")
+	ErrLoginFailed  = fmt.Errorf("账号或密码错误")
+	// ErrForbidden 鉴权相关
+	ErrForbidden = fmt.Errorf("当前角色无权执行该操作")
+	// ErrAccountExists 账号相关
+	ErrAccountExists = fmt.Errorf("账号已存在")
+	ErrRoleInvalid   = fmt.Errorf("角色不合法")
+)
@@ -0,0 +1,14 @@
+package errno
+
+import "fmt"
+
+// 派送员服务范围错误码
+var (
+	// ErrCourierAreaNotFound 数据操作相关
+	ErrCourierAreaNotFound = fmt.Errorf("派送员未登记服务范围")
+	// ErrCourierInvalid 校验相关
+	ErrCourierInvalid          = fmt.Errorf("派送员账号不存在或已停用")
+	ErrCourierAreaInvalid      = fmt.Errorf("服务范围须包含负责区县或不少于3个顶点的多边形")
+	ErrCourierAreaMismatch     = fmt.Errorf("派送员不负责该派送区域")
+	ErrCourierCapacityExceeded = fmt.Errorf("派送员运力已满")
+)
@@ -7,12 +7,20 @@ var (
 	// ErrDeliveryStatusInvalid 状态相关
 	ErrDeliveryStatusInvalid = fmt.Errorf("派送任务状态流转不合法")
 	// ErrDeliveryTaskNotBindable 包裹绑定相关
-	ErrDeliveryTaskNotBindable = fmt.Errorf("派送任务当前状态不可绑定包裹")
+	ErrDeliveryTaskNotBindable    = fmt.Errorf("派送任务当前状态不可绑定包裹")
+	ErrDeliveryTaskNotUnbindable  = fmt.Errorf("派送任务当前状态不可解绑包裹")
+	ErrPackageBoundToDeliveryTask = fmt.Errorf("包裹已绑定其他未完成的派送任务")
+	ErrPackageAlreadySigned       = fmt.Errorf("包裹已签收，不可解绑")
+	// ErrDeliveryTaskNotSequenceable 派送顺序相关
+	ErrDeliveryTaskNotSequenceable = fmt.Errorf("仅待派送/派送中的任务可重新规划派送顺序")
 	// ErrDeliveryTaskNotAbnormal 异常相关
 	ErrDeliveryTaskNotAbnormal = fmt.Errorf("派送任务非异常状态，无法处理异常")
+	ErrDeliveryTaskIsAbnormal  = fmt.Errorf("派送任务处于异常状态，请先处理异常")
 	// ErrDeliveryTaskNotFound 数据操作相关
 	ErrDeliveryTaskNotFound           = fmt.Errorf("派送任务不存在")
 	ErrPackageNotBindToDeliveryTask   = fmt.Errorf("包裹未绑定到该派送任务")
 	ErrDeliveryTaskNotBelongToCourier = fmt.Errorf("派送任务不属于该派送员")
 	ErrPackageNotSigned               = fmt.Errorf("包裹未完成签收")
+	// ErrDeliveryTaskConflict 并发相关
+	ErrDeliveryTaskConflict = fmt.Errorf("派送任务已被他人修改，请刷新后重试")
 )
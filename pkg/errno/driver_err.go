@@ -0,0 +1,17 @@
+package errno
+
+import "fmt"
+
+// 司机错误码
+var (
+	// ErrDriverNotFound 数据操作相关
+	ErrDriverNotFound = fmt.Errorf("司机档案不存在")
+	ErrDriverInUse    = fmt.Errorf("司机仍有未完成的运输任务，无法删除")
+	// ErrDriverInvalid 校验相关
+	ErrDriverInvalid         = fmt.Errorf("账号不是启用中的司机")
+	ErrDriverUnavailable     = fmt.Errorf("司机休假中或已停用，不可指派运输任务")
+	ErrDriverLicenseMismatch = fmt.Errorf("司机准驾车型与车辆不符")
+	// ErrDriverBusy 排班相关
+	ErrDriverBusy          = fmt.Errorf("司机已有未完成的运输任务")
+	ErrDriverHoursExceeded = fmt.Errorf("超过司机当日驾驶时长上限")
+)
@@ -0,0 +1,9 @@
+package errno
+
+import "fmt"
+
+// 地理信息错误码
+var (
+	// ErrAddressUnresolved 地址解析相关
+	ErrAddressUnresolved = fmt.Errorf("地址无法解析为坐标")
+)
@@ -0,0 +1,14 @@
+package errno
+
+import "fmt"
+
+// 批量导入错误码
+var (
+	// ErrImportFileInvalid 文件相关
+	ErrImportFileInvalid = fmt.Errorf("导入文件无法读取，仅支持.csv/.xlsx")
+	ErrImportEmpty       = fmt.Errorf("导入文件没有数据行")
+	ErrImportTooManyRows = fmt.Errorf("导入文件数据行数超过上限")
+	ErrImportTooLarge    = fmt.Errorf("导入文件大小超过上限")
+	// ErrImportHeaderInvalid 表头缺少必填列（错误信息附缺少的列名）
+	ErrImportHeaderInvalid = fmt.Errorf("导入文件表头缺少必填列")
+)
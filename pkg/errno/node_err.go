@@ -0,0 +1,15 @@
+package errno
+
+import "fmt"
+
+// 网络节点错误码
+var (
+	// ErrNodeNotFound 数据操作相关
+	ErrNodeNotFound = fmt.Errorf("网络节点不存在")
+	ErrNodeExists   = fmt.Errorf("网络节点名称或编码已存在")
+	ErrNodeInUse    = fmt.Errorf("网络节点仍被网点、任务、派送员或司机引用，无法删除")
+	// ErrNodeTypeInvalid 校验相关
+	ErrNodeTypeInvalid   = fmt.Errorf("网络节点类型不合法（sorting_center/station）")
+	ErrNodeParentInvalid = fmt.Errorf("网点须挂靠已存在的分拣中心")
+	ErrNodeDisabled      = fmt.Errorf("网络节点已停用")
+)
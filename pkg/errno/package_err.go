@@ -0,0 +1,13 @@
+package errno
+
+import "fmt"
+
+// 包裹领域专属错误码
+var (
+	// ErrPackageStatusInvalid 状态相关
+	ErrPackageStatusInvalid = fmt.Errorf("包裹状态流转不合法")
+	ErrPackageNotBindable   = fmt.Errorf("包裹当前状态不可绑定该任务")
+	// ErrPackageNotFound 数据操作相关
+	ErrPackageNotFound   = fmt.Errorf("包裹不存在")
+	ErrPackageDuplicated = fmt.Errorf("运单号或轨迹ID已存在")
+)
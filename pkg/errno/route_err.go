@@ -0,0 +1,12 @@
+package errno
+
+import "fmt"
+
+// 路径规划错误码
+var (
+	// ErrRouteNodeNotFound 路网相关
+	ErrRouteNodeNotFound = fmt.Errorf("路网中不存在该节点")
+	ErrRouteUnreachable  = fmt.Errorf("起止节点之间不存在可达路线")
+	// ErrRouteMetricInvalid 参数相关
+	ErrRouteMetricInvalid = fmt.Errorf("路径规划指标不合法（distance/time/cost）")
+)
@@ -0,0 +1,13 @@
+package errno
+
+import "fmt"
+
+// 公开查件错误码
+var (
+	// ErrTrackingVerifyFailed 运单不存在与手机号不匹配返回同一错误，避免泄露运单是否存在
+	ErrTrackingVerifyFailed = fmt.Errorf("运单号或手机号后4位不正确")
+	ErrTrackingPhoneInvalid = fmt.Errorf("请输入收件人或寄件人手机号后4位")
+	// ErrTooManyRequests 限流相关
+	ErrTooManyRequests = fmt.Errorf("查询过于频繁，请稍后再试")
+	ErrTrackingLocked  = fmt.Errorf("该运单校验失败次数过多，已暂时锁定查询，请稍后再试")
+)
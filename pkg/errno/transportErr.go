@@ -7,12 +7,17 @@ var (
 	// ErrTransportStatusInvalid 状态相关
 	ErrTransportStatusInvalid = fmt.Errorf("运输任务状态流转不合法")
 	// ErrTransportTaskNotBindable 包裹绑定相关
-	ErrTransportTaskNotBindable = fmt.Errorf("运输任务当前状态不可绑定包裹")
+	ErrTransportTaskNotBindable   = fmt.Errorf("运输任务当前状态不可绑定包裹")
+	ErrTransportTaskNotUnbindable = fmt.Errorf("运输任务当前状态不可解绑包裹")
+	ErrTransferSameTask           = fmt.Errorf("转出与转入运输任务不能相同")
 	// ErrTransportTaskNotAbnormal 异常相关
 	ErrTransportTaskNotAbnormal = fmt.Errorf("运输任务非异常状态，无法处理异常")
+	ErrTransportTaskIsAbnormal  = fmt.Errorf("运输任务处于异常状态，请先处理异常")
 	// ErrTransportTaskNotFound 数据操作相关
 	ErrTransportTaskNotFound = fmt.Errorf("运输任务不存在")
 	ErrPackageNotBindToTask  = fmt.Errorf("包裹未绑定到该运输任务")
+	// ErrTransportTaskConflict 并发相关
+	ErrTransportTaskConflict = fmt.Errorf("运输任务已被他人修改，请刷新后重试")
 
 	ErrParamInvalid = fmt.Errorf("参数无效")
 
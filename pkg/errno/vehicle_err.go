@@ -0,0 +1,15 @@
+package errno
+
+import "fmt"
+
+// 车辆错误码
+var (
+	// ErrVehicleNotFound 数据操作相关
+	ErrVehicleNotFound = fmt.Errorf("车辆不存在")
+	ErrVehicleExists   = fmt.Errorf("车牌号已登记")
+	ErrVehicleInUse    = fmt.Errorf("车辆仍有未完成的运输任务，无法删除")
+	// ErrVehicleUnavailable 校验相关
+	ErrVehicleUnavailable = fmt.Errorf("车辆维修中或已停用，不可指派运输任务")
+	ErrVehicleOverweight  = fmt.Errorf("绑定后装载重量超过车辆最大载重")
+	ErrVehicleOverVolume  = fmt.Errorf("绑定后装载体积超过车辆最大容积")
+)